@@ -0,0 +1,80 @@
+// Package audit checks the pages of a crawled site for problems (e.g. inconsistent canonical URLs or missing titles).
+package audit
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Issue is a problem found in a page of the crawled site.
+type Issue struct {
+	URL     string `json:"url"`
+	Rule    string `json:"rule"` // identifier of the check that found the problem (e.g. "canonical-chain")
+	Message string `json:"message"`
+}
+
+// String returns a description of the issue with the following format:
+// "http://a.com/ - canonical-chain: canonical URL http://a.com/b is canonicalized to http://a.com/c".
+func (issue Issue) String() string {
+	return issue.URL + " - " + issue.Rule + ": " + issue.Message
+}
+
+// RuleCount is the number of issues found by a rule.
+type RuleCount struct {
+	Rule  string
+	Count int
+}
+
+// CountByRule counts the issues found by each rule, ordered by rule.
+func CountByRule(issues []Issue) []RuleCount {
+	counts := make(map[string]int)
+	for _, issue := range issues {
+		counts[issue.Rule]++
+	}
+
+	ruleCounts := []RuleCount{}
+	for rule, count := range counts {
+		ruleCounts = append(ruleCounts, RuleCount{Rule: rule, Count: count})
+	}
+	sort.Slice(ruleCounts, func(i, j int) bool {
+		return ruleCounts[i].Rule < ruleCounts[j].Rule
+	})
+	return ruleCounts
+}
+
+// URLCount is the number of issues found in a page.
+type URLCount struct {
+	URL   string
+	Count int
+}
+
+// CountByURL counts the issues found in each page, ordered from the page with the most issues
+// to the one with the least (pages with the same number of issues are in the order they were found).
+func CountByURL(issues []Issue) []URLCount {
+	urlCounts := []URLCount{}
+	indexes := make(map[string]int)
+	for _, issue := range issues {
+		i, ok := indexes[issue.URL]
+		if !ok {
+			i = len(urlCounts)
+			indexes[issue.URL] = i
+			urlCounts = append(urlCounts, URLCount{URL: issue.URL})
+		}
+		urlCounts[i].Count++
+	}
+
+	sort.SliceStable(urlCounts, func(i, j int) bool {
+		return urlCounts[i].Count > urlCounts[j].Count
+	})
+	return urlCounts
+}
+
+// Summary returns a one-line summary of the issues found by each rule (e.g. "thin-content: 1, title-missing: 2").
+func Summary(issues []Issue) string {
+	parts := []string{}
+	for _, count := range CountByRule(issues) {
+		parts = append(parts, count.Rule+": "+strconv.Itoa(count.Count))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,138 @@
+package audit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/msandim/web-crawler/graph"
+	"github.com/msandim/web-crawler/structured"
+)
+
+func TestIssue_String(t *testing.T) {
+	issue := Issue{URL: "http://a.com/", Rule: "rule", Message: "message"}
+
+	if issue.String() != "http://a.com/ - rule: message" {
+		t.Errorf("Issue was invalid. Got: %s", issue.String())
+	}
+}
+
+func TestCanonicals(t *testing.T) {
+	sitemap := graph.New()
+	sitemap.AddNode(&graph.Node{URL: "http://a.com/", StatusCode: 200, Canonical: "http://a.com/"})
+	sitemap.AddNode(&graph.Node{URL: "http://a.com/print", StatusCode: 200, Canonical: "http://a.com/"})
+	sitemap.AddNode(&graph.Node{URL: "http://a.com/old", StatusCode: 200, Canonical: "http://a.com/gone"})
+	sitemap.AddNode(&graph.Node{URL: "http://a.com/gone", StatusCode: 404})
+	sitemap.AddNode(&graph.Node{URL: "http://a.com/moved", StatusCode: 200, Canonical: "http://a.com/redirect"})
+	sitemap.AddNode(&graph.Node{URL: "http://a.com/redirect", StatusCode: 200, Redirects: []string{"http://a.com/"}})
+	sitemap.AddNode(&graph.Node{URL: "http://a.com/chain", StatusCode: 200, Canonical: "http://a.com/print"})
+	sitemap.AddNode(&graph.Node{URL: "http://a.com/external", StatusCode: 200, Canonical: "http://b.com/"})
+
+	issues := Canonicals(sitemap)
+
+	expected := []Issue{
+		{URL: "http://a.com/old", Rule: RuleCanonicalNotOK, Message: "canonical URL http://a.com/gone returned status 404"},
+		{URL: "http://a.com/moved", Rule: RuleCanonicalNotOK, Message: "canonical URL http://a.com/redirect redirects to http://a.com/"},
+		{URL: "http://a.com/chain", Rule: RuleCanonicalChain, Message: "canonical URL http://a.com/print is canonicalized to http://a.com/"},
+	}
+	if !reflect.DeepEqual(issues, expected) {
+		t.Errorf("Issues were invalid. Expected: %v, Got: %v", expected, issues)
+	}
+}
+
+func TestCanonicals_Hreflang(t *testing.T) {
+	alternates := []graph.Alternate{
+		{Lang: "en", URL: "http://a.com/en"},
+		{Lang: "pt", URL: "http://a.com/pt"},
+		{Lang: "fr", URL: "http://a.com/fr"},
+		{Lang: "x-default", URL: "http://a.com/en"},
+	}
+
+	sitemap := graph.New()
+	sitemap.AddNode(&graph.Node{URL: "http://a.com/en", StatusCode: 200, Alternates: alternates})
+	sitemap.AddNode(&graph.Node{URL: "http://a.com/pt", StatusCode: 200, Alternates: alternates})
+	sitemap.AddNode(&graph.Node{URL: "http://a.com/fr", StatusCode: 200, Alternates: []graph.Alternate{{Lang: "fr", URL: "http://a.com/fr"}}})
+
+	issues := Canonicals(sitemap)
+
+	expected := []Issue{
+		{URL: "http://a.com/en", Rule: RuleHreflangNotReciprocal, Message: "alternate http://a.com/fr (fr) doesn't link back to the page"},
+		{URL: "http://a.com/pt", Rule: RuleHreflangNotReciprocal, Message: "alternate http://a.com/fr (fr) doesn't link back to the page"},
+		{URL: "http://a.com/fr", Rule: RuleHreflangMissingXDefault, Message: "the alternates of the page don't include an x-default"},
+	}
+	if !reflect.DeepEqual(issues, expected) {
+		t.Errorf("Issues were invalid. Expected: %v, Got: %v", expected, issues)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	issues := []Issue{{URL: "A", Rule: "b"}, {URL: "B", Rule: "a"}, {URL: "C", Rule: "b"}}
+
+	if Summary(issues) != "a: 1, b: 2" {
+		t.Errorf("Summary was invalid. Expected: %s, Got: %s", "a: 1, b: 2", Summary(issues))
+	}
+}
+
+func TestPageSEO(t *testing.T) {
+	node := &graph.Node{
+		URL:         "http://a.com/",
+		StatusCode:  200,
+		ContentType: "text/html; charset=utf-8",
+		Title:       strings.Repeat("a", MaxTitleLength+1),
+		Headings:    []graph.Heading{{Level: 1, Text: "One"}, {Level: 1, Text: "Two"}},
+		WordCount:   MinWordCount - 1,
+	}
+
+	expected := []Issue{
+		{URL: "http://a.com/", Rule: RuleTitleTooLong, Message: "the title has 61 characters (maximum 60)"},
+		{URL: "http://a.com/", Rule: RuleDescriptionMissing, Message: "the page has no meta description"},
+		{URL: "http://a.com/", Rule: RuleMultipleH1, Message: "the page has 2 <h1> headings: One, Two"},
+		{URL: "http://a.com/", Rule: RuleThinContent, Message: "the page has 199 words (minimum 200)"},
+	}
+	if issues := PageSEO(node); !reflect.DeepEqual(issues, expected) {
+		t.Errorf("Issues were invalid. Expected: %v, Got: %v", expected, issues)
+	}
+
+	node.Title, node.Description, node.Headings, node.WordCount = "", "Description", nil, MinWordCount
+	node.StructuredData = &structured.Data{Errors: []string{"invalid JSON-LD: unexpected end of JSON input"}}
+	expected = []Issue{
+		{URL: "http://a.com/", Rule: RuleTitleMissing, Message: "the page has no title"},
+		{URL: "http://a.com/", Rule: RuleStructuredData, Message: "invalid JSON-LD: unexpected end of JSON input"},
+	}
+	if issues := PageSEO(node); !reflect.DeepEqual(issues, expected) {
+		t.Errorf("Issues were invalid. Expected: %v, Got: %v", expected, issues)
+	}
+
+	// Pages that aren't HTML or couldn't be fetched aren't checked:
+	if issues := PageSEO(&graph.Node{URL: "http://a.com/x", StatusCode: 404, ContentType: "text/html"}); len(issues) != 0 {
+		t.Errorf("Broken pages should not be checked: %v", issues)
+	}
+}
+
+func TestDuplicateMetadata(t *testing.T) {
+	sitemap := graph.New()
+	sitemap.AddNode(&graph.Node{URL: "A", StatusCode: 200, ContentType: "text/html", Title: "Home", Description: "Bank"})
+	sitemap.AddNode(&graph.Node{URL: "B", StatusCode: 200, ContentType: "text/html", Title: "Home", Description: "Blog"})
+	sitemap.AddNode(&graph.Node{URL: "C", StatusCode: 200, ContentType: "text/html", Title: "Home", Description: "Blog"})
+	sitemap.AddNode(&graph.Node{URL: "D", StatusCode: 200, ContentType: "text/html"})
+	sitemap.AddNode(&graph.Node{URL: "E", StatusCode: 200, ContentType: "text/html"})
+	sitemap.AddNode(&graph.Node{URL: "F", StatusCode: 200, ContentType: "image/png", Title: "Home"})
+
+	expected := []Issue{
+		{URL: "B", Rule: RuleTitleDuplicate, Message: "same title as A"},
+		{URL: "C", Rule: RuleTitleDuplicate, Message: "same title as A"},
+		{URL: "C", Rule: RuleDescriptionDuplicate, Message: "same meta description as B"},
+	}
+	if issues := DuplicateMetadata(sitemap); !reflect.DeepEqual(issues, expected) {
+		t.Errorf("Issues were invalid. Expected: %v, Got: %v", expected, issues)
+	}
+}
+
+func TestCountByURL(t *testing.T) {
+	issues := []Issue{{URL: "A", Rule: "a"}, {URL: "B", Rule: "a"}, {URL: "B", Rule: "b"}, {URL: "C", Rule: "a"}}
+
+	expected := []URLCount{{URL: "B", Count: 2}, {URL: "A", Count: 1}, {URL: "C", Count: 1}}
+	if counts := CountByURL(issues); !reflect.DeepEqual(counts, expected) {
+		t.Errorf("Counts were invalid. Expected: %v, Got: %v", expected, counts)
+	}
+}
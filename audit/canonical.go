@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/msandim/web-crawler/graph"
+)
+
+// Rules of the canonical URL and hreflang checks:
+const (
+	RuleCanonicalNotOK          = "canonical-not-ok"
+	RuleCanonicalChain          = "canonical-chain"
+	RuleHreflangNotReciprocal   = "hreflang-not-reciprocal"
+	RuleHreflangMissingXDefault = "hreflang-missing-x-default"
+)
+
+// Canonicals checks the canonical URLs and hreflang alternates declared by the pages of the graph, reporting:
+// canonical URLs that redirect or don't return 200, canonical URLs that are canonicalized to yet another URL,
+// alternates that don't link back to the page and sets of alternates without an x-default.
+// Canonical URLs and alternates that weren't crawled aren't checked.
+func Canonicals(sitemap *graph.Graph) []Issue {
+	issues := []Issue{}
+
+	for _, node := range sitemap.Nodes {
+		if node.Canonical != "" && node.Canonical != node.URL {
+			issues = append(issues, checkCanonical(sitemap, node)...)
+		}
+		if len(node.Alternates) > 0 {
+			issues = append(issues, checkAlternates(sitemap, node)...)
+		}
+	}
+	return issues
+}
+
+func checkCanonical(sitemap *graph.Graph, node *graph.Node) []Issue {
+	target, ok := sitemap.Node(node.Canonical)
+	if !ok {
+		return nil
+	}
+
+	switch {
+	case len(target.Redirects) > 0:
+		return []Issue{{
+			URL:     node.URL,
+			Rule:    RuleCanonicalNotOK,
+			Message: "canonical URL " + target.URL + " redirects to " + target.Redirects[len(target.Redirects)-1],
+		}}
+	case target.StatusCode != 200:
+		return []Issue{{
+			URL:     node.URL,
+			Rule:    RuleCanonicalNotOK,
+			Message: "canonical URL " + target.URL + " returned status " + strconv.Itoa(target.StatusCode),
+		}}
+	case target.Canonical != "" && target.Canonical != target.URL:
+		return []Issue{{
+			URL:     node.URL,
+			Rule:    RuleCanonicalChain,
+			Message: "canonical URL " + target.URL + " is canonicalized to " + target.Canonical,
+		}}
+	}
+	return nil
+}
+
+func checkAlternates(sitemap *graph.Graph, node *graph.Node) []Issue {
+	issues := []Issue{}
+	hasXDefault := false
+
+	for _, alternate := range node.Alternates {
+		if strings.ToLower(alternate.Lang) == "x-default" {
+			hasXDefault = true
+		}
+		if alternate.URL == node.URL {
+			continue
+		}
+
+		target, ok := sitemap.Node(alternate.URL)
+		if !ok || target.IsBroken() {
+			continue
+		}
+		if !hasAlternate(target, node.URL) {
+			issues = append(issues, Issue{
+				URL:     node.URL,
+				Rule:    RuleHreflangNotReciprocal,
+				Message: "alternate " + alternate.URL + " (" + alternate.Lang + ") doesn't link back to the page",
+			})
+		}
+	}
+
+	if !hasXDefault {
+		issues = append(issues, Issue{
+			URL:     node.URL,
+			Rule:    RuleHreflangMissingXDefault,
+			Message: "the alternates of the page don't include an x-default",
+		})
+	}
+	return issues
+}
+
+// hasAlternate checks if a page lists an URL as one of its alternates.
+func hasAlternate(node *graph.Node, url string) bool {
+	for _, alternate := range node.Alternates {
+		if alternate.URL == url {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/msandim/web-crawler/graph"
+)
+
+// Rules of the on-page SEO checks:
+const (
+	RuleTitleMissing         = "title-missing"
+	RuleTitleTooLong         = "title-too-long"
+	RuleTitleDuplicate       = "title-duplicate"
+	RuleDescriptionMissing   = "description-missing"
+	RuleDescriptionDuplicate = "description-duplicate"
+	RuleMultipleH1           = "h1-multiple"
+	RuleThinContent          = "thin-content"
+	RuleStructuredData       = "structured-data-invalid"
+)
+
+// MaxTitleLength is the maximum number of characters of a title before it's truncated by search engines.
+const MaxTitleLength = 60
+
+// MinWordCount is the minimum number of words of the visible text of a page for it not to be thin.
+const MinWordCount = 200
+
+// PageSEO checks the on-page SEO metadata of a page, reporting: a missing or overly long title,
+// a missing meta description, multiple <h1> headings, thin content and invalid structured data (e.g. JSON-LD).
+// Only HTML pages fetched successfully are checked.
+func PageSEO(node *graph.Node) []Issue {
+	if !isHTMLPage(node) {
+		return nil
+	}
+
+	issues := []Issue{}
+	add := func(rule string, message string) {
+		issues = append(issues, Issue{URL: node.URL, Rule: rule, Message: message})
+	}
+
+	if node.Title == "" {
+		add(RuleTitleMissing, "the page has no title")
+	} else if length := utf8.RuneCountInString(node.Title); length > MaxTitleLength {
+		add(RuleTitleTooLong, "the title has "+strconv.Itoa(length)+" characters (maximum "+strconv.Itoa(MaxTitleLength)+")")
+	}
+
+	if node.Description == "" {
+		add(RuleDescriptionMissing, "the page has no meta description")
+	}
+
+	if h1s := node.H1s(); len(h1s) > 1 {
+		add(RuleMultipleH1, "the page has "+strconv.Itoa(len(h1s))+" <h1> headings: "+strings.Join(h1s, ", "))
+	}
+
+	if node.WordCount < MinWordCount {
+		add(RuleThinContent, "the page has "+strconv.Itoa(node.WordCount)+" words (minimum "+strconv.Itoa(MinWordCount)+")")
+	}
+
+	if node.StructuredData != nil {
+		for _, err := range node.StructuredData.Errors {
+			add(RuleStructuredData, err)
+		}
+	}
+	return issues
+}
+
+// DuplicateMetadata checks the titles and meta descriptions shared by several HTML pages of the graph,
+// reporting every page that uses the same one as a page crawled before it.
+func DuplicateMetadata(sitemap *graph.Graph) []Issue {
+	issues := []Issue{}
+	titles := make(map[string]string)
+	descriptions := make(map[string]string)
+
+	for _, node := range sitemap.Nodes {
+		if !isHTMLPage(node) {
+			continue
+		}
+
+		if first, ok := titles[node.Title]; ok && node.Title != "" {
+			issues = append(issues, Issue{URL: node.URL, Rule: RuleTitleDuplicate, Message: "same title as " + first})
+		} else {
+			titles[node.Title] = node.URL
+		}
+
+		if first, ok := descriptions[node.Description]; ok && node.Description != "" {
+			issues = append(issues, Issue{URL: node.URL, Rule: RuleDescriptionDuplicate, Message: "same meta description as " + first})
+		} else {
+			descriptions[node.Description] = node.URL
+		}
+	}
+	return issues
+}
+
+// isHTMLPage checks if a node corresponds to an HTML page fetched successfully.
+func isHTMLPage(node *graph.Node) bool {
+	return node.StatusCode == 200 && strings.Contains(node.ContentType, "text/html")
+}
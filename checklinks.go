@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/msandim/web-crawler/crawler"
+	"github.com/msandim/web-crawler/report"
+)
+
+// runCheckLinks crawls a domain and reports its broken links, along with the pages (and anchor texts)
+// that reference them. It returns the exit code of the program: 1 if broken links were found, 0 otherwise.
+func runCheckLinks(arguments []string) int {
+	flags := flag.NewFlagSet("check-links", flag.ExitOnError)
+	args := addCrawlFlags(flags)
+	external := flags.Bool("external", false, "also check (with HTTP HEAD requests, without crawling them) the links to other domains")
+	reportFile := flags.String("report", "", "file to which a self-contained HTML report of the crawl is written")
+	flags.Parse(arguments)
+
+	validateCrawlArguments(args)
+	serveMetrics(args.metricsAddr)
+
+	cache := loadState(args)
+	options := args.options(cache)
+	options.Fetcher = loadFetcher(args)
+	options.Cassette = newRecording(args)
+	options.CheckExternal = *external
+	options.Quiet = true
+
+	crawler := crawler.NewWithOptions(args.nWorkers, args.rateLimit, args.timeoutSeconds, args.domain, options)
+	crawler.Run()
+	saveState(args, cache)
+	saveRecording(args, options.Cassette)
+	closeSeenSet(options.Seen)
+	closeFrontier(options.Frontier)
+
+	if *reportFile != "" {
+		writeFile(*reportFile, func(f *os.File) error { return report.Write(f, crawler.Graph(), crawler.Issues()) })
+	}
+
+	nBroken, err := report.WriteBrokenLinks(os.Stdout, crawler.Graph())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "main::runCheckLinks() - Error: failed to write the broken links: ", err)
+		return 1
+	}
+
+	if nBroken > 0 {
+		fmt.Fprintln(os.Stderr, "main::runCheckLinks() - Error: broken links found: ", nBroken)
+		return 1
+	}
+
+	fmt.Fprintln(os.Stderr, "main::runCheckLinks() - No broken links found in pages crawled: ", len(crawler.Graph().Nodes))
+	return 0
+}
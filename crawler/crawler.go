@@ -1,7 +1,20 @@
 package crawler
 
 import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/msandim/web-crawler/audit"
 	"github.com/msandim/web-crawler/fetcher"
+	"github.com/msandim/web-crawler/frontier"
+	"github.com/msandim/web-crawler/graph"
+	"github.com/msandim/web-crawler/search"
+	"github.com/msandim/web-crawler/seen"
+	"github.com/msandim/web-crawler/simhash"
+	"github.com/msandim/web-crawler/traps"
 	"github.com/msandim/web-crawler/workerpool"
 )
 
@@ -12,44 +25,173 @@ type Crawler struct {
 	results chan workerpool.JobResult
 
 	// Parameters related to the crawling process:
-	domain string
+	domain  string
+	options Options
+
+	pageFetcher fetcher.Fetcher // knows how to fetch a page (through HTTP requests in production or mocked in testing)
+	log         logger          // knows how to log the results
 
 	// Variables for the crawler's state:
-	nURLsCrawled int             // number of URLs successfully crawled
-	checkedUrls  map[string]bool // number of URLs in which we initiated the crawling process
+	nURLsCrawled int      // number of pages crawled
+	checkedUrls  seen.Set // URLs in which we initiated the crawling process
 	finishedFlag chan bool
+
+	// URLs waiting to be crawled, which are given to the pool as long as it has less than maxInFlight jobs:
+	frontier    frontier.Frontier
+	inFlight    int
+	maxInFlight int
+
+	// Variables to follow and control the crawl while it runs (see Progress, Pause, Resume and Cancel):
+	control  sync.Mutex
+	progress Progress
+	running  chan struct{} // closed unless the crawl is paused
+
+	// Link graph of the pages crawled:
+	graph *graph.Graph
+
+	// Groups of pages with duplicate content:
+	duplicates *simhash.Clusters
+
+	// Pages that ask robots not to be indexed, removed from the results when obeying them:
+	noIndexURLs []string
+
+	// Problems found in the pages crawled (pageIssues are the ones found while crawling each page):
+	issues     []audit.Issue
+	pageIssues []audit.Issue
+}
+
+// Options are the optional settings of the crawling process.
+type Options struct {
+	CheckExternal bool           // check (without crawling them) the links to other domains
+	Quiet         bool           // don't log the sitemap
+	Cache         *fetcher.Cache // pages fetched in a previous crawl, to avoid downloading them if they didn't change
+
+	// Pages whose content fingerprints differ in up to DuplicateDistance bits are reported as duplicates:
+	DuplicateDistance int
+	SkipDuplicates    bool // don't follow the links of pages that are duplicates of a page already crawled
+
+	RobotsPolicy RobotsPolicy // what to do with the noindex and nofollow directives to robots
+
+	DedupeCanonical bool // merge the pages that declare another canonical URL into the canonical page
+	CanonicalAudit  bool // check the canonical URLs and hreflang alternates declared by the pages
+	SEOAudit        bool // check the on-page SEO metadata of the pages (titles, descriptions, headings and content)
+	Accessibility   bool // check the DOM of the pages for accessibility problems
+	Security        bool // check the security headers, cookies and assets (mixed content) of the HTTPS pages
+
+	Traps *traps.Detector // skips the URLs of crawler traps (e.g. infinite calendars), if not nil
+
+	// URLs in which the crawling process was initiated (seen.NewMap if nil). Sets with false positives
+	// (e.g. Bloom filters) skip the URLs that seem to have been seen:
+	Seen seen.Set
+
+	// URLs waiting to be crawled (frontier.NewMemory if nil). If it isn't empty when the crawl starts
+	// (e.g. a frontier on disk left by an interrupted crawl), the crawl resumes from its URLs, along with
+	// the set of the URLs seen it was crawled with (e.g. the one kept by frontier.Disk):
+	Frontier frontier.Frontier
+
+	Assets    bool              // also download the resources of the pages in the domain (e.g. images), e.g. to mirror them
+	Fetcher   fetcher.Fetcher   // fetches the pages instead of HTTP requests (e.g. to replay a recorded crawl), if not nil
+	Cassette  *fetcher.Cassette // every request made is recorded to it, to be replayed later (nil if not wanted)
+	Index     *search.Writer    // full-text index to which the visible text of the pages is added (nil if not wanted)
+	Transport http.RoundTripper // used to send the HTTP requests, e.g. to archive them (http.DefaultTransport if nil)
+
+	Output io.Writer // to which the sitemap is printed (os.Stdout if nil)
+	Errors io.Writer // to which the errors and warnings are printed (os.Stderr if nil)
+}
+
+// Progress is the progress of a crawl, which can be followed while it runs.
+type Progress struct {
+	Crawled   int  // pages crawled (not the assets downloaded)
+	Queued    int  // URLs waiting to be crawled
+	InFlight  int  // URLs being crawled
+	Errors    int  // errors found while crawling (e.g. pages that failed to be fetched)
+	Paused    bool // the crawl is paused: no new URLs are crawled until it's resumed
+	Cancelled bool // the crawl was cancelled: no new URLs are crawled, and it ends with the pages crawled so far
 }
 
-// Responsable to know how to fetch a page (through HTTP requests in production or mocked in testing):
-var pageFetcher fetcher.Fetcher
+// RobotsPolicy defines how the crawler handles the directives to robots (rel="nofollow" in links,
+// <meta name="robots"> tags and X-Robots-Tag headers). The directives are recorded in the graph with any policy.
+type RobotsPolicy int
 
-// Responsable to know how to log the results:
-var log logger = &printer{}
+const (
+	// RobotsIgnore crawls and reports all pages and links, regardless of their directives.
+	RobotsIgnore RobotsPolicy = iota
+	// RobotsAnnotate crawls all pages and links, annotating their directives in the sitemap (e.g. "[noindex]").
+	RobotsAnnotate
+	// RobotsObey doesn't follow nofollow links (or the links of nofollow pages) and doesn't report noindex pages.
+	RobotsObey
+)
+
+// RobotsPolicies maps the names of the policies (e.g. in the robots flag) to them.
+var RobotsPolicies = map[string]RobotsPolicy{
+	"ignore":   RobotsIgnore,
+	"annotate": RobotsAnnotate,
+	"obey":     RobotsObey,
+}
 
 // New creates a Crawler struct given the arguments and returns a pointer to it.
 func New(nWorkers int, rateLimit int, timeoutSeconds int, domain string) *Crawler {
-	pageFetcher = fetcher.NewHTTPFetcher(rateLimit, timeoutSeconds)
+	return NewWithOptions(nWorkers, rateLimit, timeoutSeconds, domain, Options{})
+}
+
+// NewWithOptions creates a Crawler struct given the arguments and optional settings and returns a pointer to it.
+func NewWithOptions(nWorkers int, rateLimit int, timeoutSeconds int, domain string, options Options) *Crawler {
+	httpFetcher := fetcher.NewHTTPFetcher(rateLimit, timeoutSeconds)
+	if options.Cache != nil {
+		httpFetcher.SetCache(options.Cache)
+	}
+	httpFetcher.SetAccessibilityCheck(options.Accessibility)
+	httpFetcher.SetSecurityCheck(options.Security)
+	httpFetcher.SetTransport(options.Transport)
+	var pageFetcher fetcher.Fetcher = httpFetcher
+	if options.Seen == nil {
+		options.Seen = seen.NewMap()
+	}
+	if options.Frontier == nil {
+		options.Frontier = frontier.NewMemory()
+	}
+	if options.Fetcher != nil {
+		pageFetcher = options.Fetcher
+	}
+	if options.Cassette != nil {
+		pageFetcher = fetcher.NewRecorder(pageFetcher, options.Cassette)
+	}
 	pool := workerpool.New(nWorkers)
 
 	return &Crawler{
 		pool:         pool,
 		results:      pool.GetResultsChannel(),
 		domain:       domain,
-		checkedUrls:  make(map[string]bool),
+		options:      options,
+		pageFetcher:  pageFetcher,
+		log:          newPrinter(options.Output, options.Errors),
+		checkedUrls:  options.Seen,
 		finishedFlag: make(chan bool),
+		frontier:     options.Frontier,
+		maxInFlight:  2 * nWorkers,
+		running:      closedChannel(),
+		graph:        graph.New(),
+		duplicates:   simhash.NewClusters(options.DuplicateDistance),
 	}
 }
 
 // newTesting creates Crawler struct given the arguments and returns a pointer to it (used only for testing).
-func newTesting(nWorkers int, domain string) *Crawler {
+func newTesting(nWorkers int, domain string, pageFetcher fetcher.Fetcher, log logger) *Crawler {
 	pool := workerpool.New(nWorkers)
 
 	return &Crawler{
 		pool:         pool,
 		results:      pool.GetResultsChannel(),
 		domain:       domain,
-		checkedUrls:  make(map[string]bool),
+		pageFetcher:  pageFetcher,
+		log:          log,
+		checkedUrls:  seen.NewMap(),
 		finishedFlag: make(chan bool),
+		frontier:     frontier.NewMemory(),
+		maxInFlight:  2 * nWorkers,
+		running:      closedChannel(),
+		graph:        graph.New(),
+		duplicates:   simhash.NewClusters(0),
 	}
 }
 
@@ -59,15 +201,176 @@ func newTesting(nWorkers int, domain string) *Crawler {
 func (crawler *Crawler) Run() {
 	crawler.pool.Run()
 
-	// Start the first job: crawl the main page of the domain:
-	crawler.pool.AddJob(&crawlerJob{url: crawler.domain})
-	crawler.checkedUrls[crawler.domain] = true
+	// Start the first job: crawl the main page of the domain (unless resuming a crawl from its frontier):
+	if crawler.frontier.Len() == 0 {
+		crawler.pushNew(&crawlerJob{url: crawler.domain})
+	}
+	crawler.endIfFinished()
 
 	// Initiate routine that will receive the crawling results:
 	go onURLCrawled(crawler)
 
-	// Wait for end of crawling process:
+	// Wait for end of crawling process (the URLs left in the frontier of a cancelled crawl aren't waiting anymore):
 	<-crawler.finishedFlag
+	queuedURLs.Add(-float64(crawler.Progress().Queued))
+
+	siteIssues := []audit.Issue{}
+	if crawler.options.CanonicalAudit {
+		siteIssues = audit.Canonicals(crawler.graph)
+	}
+
+	crawler.graph.RemoveNodes(crawler.noIndexURLs)
+	if crawler.options.DedupeCanonical {
+		crawler.graph.MergeAliases(crawler.canonicalAliases())
+	}
+
+	if crawler.options.SEOAudit {
+		siteIssues = append(siteIssues, audit.DuplicateMetadata(crawler.graph)...)
+	}
+	if crawler.options.Traps != nil {
+		siteIssues = append(siteIssues, crawler.options.Traps.Issues()...)
+	}
+
+	for _, group := range crawler.duplicates.Groups() {
+		crawler.log.logError("Crawler::Run() - Warning: pages with duplicate content: " + strings.Join(group, ", "))
+	}
+	for _, issue := range siteIssues {
+		crawler.log.logError("Crawler::Run() - Warning: " + issue.String())
+	}
+
+	crawler.issues = append(crawler.pageIssues, siteIssues...)
+	if len(crawler.issues) > 0 {
+		crawler.log.logError("Crawler::Run() - Issues found: " + audit.Summary(crawler.issues))
+	}
+}
+
+// Issues returns the problems found in the pages crawled (e.g. inconsistent canonical URLs).
+// It should only be called after Run returns.
+func (crawler *Crawler) Issues() []audit.Issue {
+	return crawler.issues
+}
+
+// canonicalAliases maps the pages that declare another canonical URL to it, as long as the canonical page
+// was crawled successfully and is canonical itself (pages in canonical chains aren't merged).
+func (crawler *Crawler) canonicalAliases() map[string]string {
+	aliases := make(map[string]string)
+	for _, node := range crawler.graph.Nodes {
+		if node.Canonical == "" || node.Canonical == node.URL {
+			continue
+		}
+		if canonical, ok := crawler.graph.Node(node.Canonical); ok && !canonical.IsBroken() &&
+			(canonical.Canonical == "" || canonical.Canonical == canonical.URL) {
+			aliases[node.URL] = node.Canonical
+		}
+	}
+	return aliases
+}
+
+// Duplicates returns the groups of pages crawled that have the same (or nearly the same) content.
+// It should only be called after Run returns.
+func (crawler *Crawler) Duplicates() [][]string {
+	return crawler.duplicates.Groups()
+}
+
+// Graph returns the link graph of the pages crawled.
+// It should only be called after Run returns.
+func (crawler *Crawler) Graph() *graph.Graph {
+	return crawler.graph
+}
+
+// annotate adds the directives to robots of a page or link to its url, if the crawler annotates them
+// (e.g. "http://a.com/ [noindex, nofollow]").
+func (crawler *Crawler) annotate(url string, nofollow bool, noindex bool) string {
+	if crawler.options.RobotsPolicy != RobotsAnnotate {
+		return url
+	}
+
+	directives := []string{}
+	if noindex {
+		directives = append(directives, "noindex")
+	}
+	if nofollow {
+		directives = append(directives, "nofollow")
+	}
+
+	if len(directives) == 0 {
+		return url
+	}
+	return url + " [" + strings.Join(directives, ", ") + "]"
+}
+
+// PageNode converts a page fetched from an url, found at a depth of the crawl, to a node of the link graph.
+func PageNode(url string, page *fetcher.Page, depth int) *graph.Node {
+	return &graph.Node{
+		URL:          url,
+		StatusCode:   page.StatusCode,
+		Depth:        depth,
+		ContentType:  page.ContentType,
+		ResponseTime: page.ResponseTime,
+		Size:         page.Size,
+		Title:        page.Title,
+		Redirects:    page.Redirects,
+		NoIndex:      page.Robots.NoIndex,
+		NoFollow:     page.Robots.NoFollow,
+		Canonical:    page.Canonical,
+		Alternates:   alternates(page.Alternates),
+
+		Description:   page.Description,
+		Headings:      headings(page.Headings),
+		WordCount:     page.WordCount,
+		Images:        page.Images,
+		ImagesWithAlt: page.ImagesWithAlt,
+
+		StructuredData: page.StructuredData,
+	}
+}
+
+// alternates converts the alternates of a page to the ones of a graph node.
+func alternates(pageAlternates []fetcher.Alternate) []graph.Alternate {
+	if len(pageAlternates) == 0 {
+		return nil
+	}
+
+	nodeAlternates := make([]graph.Alternate, len(pageAlternates))
+	for i, alternate := range pageAlternates {
+		nodeAlternates[i] = graph.Alternate{Lang: alternate.Lang, URL: alternate.URL}
+	}
+	return nodeAlternates
+}
+
+// headings converts the headings of a page to the ones of a graph node.
+func headings(pageHeadings []fetcher.Heading) []graph.Heading {
+	if len(pageHeadings) == 0 {
+		return nil
+	}
+
+	nodeHeadings := make([]graph.Heading, len(pageHeadings))
+	for i, heading := range pageHeadings {
+		nodeHeadings[i] = graph.Heading{Level: heading.Level, Text: heading.Text}
+	}
+	return nodeHeadings
+}
+
+// accessibilityIssues converts the accessibility problems found in a page to issues.
+func accessibilityIssues(page *fetcher.Page) []audit.Issue {
+	issues := []audit.Issue{}
+	for _, violation := range page.Accessibility {
+		issues = append(issues, audit.Issue{
+			URL:     page.URL,
+			Rule:    violation.Rule,
+			Message: violation.Element + " " + violation.Message,
+		})
+	}
+	return issues
+}
+
+// securityIssues converts the security problems found in a page to issues.
+func securityIssues(page *fetcher.Page) []audit.Issue {
+	issues := []audit.Issue{}
+	for _, finding := range page.Security {
+		issues = append(issues, audit.Issue{URL: page.URL, Rule: finding.Rule, Message: finding.Message})
+	}
+	return issues
 }
 
 // onUrlCrawled is a routine that iterates over the results returned by the Worker Pool
@@ -80,29 +383,260 @@ func onURLCrawled(crawler *Crawler) {
 		parentURL := job.url
 		childrenURLs := []string{}
 
-		// Get the result from crawling job and increment the number of URLs crawled:
+		// Get the result from crawling job:
 		jobResult := result.(*crawlerJobResult)
+		page := jobResult.page
+		crawler.inFlight--
+
+		// Assets are only downloaded, they aren't part of the sitemap (nor the URLs not crawled). The URLs not
+		// crawled as the crawl was cancelled are left to be crawled when a persistent frontier is resumed:
+		if job.asset || page == nil {
+			if !jobResult.cancelled {
+				crawler.done(job)
+			}
+			crawler.endIfFinished()
+			continue
+		}
 		crawler.nURLsCrawled++
 
-		// Iterate over the URLs on the page we obtained:
-		for _, url := range jobResult.urls {
+		// Only the HTML pages fetched have their content fingerprinted:
+		duplicateOf := ""
+		if page.TextHash != "" {
+			duplicateOf = crawler.duplicates.Add(parentURL, page.TextHash, page.SimHash)
+		}
 
-			childrenURLs = append(childrenURLs, url)
+		node := PageNode(parentURL, page, job.depth)
+		node.DuplicateOf = duplicateOf
+		crawler.graph.AddNode(node)
 
-			// If we never crawled that url, then we do it now:
-			if !crawler.checkedUrls[url] {
-				crawler.pool.AddJob(&crawlerJob{url: url})
-				crawler.checkedUrls[url] = true
+		obeyRobots := crawler.options.RobotsPolicy == RobotsObey
+		if obeyRobots && page.Robots.NoIndex {
+			crawler.noIndexURLs = append(crawler.noIndexURLs, parentURL)
+		}
+
+		links := page.Links
+		if (duplicateOf != "" && crawler.options.SkipDuplicates) || (obeyRobots && page.Robots.NoFollow) {
+			links = nil
+		}
+
+		// Iterate over the links on the page we obtained:
+		for _, link := range links {
+			url := link.URL
+
+			if link.External && !crawler.options.CheckExternal {
+				continue
+			}
+
+			if obeyRobots && link.Nofollow {
+				continue
 			}
+
+			// The URLs of crawler traps aren't crawled, nor linked to in the sitemap:
+			if crawler.options.Traps != nil && !link.External && !crawler.checkedUrls.Contains(url) {
+				if _, trapped := crawler.options.Traps.Check(url); trapped {
+					continue
+				}
+			}
+
+			if !link.External {
+				childrenURLs = append(childrenURLs, crawler.annotate(url, link.Nofollow, false))
+			}
+			crawler.graph.AddEdge(graph.Edge{
+				Source:     parentURL,
+				Target:     url,
+				Kind:       string(link.Kind),
+				AnchorText: link.AnchorText,
+				Nofollow:   link.Nofollow,
+			})
+
+			// If we never crawled that url, then we do it now:
+			crawler.pushNew(&crawlerJob{url: url, depth: job.depth + 1, external: link.External})
+		}
+
+		if crawler.options.Assets && !job.external {
+			crawler.addAssets(page, job.depth+1)
 		}
 
-		log.logPage(parentURL, childrenURLs)
+		// Pages of other domains are only checked, they aren't part of the sitemap:
+		if !job.external && !(obeyRobots && page.Robots.NoIndex) {
+			issues := []audit.Issue{}
+			if crawler.options.SEOAudit {
+				issues = audit.PageSEO(node)
+			}
+			if crawler.options.Accessibility {
+				issues = append(issues, accessibilityIssues(page)...)
+			}
+			if crawler.options.Security {
+				issues = append(issues, securityIssues(page)...)
+			}
+			crawler.pageIssues = append(crawler.pageIssues, issues...)
+
+			// Only the HTML pages fetched have their text extracted:
+			if crawler.options.Index != nil && page.TextHash != "" {
+				if err := crawler.options.Index.Add(parentURL, page.Title, page.Text); err != nil {
+					crawler.logError("Crawler::onURLCrawled() - Error: failed to index " + parentURL + ": " + err.Error())
+				}
+			}
 
-		// if all the URLs launched for crawling had their crawling processes ended:
-		if len(crawler.checkedUrls) == crawler.nURLsCrawled {
-			crawler.pool.EndJobs()
+			if !crawler.options.Quiet {
+				crawler.log.logPage(crawler.annotate(parentURL, page.Robots.NoFollow, page.Robots.NoIndex), childrenURLs)
+				crawler.log.logIssues(issues)
+			}
 		}
+
+		crawler.done(job)
+		crawler.endIfFinished()
 	}
 
 	crawler.finishedFlag <- true
 }
+
+// addAssets launches the download of the resources of a page in the domain that weren't downloaded before.
+func (crawler *Crawler) addAssets(page *fetcher.Page, depth int) {
+	pageURL, err := url.Parse(page.URL)
+	if err != nil {
+		return
+	}
+
+	for _, asset := range page.Assets {
+		assetURL, err := url.Parse(asset)
+		if err != nil || assetURL.Host != pageURL.Host {
+			continue
+		}
+		crawler.pushNew(&crawlerJob{url: asset, depth: depth, asset: true})
+	}
+}
+
+// pushNew adds the job of an url that wasn't seen before to the frontier, marking it as seen. It's only seen
+// once it's in the frontier, so that it isn't lost if a persistent frontier is closed in between.
+func (crawler *Crawler) pushNew(job *crawlerJob) {
+	if crawler.checkedUrls.Contains(job.url) {
+		return
+	}
+	crawler.push(job)
+	crawler.checkedUrls.Add(job.url)
+}
+
+// push adds a job to the frontier, to be given to the pool when it has room for it (breadth first).
+func (crawler *Crawler) push(job *crawlerJob) {
+	if err := crawler.frontier.Push(jobItem(job)); err != nil {
+		crawler.logError("Crawler::push() - Error: failed to add " + job.url + " to the frontier: " + err.Error())
+	}
+}
+
+// done tells the frontier that the url of a job was crawled, once the urls it links to were added to it.
+func (crawler *Crawler) done(job *crawlerJob) {
+	if err := crawler.frontier.Done(jobItem(job)); err != nil {
+		crawler.logError("Crawler::done() - Error: failed to remove " + job.url + " from the frontier: " + err.Error())
+	}
+}
+
+// jobItem returns the item of the frontier of a job.
+func jobItem(job *crawlerJob) frontier.Item {
+	return frontier.Item{URL: job.url, Depth: job.depth, External: job.external, Asset: job.asset, Priority: job.depth}
+}
+
+// dispatch gives the jobs of the frontier to the pool, until it has maxInFlight jobs or the frontier is empty
+// (or none, if the crawl was cancelled).
+func (crawler *Crawler) dispatch() {
+	for crawler.inFlight < crawler.maxInFlight && !crawler.Progress().Cancelled {
+		item, ok, err := crawler.frontier.Pop()
+		if err != nil {
+			crawler.logError("Crawler::dispatch() - Error: failed to get an URL from the frontier: " + err.Error())
+			return
+		}
+		if !ok {
+			return
+		}
+
+		// The URLs of a resumed crawl weren't seen by this crawler yet:
+		crawler.checkedUrls.Add(item.URL)
+		crawler.pool.AddJob(&crawlerJob{crawler: crawler, url: item.URL, depth: item.Depth, external: item.External, asset: item.Asset})
+		crawler.inFlight++
+	}
+}
+
+// endIfFinished gives the pool the next jobs of the frontier, ending its jobs if there are none left
+// and all the URLs launched for crawling had their crawling processes ended.
+func (crawler *Crawler) endIfFinished() {
+	crawler.dispatch()
+
+	crawler.control.Lock()
+	queuedURLs.Add(float64(crawler.frontier.Len() - crawler.progress.Queued))
+	crawler.progress.Crawled = crawler.nURLsCrawled
+	crawler.progress.Queued = crawler.frontier.Len()
+	crawler.progress.InFlight = crawler.inFlight
+	crawler.control.Unlock()
+
+	if crawler.inFlight == 0 {
+		crawler.pool.EndJobs()
+	}
+}
+
+// Progress returns the progress of the crawl. It can be called while it runs.
+func (crawler *Crawler) Progress() Progress {
+	crawler.control.Lock()
+	defer crawler.control.Unlock()
+	return crawler.progress
+}
+
+// Pause stops crawling new URLs (the ones being crawled are finished) until Resume is called.
+func (crawler *Crawler) Pause() {
+	crawler.control.Lock()
+	defer crawler.control.Unlock()
+
+	if !crawler.progress.Paused && !crawler.progress.Cancelled {
+		crawler.progress.Paused = true
+		crawler.running = make(chan struct{})
+	}
+}
+
+// Resume resumes crawling the URLs, after the crawl was paused.
+func (crawler *Crawler) Resume() {
+	crawler.control.Lock()
+	defer crawler.control.Unlock()
+
+	if crawler.progress.Paused {
+		crawler.progress.Paused = false
+		close(crawler.running)
+	}
+}
+
+// Cancel stops crawling new URLs, so that Run returns with the pages crawled so far once the ones being
+// crawled are finished.
+func (crawler *Crawler) Cancel() {
+	crawler.control.Lock()
+	defer crawler.control.Unlock()
+
+	crawler.progress.Cancelled = true
+	if crawler.progress.Paused {
+		crawler.progress.Paused = false
+		close(crawler.running)
+	}
+}
+
+// waitIfPaused blocks while the crawl is paused. It returns false if the crawl was cancelled.
+func (crawler *Crawler) waitIfPaused() bool {
+	crawler.control.Lock()
+	running := crawler.running
+	crawler.control.Unlock()
+
+	<-running
+	return !crawler.Progress().Cancelled
+}
+
+// logError logs an error found while crawling, counting it in the progress of the crawl.
+func (crawler *Crawler) logError(msg string) {
+	crawler.control.Lock()
+	crawler.progress.Errors++
+	crawler.control.Unlock()
+
+	crawler.log.logError(msg)
+}
+
+// closedChannel returns a closed channel, from which receiving doesn't block.
+func closedChannel() chan struct{} {
+	running := make(chan struct{})
+	close(running)
+	return running
+}
@@ -1,10 +1,25 @@
 package crawler
 
 import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/msandim/web-crawler/audit"
 	"github.com/msandim/web-crawler/fetcher"
 	"github.com/msandim/web-crawler/fetcher/urlwrapper"
+	"github.com/msandim/web-crawler/frontier"
+	"github.com/msandim/web-crawler/search"
+	"github.com/msandim/web-crawler/seen"
+	"github.com/msandim/web-crawler/sitegen"
+	"github.com/msandim/web-crawler/traps"
 )
 
 func TestCrawler1(t *testing.T) {
@@ -35,21 +50,34 @@ func TestCrawler1(t *testing.T) {
 		t.Errorf("Finished flag channel was not initialized")
 	}
 
-	if pageFetcher == nil {
-		t.Errorf("PageFetcher global variable was not initialized")
+	if crawler.pageFetcher == nil {
+		t.Errorf("PageFetcher attribute was not initialized")
 	}
-	if _, ok := pageFetcher.(*fetcher.HTTPFetcher); !ok {
-		t.Errorf("PageFetcher global variable is not set for production")
+	if _, ok := crawler.pageFetcher.(*fetcher.HTTPFetcher); !ok {
+		t.Errorf("PageFetcher attribute is not set for production")
 	}
 }
 
-func TestCrawler2(t *testing.T) {
-	setUpTest()
+func TestCrawler_Fetcher(t *testing.T) {
+	testFetcher := &TestFetcher{}
+	crawler := NewWithOptions(5, 4, 10, "A", Options{Fetcher: testFetcher})
+
+	if crawler.pageFetcher != testFetcher {
+		t.Errorf("PageFetcher attribute is not the fetcher of the options")
+	}
 
-	crawler := newTesting(10, "A")
+	cassette := fetcher.NewCassette()
+	crawler = NewWithOptions(5, 4, 10, "A", Options{Fetcher: testFetcher, Cassette: cassette})
+	if _, ok := crawler.pageFetcher.(*fetcher.Recorder); !ok {
+		t.Errorf("PageFetcher attribute does not record the requests")
+	}
+}
+
+func TestCrawler2(t *testing.T) {
+	crawler := newTesting(10, "A", &TestFetcher{}, &testPrinter{})
 	crawler.Run()
 
-	testLog := log.(*testPrinter)
+	testLog := crawler.log.(*testPrinter)
 
 	if len(testLog.errorMsgs) != 0 {
 		t.Errorf("Number of error messages in crawling should be 0.")
@@ -98,6 +126,633 @@ func TestCrawler2(t *testing.T) {
 	}
 }
 
+func TestCrawler_Graph(t *testing.T) {
+	crawler := newTesting(10, "A", &TestFetcher{}, &testPrinter{})
+	crawler.Run()
+
+	sitemap := crawler.Graph()
+
+	if len(sitemap.Nodes) != 5 {
+		t.Errorf("Number of nodes was invalid. Expected: %d, Got: %d", 5, len(sitemap.Nodes))
+	}
+
+	if len(sitemap.Edges) != 8 {
+		t.Errorf("Number of edges was invalid. Expected: %d, Got: %d", 8, len(sitemap.Edges))
+	}
+
+	expectedDepths := map[string]int{"A": 0, "B": 1, "C": 1, "D": 2, "E": 2}
+	for url, depth := range expectedDepths {
+		node, ok := sitemap.Node(url)
+		if !ok {
+			t.Errorf("Node %s not found in the graph", url)
+			continue
+		}
+		if node.Depth != depth {
+			t.Errorf("Depth of %s was invalid. Expected: %d, Got: %d", url, depth, node.Depth)
+		}
+		if node.StatusCode != 200 {
+			t.Errorf("Status code of %s was invalid. Expected: %d, Got: %d", url, 200, node.StatusCode)
+		}
+	}
+
+	for _, edge := range sitemap.Edges {
+		if edge.AnchorText != "to "+edge.Target {
+			t.Errorf("Anchor text of edge %s -> %s was invalid: %s", edge.Source, edge.Target, edge.AnchorText)
+		}
+	}
+}
+
+func TestCrawler_CheckExternal(t *testing.T) {
+	crawler := newTesting(10, "A", &TestFetcher{}, &testPrinter{})
+	crawler.options = Options{CheckExternal: true, Quiet: true}
+	crawler.Run()
+
+	testLog := crawler.log.(*testPrinter)
+
+	if len(testLog.domainMap) != 0 {
+		t.Errorf("No pages should be logged in quiet mode, Got: %d", len(testLog.domainMap))
+	}
+
+	if len(testLog.errorMsgs) != 1 || testLog.errorMsgs[0] != "X not found" {
+		t.Errorf("Invalid error messages: %v", testLog.errorMsgs)
+	}
+
+	sitemap := crawler.Graph()
+
+	node, ok := sitemap.Node("X")
+	if !ok {
+		t.Fatalf("External page X was not checked")
+	}
+
+	if node.StatusCode != 404 || node.Depth != 3 {
+		t.Errorf("Invalid external node. Expected status 404 and depth 3, Got: %d and %d", node.StatusCode, node.Depth)
+	}
+
+	if len(sitemap.Edges) != 9 {
+		t.Errorf("Number of edges was invalid. Expected: %d, Got: %d", 9, len(sitemap.Edges))
+	}
+}
+
+func TestCrawler_Duplicates(t *testing.T) {
+	for _, skip := range []bool{false, true} {
+		crawler := newTesting(10, "A", &duplicateFetcher{}, &testPrinter{})
+		crawler.options = Options{SkipDuplicates: skip}
+		crawler.Run()
+
+		groups := crawler.Duplicates()
+		if len(groups) != 1 || !checkEqualSlices(groups[0], []string{"B", "C"}) {
+			t.Errorf("Invalid duplicate groups. Expected: [[B C]], Got: %v", groups)
+		}
+
+		if node, ok := crawler.Graph().Node("C"); !ok || node.DuplicateOf != "B" {
+			t.Errorf("C should be marked as a duplicate of B")
+		}
+
+		expectedNodes := 4
+		if skip {
+			expectedNodes = 3
+		}
+		if len(crawler.Graph().Nodes) != expectedNodes {
+			t.Errorf("Number of nodes was invalid (skip: %t). Expected: %d, Got: %d", skip, expectedNodes, len(crawler.Graph().Nodes))
+		}
+
+		testLog := crawler.log.(*testPrinter)
+		warning := "Crawler::Run() - Warning: pages with duplicate content: B, C"
+		if len(testLog.errorMsgs) != 1 || testLog.errorMsgs[0] != warning {
+			t.Errorf("Invalid error messages. Expected: [%s], Got: %v", warning, testLog.errorMsgs)
+		}
+	}
+}
+
+func TestCrawler_RobotsPolicy(t *testing.T) {
+	// Obey: B isn't followed (nofollow link) and C isn't reported (noindex):
+	crawler := newTesting(10, "A", &robotsFetcher{}, &testPrinter{})
+	crawler.options = Options{RobotsPolicy: RobotsObey}
+	crawler.Run()
+
+	testLog := crawler.log.(*testPrinter)
+	loggedPages := map[string][]string{}
+	for _, page := range testLog.domainMap {
+		loggedPages[page.parentURL] = page.childrenURLs
+	}
+
+	if len(loggedPages) != 2 || !checkEqualSlices(loggedPages["A"], []string{"C"}) || loggedPages["D"] == nil {
+		t.Errorf("Invalid pages logged when obeying robots: %v", loggedPages)
+	}
+
+	if len(crawler.Graph().Nodes) != 2 || len(crawler.Graph().Edges) != 0 {
+		t.Errorf("Invalid graph when obeying robots. Expected 2 nodes and 0 edges, Got: %d and %d",
+			len(crawler.Graph().Nodes), len(crawler.Graph().Edges))
+	}
+
+	// Annotate: everything is crawled and reported, with the directives annotated:
+	crawler = newTesting(10, "A", &robotsFetcher{}, &testPrinter{})
+	crawler.options = Options{RobotsPolicy: RobotsAnnotate}
+	crawler.Run()
+
+	testLog = crawler.log.(*testPrinter)
+	loggedPages = map[string][]string{}
+	for _, page := range testLog.domainMap {
+		loggedPages[page.parentURL] = page.childrenURLs
+	}
+
+	if len(loggedPages) != 5 || !checkEqualSlices(loggedPages["A"], []string{"B [nofollow]", "C"}) ||
+		!checkEqualSlices(loggedPages["C [noindex]"], []string{"D"}) {
+		t.Errorf("Invalid pages logged when annotating robots: %v", loggedPages)
+	}
+
+	if node, ok := crawler.Graph().Node("C"); !ok || !node.NoIndex || node.NoFollow {
+		t.Errorf("Robots directives of C were not recorded in the graph")
+	}
+}
+
+func TestCrawler_Canonical(t *testing.T) {
+	// The canonical URLs are only checked if asked:
+	crawler := newTesting(10, "A", &canonicalFetcher{}, &testPrinter{})
+	crawler.Run()
+	if issues := crawler.Issues(); len(issues) != 0 {
+		t.Errorf("Canonical URLs were checked without the audit: %v", issues)
+	}
+
+	crawler = newTesting(10, "A", &canonicalFetcher{}, &testPrinter{})
+	crawler.options = Options{DedupeCanonical: true, CanonicalAudit: true}
+	crawler.Run()
+
+	issues := crawler.Issues()
+	if len(issues) != 1 || issues[0].URL != "C" || issues[0].Message != "canonical URL D returned status 404" {
+		t.Errorf("Invalid issues: %v", issues)
+	}
+
+	testLog := crawler.log.(*testPrinter)
+	expectedMsgs := []string{
+		"Crawler::Run() - Warning: " + issues[0].String(),
+		"Crawler::Run() - Issues found: canonical-not-ok: 1",
+	}
+	if !checkEqualSlices(testLog.errorMsgs, expectedMsgs) {
+		t.Errorf("Invalid error messages. Expected: %v, Got: %v", expectedMsgs, testLog.errorMsgs)
+	}
+
+	// B is merged into its canonical page (A), but C isn't since its canonical page is broken:
+	if _, ok := crawler.Graph().Node("B"); ok || len(crawler.Graph().Nodes) != 3 {
+		t.Errorf("Pages were not deduped by canonical URL: %v", crawler.Graph().Nodes)
+	}
+	if len(crawler.Graph().Edges) != 2 {
+		t.Errorf("Invalid number of edges. Expected: %d, Got: %d", 2, len(crawler.Graph().Edges))
+	}
+}
+
+func TestCrawler_SEOAudit(t *testing.T) {
+	crawler := newTesting(10, "A", &seoFetcher{}, &testPrinter{})
+	crawler.options = Options{SEOAudit: true}
+	crawler.Run()
+
+	testLog := crawler.log.(*testPrinter)
+	loggedIssues := map[string][]string{}
+	for _, issue := range testLog.issues {
+		loggedIssues[issue.URL] = append(loggedIssues[issue.URL], issue.Rule)
+	}
+
+	if !checkEqualSlices(loggedIssues["A"], []string{"h1-multiple"}) ||
+		!checkEqualSlices(loggedIssues["B"], []string{"title-missing"}) || loggedIssues["C"] != nil {
+		t.Errorf("Invalid issues logged per page: %v", loggedIssues)
+	}
+
+	expectedMsgs := []string{
+		"Crawler::Run() - Warning: C - description-duplicate: same meta description as A",
+		"Crawler::Run() - Issues found: description-duplicate: 1, h1-multiple: 1, title-missing: 1",
+	}
+	if !checkEqualSlices(testLog.errorMsgs, expectedMsgs) {
+		t.Errorf("Invalid error messages. Expected: %v, Got: %v", expectedMsgs, testLog.errorMsgs)
+	}
+
+	if len(crawler.Issues()) != 3 {
+		t.Errorf("Invalid number of issues. Expected: %d, Got: %d", 3, len(crawler.Issues()))
+	}
+}
+
+func TestCrawler_Accessibility(t *testing.T) {
+	crawler := newTesting(10, "A", &accessibilityFetcher{}, &testPrinter{})
+	crawler.options = Options{Accessibility: true}
+	crawler.Run()
+
+	testLog := crawler.log.(*testPrinter)
+	expected := []audit.Issue{{URL: "B", Rule: fetcher.AccessibilityImageAlt, Message: `<img src="a.png"> has no alt attribute`}}
+	if len(testLog.issues) != 1 || testLog.issues[0] != expected[0] {
+		t.Errorf("Invalid issues logged. Expected: %v, Got: %v", expected, testLog.issues)
+	}
+
+	if len(crawler.Issues()) != 1 || crawler.Issues()[0] != expected[0] {
+		t.Errorf("Invalid issues. Expected: %v, Got: %v", expected, crawler.Issues())
+	}
+}
+
+func TestCrawler_Index(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crawler")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writer, err := search.NewWriter(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	crawler := newTesting(10, "A", &duplicateFetcher{}, &testPrinter{})
+	crawler.options = Options{Index: writer}
+	crawler.Run()
+	writer.Close()
+
+	// D has no text hash, as if it wasn't an HTML page:
+	index, err := search.Open(dir)
+	if err != nil || index.Len() != 3 {
+		t.Fatalf("Invalid index (error: %v)", err)
+	}
+}
+
+func TestCrawler_RecordReplay(t *testing.T) {
+	cassette := fetcher.NewCassette()
+
+	recorded := newTesting(10, "A", fetcher.NewRecorder(&TestFetcher{}, cassette), &testPrinter{})
+	recorded.options = Options{CheckExternal: true, Quiet: true}
+	recorded.Run()
+
+	// The 5 pages fetched and the external link checked:
+	if cassette.Len() != 6 {
+		t.Errorf("Number of requests recorded was invalid. Expected: %d, Got: %d", 6, cassette.Len())
+	}
+
+	replayed := newTesting(10, "A", fetcher.NewReplayer(cassette), &testPrinter{})
+	replayed.options = Options{CheckExternal: true, Quiet: true}
+	replayed.Run()
+
+	testLog := replayed.log.(*testPrinter)
+	if len(testLog.errorMsgs) != 1 || testLog.errorMsgs[0] != "X not found" {
+		t.Errorf("Invalid error messages: %v", testLog.errorMsgs)
+	}
+
+	if len(replayed.Graph().Nodes) != len(recorded.Graph().Nodes) || len(replayed.Graph().Edges) != len(recorded.Graph().Edges) {
+		t.Errorf("Replayed graph is different. Expected: %d nodes and %d edges, Got: %d nodes and %d edges",
+			len(recorded.Graph().Nodes), len(recorded.Graph().Edges), len(replayed.Graph().Nodes), len(replayed.Graph().Edges))
+	}
+
+	if node, ok := replayed.Graph().Node("X"); !ok || node.StatusCode != 404 {
+		t.Errorf("External page X was not replayed: %+v", node)
+	}
+}
+
+func TestCrawler_SyntheticSite(t *testing.T) {
+	crawlSyntheticSite(t, sitegen.Spec{
+		Pages: 1000, FanOut: 8, CrossLinks: 2, Seed: 7,
+		NotFound: 0.05, Redirects: 0.05, Slow: 0.02, NonHTML: 0.05, SlowDelay: 10 * time.Millisecond,
+	}, Options{Quiet: true})
+}
+
+func TestCrawler_SeenSets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crawler")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	disk, err := seen.NewDiskSet(dir, 100)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer disk.Close()
+
+	spec := sitegen.Spec{Pages: 1000, FanOut: 8, CrossLinks: 2, Seed: 8, NotFound: 0.05}
+	for _, set := range []seen.Set{seen.NewHashSet(), seen.NewBloomFilter(10000, 0.00001), disk} {
+		crawlSyntheticSite(t, spec, Options{Quiet: true, Seen: set})
+	}
+}
+
+func TestCrawler_Frontier(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crawler")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	disk, err := frontier.OpenDisk(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer disk.Close()
+
+	spec := sitegen.Spec{Pages: 1000, FanOut: 8, CrossLinks: 2, Seed: 9, NotFound: 0.05, Redirects: 0.05}
+	crawlSyntheticSite(t, spec, Options{Quiet: true, Frontier: disk})
+	if disk.Len() != 0 {
+		t.Errorf("Length of the frontier was invalid. Expected: %d, Got: %d", 0, disk.Len())
+	}
+}
+
+func TestCrawler_Frontier_Resume(t *testing.T) {
+	server, _ := sitegen.NewServer(sitegen.Spec{Pages: 50, FanOut: 5, Seed: 3})
+	defer server.Close()
+
+	// The frontier left by an interrupted crawl has the URLs it was going to crawl:
+	pending := frontier.NewMemory()
+	pending.Push(frontier.Item{URL: sitegen.URL(server, "/p/7"), Depth: 2, Priority: 2})
+
+	crawler := NewWithOptions(8, 8, 10, sitegen.URL(server, "/"), Options{Quiet: true, Frontier: pending, Errors: ioutil.Discard})
+	crawler.Run()
+
+	node, ok := crawler.Graph().Node(sitegen.URL(server, "/p/7"))
+	if !ok || node.Depth != 2 {
+		t.Errorf("Resumed page was not crawled from the frontier: %v", node)
+	}
+	if _, ok := crawler.Graph().Node(sitegen.URL(server, "/")); ok {
+		t.Errorf("Domain was crawled again when resuming the crawl")
+	}
+}
+
+func TestCrawler_Frontier_Interrupted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crawler")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	disk, err := frontier.OpenDisk(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The crawl is interrupted after 100 pages: the frontier is closed (as when the program is interrupted)
+	// while pages are being crawled:
+	site := sitegen.Generate(sitegen.Spec{Pages: 500, FanOut: 5, CrossLinks: 2, Seed: 12})
+	mutex := sync.Mutex{}
+	runs := []map[string]int{{}, {}}
+	run := 0
+	var interrupted *Crawler
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		runs[run][r.URL.Path]++
+		if run == 0 && len(runs[0]) == 100 {
+			disk.Close()
+			interrupted.Cancel()
+		}
+		mutex.Unlock()
+		site.Handler().ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	interrupted = NewWithOptions(8, 8, 10, sitegen.URL(server, "/"), Options{Quiet: true, Seen: disk.Seen(), Frontier: disk, Errors: ioutil.Discard})
+	interrupted.Run()
+
+	// The crawl resumed from the frontier crawls the pages left, and only crawls again the ones that were
+	// being crawled when it was interrupted:
+	mutex.Lock()
+	run = 1
+	mutex.Unlock()
+	disk, err = frontier.OpenDisk(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer disk.Close()
+	if disk.Len() == 0 {
+		t.Fatalf("Frontier of the interrupted crawl was empty")
+	}
+
+	resumed := NewWithOptions(8, 8, 10, sitegen.URL(server, "/"), Options{Quiet: true, Seen: disk.Seen(), Frontier: disk, Errors: ioutil.Discard})
+	resumed.Run()
+
+	again := 0
+	for path := range site.Reachable() {
+		if runs[0][path]+runs[1][path] == 0 {
+			t.Errorf("Page %s was not crawled", path)
+		}
+		if runs[0][path] > 0 && runs[1][path] > 0 {
+			again++
+		}
+	}
+	for _, requests := range runs {
+		for path, n := range requests {
+			if n > 1 {
+				t.Errorf("Page %s was crawled %d times by the same crawl", path, n)
+			}
+		}
+	}
+	if maxInFlight := 2 * 8; again > maxInFlight {
+		t.Errorf("Number of pages crawled again was invalid. Expected: at most %d, Got: %d", maxInFlight, again)
+	}
+	if disk.Len() != 0 {
+		t.Errorf("Length of the frontier was invalid. Expected: %d, Got: %d", 0, disk.Len())
+	}
+}
+
+func TestCrawler_PauseResume(t *testing.T) {
+	server, site := sitegen.NewServer(sitegen.Spec{Pages: 200, FanOut: 5, Seed: 6})
+	defer server.Close()
+
+	// Nothing is crawled while the crawl is paused:
+	crawler := NewWithOptions(8, 8, 10, sitegen.URL(server, "/"), Options{Quiet: true, Errors: ioutil.Discard})
+	crawler.Pause()
+	finished := make(chan bool)
+	go func() {
+		crawler.Run()
+		finished <- true
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	if progress := crawler.Progress(); !progress.Paused || progress.Crawled != 0 || progress.InFlight != 1 {
+		t.Errorf("Invalid progress of the paused crawl: %+v", progress)
+	}
+
+	crawler.Resume()
+	<-finished
+
+	progress := crawler.Progress()
+	if progress.Paused || progress.Crawled != len(site.Reachable()) || progress.Queued != 0 || progress.InFlight != 0 {
+		t.Errorf("Invalid progress of the crawl: %+v", progress)
+	}
+}
+
+func TestCrawler_Cancel(t *testing.T) {
+	server, _ := sitegen.NewServer(sitegen.Spec{Pages: 200, FanOut: 5, Seed: 6})
+	defer server.Close()
+
+	// The crawl ends without crawling the URLs waiting, even if it was paused:
+	crawler := NewWithOptions(8, 8, 10, sitegen.URL(server, "/"), Options{Quiet: true, Errors: ioutil.Discard})
+	crawler.Pause()
+	finished := make(chan bool)
+	go func() {
+		crawler.Run()
+		finished <- true
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	crawler.Cancel()
+
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Cancelled crawl didn't end")
+	}
+
+	if progress := crawler.Progress(); !progress.Cancelled || progress.Paused || len(crawler.Graph().Nodes) != 0 {
+		t.Errorf("Invalid progress of the cancelled crawl: %+v (%d pages)", progress, len(crawler.Graph().Nodes))
+	}
+}
+
+func TestCrawler_Progress(t *testing.T) {
+	// The assets downloaded aren't counted as pages crawled:
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body><img src="/a.png"><img src="/b.png"><a href="/c">C</a></body></html>`))
+		case "/c":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body><p>C</p></body></html>`))
+		default:
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("png"))
+		}
+	}))
+	defer server.Close()
+
+	crawler := NewWithOptions(4, 4, 10, server.URL+"/", Options{Quiet: true, Assets: true, Errors: ioutil.Discard})
+	crawler.Run()
+	if progress := crawler.Progress(); progress.Crawled != 2 {
+		t.Errorf("Number of pages crawled was invalid. Expected: %d, Got: %d", 2, progress.Crawled)
+	}
+
+	// The URLs not crawled as the crawl was cancelled aren't counted, and the ones left in its frontier
+	// aren't waiting anymore:
+	site := sitegen.Generate(sitegen.Spec{Pages: 300, FanOut: 5, Seed: 13})
+	queued := queuedURLs.Value()
+	requests := 0
+	mutex := sync.Mutex{}
+	var cancelled *Crawler
+	siteServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		if requests++; requests == 20 {
+			cancelled.Cancel()
+		}
+		mutex.Unlock()
+		site.Handler().ServeHTTP(w, r)
+	}))
+	defer siteServer.Close()
+
+	cancelled = NewWithOptions(8, 8, 10, sitegen.URL(siteServer, "/"), Options{Quiet: true, Errors: ioutil.Discard})
+	cancelled.Run()
+	progress := cancelled.Progress()
+	if progress.Crawled != len(cancelled.Graph().Nodes) || progress.Queued == 0 {
+		t.Errorf("Invalid progress of the cancelled crawl. Expected: %d pages crawled, Got: %+v", len(cancelled.Graph().Nodes), progress)
+	}
+	if queuedURLs.Value() != queued {
+		t.Errorf("Number of URLs queued was invalid. Expected: %v, Got: %v", queued, queuedURLs.Value())
+	}
+}
+
+func TestCrawler_Output(t *testing.T) {
+	// Crawlers running at the same time don't share their fetcher nor their output:
+	outputs := make([]bytes.Buffer, 2)
+	errs := make([]bytes.Buffer, 2)
+	wg := sync.WaitGroup{}
+	for i := range outputs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			crawler := NewWithOptions(4, 4, 10, "A", Options{Fetcher: &TestFetcher{}, Output: &outputs[i], Errors: &errs[i]})
+			crawler.Run()
+			if progress := crawler.Progress(); progress.Crawled != 5 || progress.Errors != 0 {
+				t.Errorf("Invalid progress of the crawl: %+v", progress)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range outputs {
+		if strings.Count(outputs[i].String(), ". ") != 5 || !strings.Contains(outputs[i].String(), ". A\n  -> B\n  -> C\n") {
+			t.Errorf("Invalid sitemap printed: %s", outputs[i].String())
+		}
+		if errs[i].Len() != 0 {
+			t.Errorf("Invalid errors printed: %s", errs[i].String())
+		}
+	}
+}
+
+func TestCrawler_SyntheticSite_10k(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping the crawl of 10k pages in short mode")
+	}
+
+	crawlSyntheticSite(t, sitegen.Spec{
+		Pages: 10000, FanOut: 10, MaxDepth: 6, CrossLinks: 3, Seed: 10,
+		NotFound: 0.02, Redirects: 0.02, NonHTML: 0.02,
+	}, Options{Quiet: true})
+}
+
+// crawlSyntheticSite crawls the site generated from a spec with some options and compares the graph obtained
+// with its ground truth.
+func crawlSyntheticSite(t *testing.T, spec sitegen.Spec, options Options) {
+	server, site := sitegen.NewServer(spec)
+	defer server.Close()
+
+	options.Errors = ioutil.Discard
+	crawler := NewWithOptions(32, 32, 10, sitegen.URL(server, "/"), options)
+	crawler.Run()
+	sitemap := crawler.Graph()
+
+	reachable := site.Reachable()
+	if len(sitemap.Nodes) != len(reachable) {
+		t.Errorf("Number of pages crawled was invalid. Expected: %d, Got: %d", len(reachable), len(sitemap.Nodes))
+	}
+
+	for path := range reachable {
+		page, _ := site.Page(path)
+		node, ok := sitemap.Node(sitegen.URL(server, path))
+		if !ok {
+			t.Errorf("Page %s was not crawled", path)
+			continue
+		}
+		if node.StatusCode != page.StatusCode() {
+			t.Errorf("Invalid status code of %s. Expected: %d, Got: %d", path, page.StatusCode(), node.StatusCode)
+		}
+	}
+
+	if len(sitemap.Edges) != len(site.Edges()) {
+		t.Errorf("Number of links was invalid. Expected: %d, Got: %d", len(site.Edges()), len(sitemap.Edges))
+	}
+}
+
+func TestCrawler_Traps(t *testing.T) {
+	server, site := sitegen.NewServer(sitegen.Spec{Pages: 50, FanOut: 5, Seed: 3, CalendarTrap: true, PathTrap: true})
+	defer server.Close()
+
+	limits := traps.DefaultLimits
+	limits.MaxPerPattern = 100
+	crawler := NewWithOptions(8, 8, 10, sitegen.URL(server, "/"), Options{Quiet: true, Traps: traps.New(limits), Errors: ioutil.Discard})
+	crawler.Run()
+
+	// The pages of the site, the months of the calendar until the budget and /loop/ repeated up to 3 times:
+	expected := len(site.Reachable()) + 100 + 3
+	if len(crawler.Graph().Nodes) != expected {
+		t.Errorf("Number of pages crawled was invalid. Expected: %d, Got: %d", expected, len(crawler.Graph().Nodes))
+	}
+
+	rules := map[string]int{}
+	for _, issue := range crawler.Issues() {
+		rules[issue.Rule]++
+	}
+	if rules[traps.RulePatternBudget] != 1 || rules[traps.RuleRepeatingSegments] != 1 {
+		t.Errorf("Invalid traps reported: %v", crawler.Issues())
+	}
+}
+
+func BenchmarkCrawler_SyntheticSite(b *testing.B) {
+	server, _ := sitegen.NewServer(sitegen.Spec{Pages: 1000, FanOut: 8, CrossLinks: 2, Seed: 1})
+	defer server.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewWithOptions(32, 32, 10, sitegen.URL(server, "/"), Options{Quiet: true, Errors: ioutil.Discard}).Run()
+	}
+}
+
 func checkMatchingChildren(t *testing.T, page string, expectedChildren []string, obtainedChildren []string) {
 	if !checkEqualSlices(expectedChildren, obtainedChildren) {
 		t.Errorf("Children URLs for %s are not correct. Expected: %v, Obtained: %v",
@@ -126,29 +781,156 @@ func checkEqualSlices(s1 []string, s2 []string) bool {
 	return true
 }
 
-func setUpTest() {
-	pageFetcher = &TestFetcher{}
-	log = &testPrinter{}
+type TestFetcher struct {
 }
 
-type TestFetcher struct {
+func (testFetcher *TestFetcher) Fetch(urlArg *urlwrapper.URLWrapper) (*fetcher.Page, []error) {
+	switch urlArg.URL {
+	case "A":
+		return testPage(urlArg.URL, "B", "C"), nil
+	case "B":
+		return testPage(urlArg.URL, "C", "D"), nil
+	case "C":
+		return testPage(urlArg.URL, "A", "B", "E", "D"), nil
+	case "E":
+		page := testPage(urlArg.URL)
+		page.Links = append(page.Links, fetcher.Link{URL: "X", Kind: fetcher.LinkAnchor, AnchorText: "to X", External: true})
+		return page, nil
+	default:
+		return testPage(urlArg.URL), nil
+	}
 }
 
-func (fetcher *TestFetcher) Fetch(urlArg *urlwrapper.URLWrapper) ([]string, []error) {
+func (testFetcher *TestFetcher) Check(url string) (int, error) {
+	if url == "X" {
+		return 404, errors.New("X not found")
+	}
+	return 200, nil
+}
+
+func testPage(url string, childrenURLs ...string) *fetcher.Page {
+	page := &fetcher.Page{URL: url, StatusCode: 200, ContentType: "text/html", Links: []fetcher.Link{}}
+	for _, childURL := range childrenURLs {
+		page.Links = append(page.Links, fetcher.Link{URL: childURL, Kind: fetcher.LinkAnchor, AnchorText: "to " + childURL})
+	}
+	return page
+}
+
+// duplicateFetcher fetches the pages A -> B -> C -> D, in which B and C have the same content.
+type duplicateFetcher struct {
+}
+
+func (duplicateFetcher *duplicateFetcher) Fetch(urlArg *urlwrapper.URLWrapper) (*fetcher.Page, []error) {
 	switch urlArg.URL {
 	case "A":
-		return []string{"B", "C"}, nil
+		page := testPage(urlArg.URL, "B")
+		page.TextHash, page.SimHash = "hashA", 0x0
+		return page, nil
 	case "B":
-		return []string{"C", "D"}, nil
+		page := testPage(urlArg.URL, "C")
+		page.TextHash, page.SimHash = "hashB", 0xFFFF
+		return page, nil
 	case "C":
-		return []string{"A", "B", "E", "D"}, nil
+		page := testPage(urlArg.URL, "D")
+		page.TextHash, page.SimHash = "hashB", 0xFFFF
+		return page, nil
 	default:
-		return []string{}, nil
+		return testPage(urlArg.URL), nil
 	}
 }
 
+// robotsFetcher fetches the pages A -> (B, C), B -> E and C -> D,
+// in which the link to B is nofollow and C is noindex.
+type robotsFetcher struct {
+}
+
+func (robotsFetcher *robotsFetcher) Fetch(urlArg *urlwrapper.URLWrapper) (*fetcher.Page, []error) {
+	switch urlArg.URL {
+	case "A":
+		page := testPage(urlArg.URL, "B", "C")
+		page.Links[0].Nofollow = true
+		return page, nil
+	case "B":
+		return testPage(urlArg.URL, "E"), nil
+	case "C":
+		page := testPage(urlArg.URL, "D")
+		page.Robots.NoIndex = true
+		return page, nil
+	default:
+		return testPage(urlArg.URL), nil
+	}
+}
+
+// canonicalFetcher fetches the pages A -> (B, C), in which B is canonicalized to A
+// and C is canonicalized to D, which returns 404.
+type canonicalFetcher struct {
+}
+
+func (canonicalFetcher *canonicalFetcher) Fetch(urlArg *urlwrapper.URLWrapper) (*fetcher.Page, []error) {
+	switch urlArg.URL {
+	case "A":
+		return testPage(urlArg.URL, "B", "C"), nil
+	case "B":
+		page := testPage(urlArg.URL)
+		page.Canonical = "A"
+		page.Links = append(page.Links, fetcher.Link{URL: "A", Kind: fetcher.LinkCanonical})
+		return page, nil
+	case "C":
+		page := testPage(urlArg.URL)
+		page.Canonical = "D"
+		page.Links = append(page.Links, fetcher.Link{URL: "D", Kind: fetcher.LinkCanonical})
+		return page, nil
+	default:
+		page := testPage(urlArg.URL)
+		page.StatusCode = 404
+		return page, nil
+	}
+}
+
+// seoFetcher fetches the pages A -> B -> C, in which A has two <h1>, B has no title
+// and C has the same description as A.
+type seoFetcher struct {
+}
+
+func (seoFetcher *seoFetcher) Fetch(urlArg *urlwrapper.URLWrapper) (*fetcher.Page, []error) {
+	page := testPage(urlArg.URL)
+	page.Title = "Page " + urlArg.URL
+	page.Description = "Description of " + urlArg.URL
+	page.WordCount = 500
+
+	switch urlArg.URL {
+	case "A":
+		page.Links = testPage("", "B").Links
+		page.Headings = []fetcher.Heading{{Level: 1, Text: "One"}, {Level: 2, Text: "Sub"}, {Level: 1, Text: "Two"}}
+	case "B":
+		page.Links = testPage("", "C").Links
+		page.Title = ""
+	case "C":
+		page.Description = "Description of A"
+	}
+	return page, nil
+}
+
+// accessibilityFetcher fetches the pages A -> B, in which B has an image without alt text.
+type accessibilityFetcher struct {
+}
+
+func (accessibilityFetcher *accessibilityFetcher) Fetch(urlArg *urlwrapper.URLWrapper) (*fetcher.Page, []error) {
+	if urlArg.URL == "A" {
+		return testPage(urlArg.URL, "B"), nil
+	}
+
+	page := testPage(urlArg.URL)
+	page.Accessibility = []fetcher.AccessibilityViolation{
+		{Rule: fetcher.AccessibilityImageAlt, Element: `<img src="a.png">`, Message: "has no alt attribute"},
+	}
+	return page, nil
+}
+
 type testPrinter struct {
+	mutex     sync.Mutex // errors are logged by the workers concurrently
 	domainMap []parentPage
+	issues    []audit.Issue
 	errorMsgs []string
 }
 
@@ -164,6 +946,12 @@ func (log *testPrinter) logPage(parentURL string, childrenURLs []string) {
 	})
 }
 
+func (log *testPrinter) logIssues(issues []audit.Issue) {
+	log.issues = append(log.issues, issues...)
+}
+
 func (log *testPrinter) logError(msg string) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
 	log.errorMsgs = append(log.errorMsgs, msg)
 }
@@ -1,6 +1,7 @@
 package crawler
 
 import (
+	"github.com/msandim/web-crawler/fetcher"
 	"github.com/msandim/web-crawler/fetcher/urlwrapper"
 	"github.com/msandim/web-crawler/workerpool"
 )
@@ -8,25 +9,75 @@ import (
 // Implementation of the Crawling Jobs for the Worker Pool:
 
 type crawlerJob struct {
-	url string
+	crawler  *Crawler // crawler that launched the job, with the fetcher and logger used
+	url      string
+	depth    int
+	external bool // the url is from another domain, so it's only checked, not crawled
+	asset    bool // the url is a resource of a page (e.g. an image), so it's only downloaded, not crawled
 }
 
 type crawlerJobResult struct {
-	urls []string
-	job  *crawlerJob
+	page      *fetcher.Page // nil if the url wasn't crawled
+	job       *crawlerJob
+	cancelled bool // the url wasn't crawled, as the crawl was cancelled
 }
 
 func (job *crawlerJob) Process() workerpool.JobResult {
-	obtainedURLs, errs := pageFetcher.Fetch(urlwrapper.New(job.url))
+	crawler := job.crawler
+	if !crawler.waitIfPaused() {
+		return &crawlerJobResult{job: job, cancelled: true}
+	}
+
+	if job.external {
+		return &crawlerJobResult{page: crawler.checkPage(job.url), job: job}
+	}
+	if job.asset {
+		return &crawlerJobResult{page: crawler.downloadAsset(job.url), job: job}
+	}
+
+	page, errs := crawler.pageFetcher.Fetch(urlwrapper.New(job.url))
 
 	for _, err := range errs {
-		log.logError(err.Error())
+		crawler.logError(err.Error())
 	}
 
-	result := &crawlerJobResult{urls: obtainedURLs, job: job}
+	result := &crawlerJobResult{page: page, job: job}
 	return result
 }
 
 func (result *crawlerJobResult) GetJob() workerpool.Job {
 	return result.job
 }
+
+// checkPage checks if the page of an url is reachable, without fetching it.
+func (crawler *Crawler) checkPage(url string) *fetcher.Page {
+	page := &fetcher.Page{URL: url, Size: -1, Links: []fetcher.Link{}}
+
+	checker, ok := crawler.pageFetcher.(fetcher.Checker)
+	if !ok {
+		crawler.logError("crawlerJob::checkPage() - Error: the fetcher can't check external URLs: " + url)
+		return page
+	}
+
+	statusCode, err := checker.Check(url)
+	if err != nil {
+		crawler.logError(err.Error())
+	}
+	page.StatusCode = statusCode
+	return page
+}
+
+// downloadAsset downloads a resource of a page (e.g. an image), without crawling it.
+func (crawler *Crawler) downloadAsset(url string) *fetcher.Page {
+	downloader, ok := crawler.pageFetcher.(fetcher.Downloader)
+	if !ok {
+		crawler.logError("crawlerJob::downloadAsset() - Error: the fetcher can't download assets: " + url)
+		return &fetcher.Page{URL: url, Size: -1, Links: []fetcher.Link{}}
+	}
+
+	page, err := downloader.Download(url)
+	if err != nil {
+		crawler.logError(err.Error())
+	}
+	return page
+}
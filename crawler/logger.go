@@ -2,23 +2,49 @@ package crawler
 
 import (
 	"fmt"
+	"io"
 	"os"
+
+	"github.com/msandim/web-crawler/audit"
 )
 
 type logger interface {
 	logPage(parentURL string, childrenURLs []string)
+	logIssues(issues []audit.Issue)
 	logError(msg string)
 }
 
-type printer struct{}
+// printer prints the sitemap to an output and the errors to another one.
+type printer struct {
+	out  io.Writer
+	errs io.Writer
+}
+
+// newPrinter returns a printer to an output and errors writer (os.Stdout and os.Stderr if nil).
+func newPrinter(out io.Writer, errs io.Writer) *printer {
+	if out == nil {
+		out = os.Stdout
+	}
+	if errs == nil {
+		errs = os.Stderr
+	}
+	return &printer{out: out, errs: errs}
+}
 
 func (log *printer) logPage(parentURL string, childrenURLs []string) {
-	fmt.Println(". " + parentURL)
+	fmt.Fprintln(log.out, ". "+parentURL)
 	for _, childURL := range childrenURLs {
-		fmt.Println("  -> " + childURL)
+		fmt.Fprintln(log.out, "  -> "+childURL)
+	}
+}
+
+// logIssues prints the issues found in the page printed last, below its children.
+func (log *printer) logIssues(issues []audit.Issue) {
+	for _, issue := range issues {
+		fmt.Fprintln(log.out, "  ! "+issue.Rule+": "+issue.Message)
 	}
 }
 
 func (log *printer) logError(msg string) {
-	fmt.Fprintln(os.Stderr, msg)
+	fmt.Fprintln(log.errs, msg)
 }
@@ -0,0 +1,7 @@
+package crawler
+
+import "github.com/msandim/web-crawler/metrics"
+
+// Metrics of the crawls:
+var queuedURLs = metrics.NewGauge("web_crawler_frontier_urls",
+	"Number of URLs waiting to be crawled in the frontiers of the crawls (the queue depth).")
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/msandim/web-crawler/graph"
+)
+
+// runDiff compares two crawls saved with the -json flag and outputs the changes.
+// It returns the exit code of the program.
+func runDiff(arguments []string) int {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "output the changes as JSON instead of text")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: web-crawler diff [-json] before.json after.json")
+		flags.PrintDefaults()
+	}
+	flags.Parse(arguments)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return -1
+	}
+
+	before, err := readGraph(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "main::runDiff() - Error: failed to read the crawl: ", flags.Arg(0), err)
+		return -1
+	}
+
+	after, err := readGraph(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "main::runDiff() - Error: failed to read the crawl: ", flags.Arg(1), err)
+		return -1
+	}
+
+	diff := graph.Compare(before, after)
+
+	if *asJSON {
+		err = diff.WriteJSON(os.Stdout)
+	} else {
+		err = diff.WriteText(os.Stdout)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "main::runDiff() - Error: failed to write the changes: ", err)
+		return -1
+	}
+	return 0
+}
+
+// readGraph reads a crawl saved as JSON.
+func readGraph(path string) (*graph.Graph, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return graph.ReadJSON(f)
+}
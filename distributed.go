@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/msandim/web-crawler/distributed"
+	"github.com/msandim/web-crawler/fetcher"
+	"github.com/msandim/web-crawler/frontier"
+)
+
+// workersGracePeriod is the time during which the coordinator keeps answering after the crawl ends,
+// so that the workers waiting for URLs find out that it ended.
+const workersGracePeriod = 2 * time.Second
+
+// runCoordinator coordinates the distributed crawl of a domain by the workers that connect to it, and saves
+// the crawl when it ends. It returns the exit code of the program.
+func runCoordinator(arguments []string) int {
+	flags := flag.NewFlagSet("coordinator", flag.ExitOnError)
+	listen := flags.String("listen", ":8090", "address on which the coordinator listens to the workers")
+	domain := flags.String("domain", "https://www.monzo.com", "the domain to crawl")
+	external := flags.Bool("external", false, "also check (with HTTP HEAD requests, without crawling them) the links to other domains")
+	leaseTimeout := flags.Int("leasetimeout", int(distributed.DefaultLeaseTimeout/time.Second), "number of seconds after which the URLs leased to a worker are leased to another one")
+	frontierDir := flags.String("frontierdir", "", "directory in which the URLs waiting to be crawled are kept (instead of memory), to resume the crawl from it if it's interrupted")
+	outputs := outputFiles{}
+	flags.StringVar(&outputs.report, "report", "", "file to which a self-contained HTML report of the crawl is written")
+	flags.StringVar(&outputs.json, "json", "", "file to which the crawl is saved as JSON (e.g. to be compared with the diff command)")
+	flags.Parse(arguments)
+
+	if !isDomainValid(*domain) {
+		fmt.Fprintln(os.Stderr, "main::runCoordinator() - Error: Domain is invalid: ", *domain)
+		return -1
+	}
+	if *leaseTimeout <= 0 {
+		fmt.Fprintln(os.Stderr, "main::runCoordinator() - Error: Lease timeout (seconds) is invalid: ", *leaseTimeout)
+		return -1
+	}
+
+	options := distributed.Options{
+		CheckExternal: *external,
+		LeaseTimeout:  time.Duration(*leaseTimeout) * time.Second,
+		Frontier:      openFrontier(*frontierDir),
+		Errors:        os.Stderr,
+	}
+	if disk, ok := options.Frontier.(*frontier.Disk); ok {
+		options.Seen = disk.Seen()
+	}
+	coordinator := distributed.NewCoordinator(*domain, options)
+
+	server := &http.Server{Addr: *listen, Handler: coordinator}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Fprintln(os.Stderr, "main::runCoordinator() - Error: failed to listen to the workers: ", *listen, err)
+			os.Exit(-1)
+		}
+	}()
+	fmt.Println("Coordinating the crawl of ", *domain, " on ", *listen)
+
+	sitemap := coordinator.Wait()
+	time.Sleep(workersGracePeriod)
+	server.Close()
+	closeFrontier(options.Frontier)
+
+	fmt.Println("Pages crawled: ", len(sitemap.Nodes))
+	writeOutputs(sitemap, nil, outputs)
+	return 0
+}
+
+// runWorker crawls the URLs leased by a coordinator until the crawl ends. It returns the exit code of the program.
+func runWorker(arguments []string) int {
+	hostname, _ := os.Hostname()
+
+	flags := flag.NewFlagSet("worker", flag.ExitOnError)
+	coordinator := flags.String("coordinator", "http://localhost:8090", "base URL of the coordinator")
+	id := flags.String("id", hostname+"-"+strconv.Itoa(os.Getpid()), "name of the worker, unique among the workers of the crawl")
+	nWorkers := flags.Int("nworkers", 4, "the number of URLs crawled at the same time")
+	rateLimit := flags.Int("ratelimit", 4, "the number of HTTP requests that can be done at the same time")
+	timeoutSeconds := flags.Int("timeoutseconds", 10, "The number of seconds to wait for a HTTP GET request")
+	metricsAddr := flags.String("metrics-addr", "", "address on which the metrics of the worker are served at /metrics, in the Prometheus text format (e.g. :9090)")
+	flags.Parse(arguments)
+
+	if !isnWorkersValid(*nWorkers) {
+		fmt.Fprintln(os.Stderr, "main::runWorker() - Error: Number of workers is invalid: ", *nWorkers)
+		return -1
+	}
+	if !isRateLimitValid(*rateLimit) {
+		fmt.Fprintln(os.Stderr, "main::runWorker() - Error: Rate limit is invalid: ", *rateLimit)
+		return -1
+	}
+	if !isTimeoutSecondsValid(*timeoutSeconds) {
+		fmt.Fprintln(os.Stderr, "main::runWorker() - Error: Timeout (seconds) is invalid: ", *timeoutSeconds)
+		return -1
+	}
+
+	serveMetrics(*metricsAddr)
+	worker := distributed.NewWorker(*id, *coordinator, fetcher.NewHTTPFetcher(*rateLimit, *timeoutSeconds), *nWorkers)
+	if err := worker.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "main::runWorker() - Error: failed to reach the coordinator: ", *coordinator, err)
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,286 @@
+package distributed
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/msandim/web-crawler/crawler"
+	"github.com/msandim/web-crawler/fetcher"
+	"github.com/msandim/web-crawler/frontier"
+	"github.com/msandim/web-crawler/graph"
+	"github.com/msandim/web-crawler/seen"
+)
+
+// DefaultLeaseTimeout is the time after which the URLs leased to a worker are leased again, if not set.
+const DefaultLeaseTimeout = 30 * time.Second
+
+// Options are the optional settings of a Coordinator.
+type Options struct {
+	CheckExternal bool          // check (without crawling them) the links to other domains
+	LeaseTimeout  time.Duration // time after which the URLs leased to a worker are leased again (DefaultLeaseTimeout if 0)
+
+	// URLs waiting to be leased (frontier.NewMemory if nil), and URLs in which the crawling process was
+	// initiated (seen.NewMap if nil). The URLs leased are only done in the frontier when their results are
+	// completed, so that a crawl resumed from a persistent frontier (along with its set, e.g. the one kept by
+	// frontier.Disk) leases them again:
+	Seen     seen.Set
+	Frontier frontier.Frontier
+
+	Errors io.Writer // to which the errors found by the workers are written (discarded if nil)
+}
+
+// Coordinator is the process of a distributed crawl that owns its frontier and set of URLs seen, leases
+// the URLs to crawl to the workers and builds the link graph from the pages they fetch. It is an
+// http.Handler, to be served to the workers.
+type Coordinator struct {
+	domain  string
+	options Options
+
+	mutex     sync.Mutex
+	leases    map[string]*lease // by ID
+	nextLease int
+	crawled   int
+	expired   int
+	graph     *graph.Graph
+	done      chan struct{}
+	finished  bool
+}
+
+// lease is an URL leased to a worker, until it expires.
+type lease struct {
+	item    frontier.Item
+	worker  string
+	expires time.Time
+}
+
+// NewCoordinator creates the coordinator of the crawl of a domain, with its main page waiting to be leased.
+func NewCoordinator(domain string, options Options) *Coordinator {
+	if options.LeaseTimeout == 0 {
+		options.LeaseTimeout = DefaultLeaseTimeout
+	}
+	if options.Seen == nil {
+		options.Seen = seen.NewMap()
+	}
+	if options.Frontier == nil {
+		options.Frontier = frontier.NewMemory()
+	}
+
+	coordinator := &Coordinator{
+		domain:  domain,
+		options: options,
+		leases:  make(map[string]*lease),
+		graph:   graph.New(),
+		done:    make(chan struct{}),
+	}
+
+	// Resume the crawl from the frontier, if it isn't empty:
+	if options.Frontier.Len() == 0 {
+		coordinator.pushNew(frontier.Item{URL: domain})
+	}
+
+	// The crawl of a frontier that was finished already ends right away (the domain was seen):
+	coordinator.endIfFinished()
+	return coordinator
+}
+
+// Wait returns the link graph of the pages crawled when the crawl ends.
+func (coordinator *Coordinator) Wait() *graph.Graph {
+	<-coordinator.done
+	return coordinator.graph
+}
+
+// Status returns the progress of the crawl.
+func (coordinator *Coordinator) Status() Status {
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	coordinator.reclaim(time.Now())
+	return Status{
+		Crawled: coordinator.crawled,
+		Pending: coordinator.options.Frontier.Len(),
+		Leased:  len(coordinator.leases),
+		Expired: coordinator.expired,
+		Done:    coordinator.finished,
+	}
+}
+
+// ServeHTTP answers the requests of the workers.
+func (coordinator *Coordinator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/lease":
+		request := LeaseRequest{}
+		if !decode(w, r, &request) {
+			return
+		}
+		encode(w, coordinator.Lease(request))
+	case "/complete":
+		request := CompleteRequest{}
+		if !decode(w, r, &request) {
+			return
+		}
+		encode(w, coordinator.Complete(request))
+	case "/status":
+		encode(w, coordinator.Status())
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Lease leases URLs to crawl to a worker, leasing again the ones whose leases expired.
+func (coordinator *Coordinator) Lease(request LeaseRequest) LeaseResponse {
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	now := time.Now()
+	coordinator.reclaim(now)
+
+	response := LeaseResponse{Leases: []Lease{}, Done: coordinator.finished}
+	for len(response.Leases) < request.Max {
+		item, ok, err := coordinator.options.Frontier.Pop()
+		if err != nil {
+			coordinator.logError("Coordinator::Lease() - Error: failed to get an URL from the frontier: " + err.Error())
+			break
+		}
+		if !ok {
+			break
+		}
+
+		coordinator.nextLease++
+		id := strconv.Itoa(coordinator.nextLease)
+		coordinator.leases[id] = &lease{item: item, worker: request.Worker, expires: now.Add(coordinator.options.LeaseTimeout)}
+		response.Leases = append(response.Leases, Lease{ID: id, URL: item.URL, External: item.External})
+	}
+	return response
+}
+
+// Complete adds the pages fetched by a worker to the link graph, and the URLs they link to that weren't
+// seen to the frontier. The results of leases that expired are ignored (their URLs were leased again).
+func (coordinator *Coordinator) Complete(request CompleteRequest) CompleteResponse {
+	coordinator.mutex.Lock()
+	defer coordinator.mutex.Unlock()
+
+	response := CompleteResponse{}
+	for _, result := range request.Results {
+		leased, ok := coordinator.leases[result.Lease]
+		if !ok || leased.worker != request.Worker {
+			continue
+		}
+		delete(coordinator.leases, result.Lease)
+		response.Accepted++
+
+		for _, err := range result.Errors {
+			coordinator.logError(request.Worker + ": " + err)
+		}
+		coordinator.add(leased.item, result.Page)
+		coordinator.markDone(leased.item)
+	}
+
+	coordinator.endIfFinished()
+	return response
+}
+
+// add adds the page fetched from the URL of an item to the link graph, and the URLs it links to to the frontier.
+func (coordinator *Coordinator) add(item frontier.Item, page *fetcher.Page) {
+	if page == nil {
+		page = &fetcher.Page{URL: item.URL, Size: -1}
+	}
+	coordinator.crawled++
+	coordinator.graph.AddNode(crawler.PageNode(item.URL, page, item.Depth))
+
+	// Pages of other domains are only checked:
+	if item.External {
+		return
+	}
+
+	for _, link := range page.Links {
+		if link.External && !coordinator.options.CheckExternal {
+			continue
+		}
+
+		coordinator.graph.AddEdge(graph.Edge{
+			Source:     item.URL,
+			Target:     link.URL,
+			Kind:       string(link.Kind),
+			AnchorText: link.AnchorText,
+			Nofollow:   link.Nofollow,
+		})
+
+		depth := item.Depth + 1
+		coordinator.pushNew(frontier.Item{URL: link.URL, Depth: depth, External: link.External, Priority: depth})
+	}
+}
+
+// pushNew adds the item of an URL that wasn't seen before to the frontier, marking it as seen once it's in it
+// (so that it isn't lost if a persistent frontier is closed in between).
+func (coordinator *Coordinator) pushNew(item frontier.Item) {
+	if coordinator.options.Seen.Contains(item.URL) {
+		return
+	}
+	coordinator.push(item)
+	coordinator.options.Seen.Add(item.URL)
+}
+
+// push adds an item to the frontier.
+func (coordinator *Coordinator) push(item frontier.Item) {
+	if err := coordinator.options.Frontier.Push(item); err != nil {
+		coordinator.logError("Coordinator::push() - Error: failed to add " + item.URL + " to the frontier: " + err.Error())
+	}
+}
+
+// markDone tells the frontier that the URL of an item leased was crawled.
+func (coordinator *Coordinator) markDone(item frontier.Item) {
+	if err := coordinator.options.Frontier.Done(item); err != nil {
+		coordinator.logError("Coordinator::markDone() - Error: failed to remove " + item.URL + " from the frontier: " + err.Error())
+	}
+}
+
+// reclaim puts the URLs of the leases expired back in the frontier, to be leased again.
+func (coordinator *Coordinator) reclaim(now time.Time) {
+	for id, leased := range coordinator.leases {
+		if now.After(leased.expires) {
+			delete(coordinator.leases, id)
+			coordinator.expired++
+			coordinator.push(leased.item)
+			coordinator.markDone(leased.item)
+		}
+	}
+}
+
+// endIfFinished ends the crawl if there are no URLs waiting to be leased nor leased.
+func (coordinator *Coordinator) endIfFinished() {
+	if !coordinator.finished && coordinator.options.Frontier.Len() == 0 && len(coordinator.leases) == 0 {
+		coordinator.finished = true
+		close(coordinator.done)
+	}
+}
+
+// logError writes an error to the errors writer, if there is one.
+func (coordinator *Coordinator) logError(msg string) {
+	if coordinator.options.Errors != nil {
+		fmt.Fprintln(coordinator.options.Errors, msg)
+	}
+}
+
+// decode decodes the JSON body of a request, answering it with an error if it's invalid.
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Coordinator::ServeHTTP() - Error: method not allowed: "+r.Method, http.StatusMethodNotAllowed)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, "Coordinator::ServeHTTP() - Error: invalid request: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// encode writes a value as the JSON body of a response.
+func encode(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,229 @@
+package distributed
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/msandim/web-crawler/fetcher"
+	"github.com/msandim/web-crawler/frontier"
+	"github.com/msandim/web-crawler/graph"
+	"github.com/msandim/web-crawler/sitegen"
+)
+
+// runWorkers runs n workers against a coordinator until the crawl ends.
+func runWorkers(t *testing.T, coordinator string, n int) {
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			worker := NewWorker("worker-"+strconv.Itoa(i), coordinator, fetcher.NewHTTPFetcher(8, 10), 8)
+			if err := worker.Run(); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// checkSitemap compares the graph of a crawl with the ground truth of the synthetic site crawled.
+func checkSitemap(t *testing.T, sitemap *graph.Graph, server *httptest.Server, site *sitegen.Site) {
+	reachable := site.Reachable()
+	if len(sitemap.Nodes) != len(reachable) {
+		t.Errorf("Number of pages crawled was invalid. Expected: %d, Got: %d", len(reachable), len(sitemap.Nodes))
+	}
+
+	for path := range reachable {
+		page, _ := site.Page(path)
+		node, ok := sitemap.Node(sitegen.URL(server, path))
+		if !ok {
+			t.Errorf("Page %s was not crawled", path)
+			continue
+		}
+		if node.StatusCode != page.StatusCode() {
+			t.Errorf("Invalid status code of %s. Expected: %d, Got: %d", path, page.StatusCode(), node.StatusCode)
+		}
+	}
+
+	if len(sitemap.Edges) != len(site.Edges()) {
+		t.Errorf("Number of links was invalid. Expected: %d, Got: %d", len(site.Edges()), len(sitemap.Edges))
+	}
+}
+
+func TestCoordinator_Workers(t *testing.T) {
+	server, site := sitegen.NewServer(sitegen.Spec{Pages: 500, FanOut: 6, CrossLinks: 2, Seed: 4, NotFound: 0.05, Redirects: 0.05})
+	defer server.Close()
+
+	coordinator := NewCoordinator(sitegen.URL(server, "/"), Options{})
+	coordinatorServer := httptest.NewServer(coordinator)
+	defer coordinatorServer.Close()
+
+	runWorkers(t, coordinatorServer.URL, 3)
+	checkSitemap(t, coordinator.Wait(), server, site)
+
+	status := coordinator.Status()
+	if !status.Done || status.Crawled != len(site.Reachable()) || status.Pending != 0 || status.Leased != 0 {
+		t.Errorf("Status of the crawl was invalid: %+v", status)
+	}
+}
+
+func TestCoordinator_DeadWorker(t *testing.T) {
+	server, site := sitegen.NewServer(sitegen.Spec{Pages: 200, FanOut: 5, Seed: 5})
+	defer server.Close()
+
+	coordinator := NewCoordinator(sitegen.URL(server, "/"), Options{LeaseTimeout: 200 * time.Millisecond})
+	coordinatorServer := httptest.NewServer(coordinator)
+	defer coordinatorServer.Close()
+
+	// A worker leases the main page and dies without crawling it:
+	leases := coordinator.Lease(LeaseRequest{Worker: "dead", Max: 10})
+	if len(leases.Leases) != 1 {
+		t.Fatalf("Number of URLs leased was invalid. Expected: %d, Got: %d", 1, len(leases.Leases))
+	}
+
+	runWorkers(t, coordinatorServer.URL, 2)
+	checkSitemap(t, coordinator.Wait(), server, site)
+
+	if status := coordinator.Status(); status.Expired < 1 {
+		t.Errorf("Number of leases expired was invalid. Expected at least: %d, Got: %d", 1, status.Expired)
+	}
+
+	// The results of the expired lease are ignored:
+	late := coordinator.Complete(CompleteRequest{
+		Worker:  "dead",
+		Results: []Result{{Lease: leases.Leases[0].ID, Page: &fetcher.Page{URL: leases.Leases[0].URL, StatusCode: 500}}},
+	})
+	if late.Accepted != 0 {
+		t.Errorf("Number of results accepted was invalid. Expected: %d, Got: %d", 0, late.Accepted)
+	}
+}
+
+func TestCoordinator_Lease(t *testing.T) {
+	coordinator := NewCoordinator("http://a.com/", Options{LeaseTimeout: time.Hour})
+
+	first := coordinator.Lease(LeaseRequest{Worker: "a", Max: 5})
+	if len(first.Leases) != 1 || first.Leases[0].URL != "http://a.com/" || first.Done {
+		t.Fatalf("URLs leased were invalid: %+v", first)
+	}
+
+	// While the main page is leased, there's nothing to lease, but the crawl didn't end:
+	if second := coordinator.Lease(LeaseRequest{Worker: "b", Max: 5}); len(second.Leases) != 0 || second.Done {
+		t.Errorf("URLs leased were invalid: %+v", second)
+	}
+
+	// Only the worker of a lease can complete it:
+	page := &fetcher.Page{URL: "http://a.com/", StatusCode: 200, Links: []fetcher.Link{
+		{URL: "http://a.com/b", Kind: fetcher.LinkAnchor},
+		{URL: "http://a.com/", Kind: fetcher.LinkAnchor},
+		{URL: "http://b.com/", Kind: fetcher.LinkAnchor, External: true},
+	}}
+	request := CompleteRequest{Worker: "b", Results: []Result{{Lease: first.Leases[0].ID, Page: page}}}
+	if response := coordinator.Complete(request); response.Accepted != 0 {
+		t.Errorf("Number of results accepted was invalid. Expected: %d, Got: %d", 0, response.Accepted)
+	}
+	request.Worker = "a"
+	if response := coordinator.Complete(request); response.Accepted != 1 {
+		t.Errorf("Number of results accepted was invalid. Expected: %d, Got: %d", 1, response.Accepted)
+	}
+
+	// The links to other domains aren't crawled (nor checked), and the ones seen aren't leased again:
+	third := coordinator.Lease(LeaseRequest{Worker: "b", Max: 5})
+	if len(third.Leases) != 1 || third.Leases[0].URL != "http://a.com/b" {
+		t.Fatalf("URLs leased were invalid: %+v", third)
+	}
+
+	coordinator.Complete(CompleteRequest{Worker: "b", Results: []Result{{Lease: third.Leases[0].ID}}})
+	if last := coordinator.Lease(LeaseRequest{Worker: "a", Max: 5}); !last.Done {
+		t.Errorf("Crawl didn't end: %+v", last)
+	}
+	if sitemap := coordinator.Wait(); len(sitemap.Nodes) != 2 || len(sitemap.Edges) != 2 {
+		t.Errorf("Graph of the crawl was invalid. Expected: %d nodes and %d edges, Got: %d and %d", 2, 2, len(sitemap.Nodes), len(sitemap.Edges))
+	}
+}
+
+func TestCoordinator_Restart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coordinator")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	disk, err := frontier.OpenDisk(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	coordinator := NewCoordinator("http://a.com/", Options{LeaseTimeout: time.Hour, Seen: disk.Seen(), Frontier: disk})
+
+	links := func(paths ...string) []fetcher.Link {
+		pageLinks := []fetcher.Link{}
+		for _, path := range paths {
+			pageLinks = append(pageLinks, fetcher.Link{URL: "http://a.com/" + path, Kind: fetcher.LinkAnchor})
+		}
+		return pageLinks
+	}
+	complete := func(coordinator *Coordinator, leased Lease, paths ...string) {
+		page := &fetcher.Page{URL: leased.URL, StatusCode: 200, Links: links(paths...)}
+		coordinator.Complete(CompleteRequest{Worker: "a", Results: []Result{{Lease: leased.ID, Page: page}}})
+	}
+
+	// The coordinator is restarted while b and c are leased, after b was completed:
+	complete(coordinator, coordinator.Lease(LeaseRequest{Worker: "a", Max: 5}).Leases[0], "b", "c")
+	leased := coordinator.Lease(LeaseRequest{Worker: "a", Max: 5}).Leases
+	if len(leased) != 2 {
+		t.Fatalf("Number of URLs leased was invalid. Expected: %d, Got: %d", 2, len(leased))
+	}
+	complete(coordinator, leased[0], "c", "d")
+	if err := disk.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The restarted coordinator leases c again, and d, but none of the URLs completed:
+	disk, err = frontier.OpenDisk(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	coordinator = NewCoordinator("http://a.com/", Options{LeaseTimeout: time.Hour, Seen: disk.Seen(), Frontier: disk})
+
+	urls := []string{}
+	for _, leased := range coordinator.Lease(LeaseRequest{Worker: "a", Max: 5}).Leases {
+		urls = append(urls, leased.URL)
+		complete(coordinator, leased, "b")
+	}
+	if expected := "http://a.com/c,http://a.com/d"; strings.Join(urls, ",") != expected {
+		t.Errorf("URLs leased were invalid. Expected: %s, Got: %s", expected, strings.Join(urls, ","))
+	}
+	if status := coordinator.Status(); !status.Done || status.Crawled != 2 {
+		t.Errorf("Status of the crawl was invalid: %+v", status)
+	}
+	if err := disk.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A coordinator restarted after the crawl finished ends right away, telling the workers:
+	disk, err = frontier.OpenDisk(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer disk.Close()
+	coordinator = NewCoordinator("http://a.com/", Options{LeaseTimeout: time.Hour, Seen: disk.Seen(), Frontier: disk})
+
+	finished := make(chan bool)
+	go func() {
+		coordinator.Wait()
+		finished <- true
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Crawl of a finished frontier didn't end")
+	}
+	if leases := coordinator.Lease(LeaseRequest{Worker: "a", Max: 5}); len(leases.Leases) != 0 || !leases.Done {
+		t.Errorf("URLs leased were invalid: %+v", leases)
+	}
+}
@@ -0,0 +1,62 @@
+// Package distributed implements crawling a domain with several processes: a coordinator, which owns the
+// frontier and the set of the URLs seen and builds the link graph, and workers, which fetch the URLs
+// leased to them by the coordinator and send it back the pages fetched.
+//
+// The coordinator and the workers talk over HTTP with JSON messages:
+//
+//	POST /lease     LeaseRequest  -> LeaseResponse   a worker asks for up to Max URLs to crawl
+//	POST /complete  CompleteRequest -> CompleteResponse  a worker sends the pages fetched from its leases
+//	GET  /status    -> Status                         progress of the crawl
+//
+// Every lease expires after a timeout: if a worker dies (or takes too long), its URLs are leased again to
+// another worker, and the pages it sends for them afterwards are ignored.
+package distributed
+
+import "github.com/msandim/web-crawler/fetcher"
+
+// Lease is an URL leased to a worker to be crawled.
+type Lease struct {
+	ID       string `json:"id"`
+	URL      string `json:"url"`
+	External bool   `json:"external,omitempty"` // the URL is from another domain, so it's only checked
+}
+
+// LeaseRequest asks the coordinator for URLs to crawl.
+type LeaseRequest struct {
+	Worker string `json:"worker"`
+	Max    int    `json:"max"` // maximum number of URLs leased
+}
+
+// LeaseResponse are the URLs leased to a worker. There may be none while the URLs leased to other workers
+// are being crawled: Done is only set when the crawl ended, and the worker can stop.
+type LeaseResponse struct {
+	Leases []Lease `json:"leases"`
+	Done   bool    `json:"done"`
+}
+
+// Result is the outcome of crawling the URL of a lease.
+type Result struct {
+	Lease  string        `json:"lease"`
+	Page   *fetcher.Page `json:"page"`
+	Errors []string      `json:"errors,omitempty"`
+}
+
+// CompleteRequest sends the coordinator the results of the leases of a worker.
+type CompleteRequest struct {
+	Worker  string   `json:"worker"`
+	Results []Result `json:"results"`
+}
+
+// CompleteResponse tells a worker how many of its results were accepted (the ones of expired leases aren't).
+type CompleteResponse struct {
+	Accepted int `json:"accepted"`
+}
+
+// Status is the progress of a crawl.
+type Status struct {
+	Crawled int  `json:"crawled"` // URLs crawled
+	Pending int  `json:"pending"` // URLs waiting to be leased
+	Leased  int  `json:"leased"`  // URLs leased to workers
+	Expired int  `json:"expired"` // leases that expired and were leased again
+	Done    bool `json:"done"`
+}
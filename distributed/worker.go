@@ -0,0 +1,153 @@
+package distributed
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/msandim/web-crawler/fetcher"
+	"github.com/msandim/web-crawler/fetcher/urlwrapper"
+)
+
+// Parameters of the requests of the workers to the coordinator:
+const (
+	pollInterval = 500 * time.Millisecond // time waited when no URLs were leased, or the coordinator can't be reached
+	maxFailures  = 20                     // number of requests in a row that can fail before the worker gives up
+)
+
+// Worker is a process of a distributed crawl that fetches the URLs leased to it by the coordinator and sends
+// it back the pages fetched.
+type Worker struct {
+	id          string
+	coordinator string // base URL of the coordinator (e.g. "http://localhost:8090")
+	pageFetcher fetcher.Fetcher
+	nWorkers    int // number of URLs leased (and fetched concurrently) at a time
+	client      *http.Client
+}
+
+// NewWorker creates a worker, identified by an ID, that fetches the URLs leased by a coordinator with a fetcher,
+// nWorkers at a time.
+func NewWorker(id string, coordinator string, pageFetcher fetcher.Fetcher, nWorkers int) *Worker {
+	return &Worker{
+		id:          id,
+		coordinator: strings.TrimSuffix(coordinator, "/"),
+		pageFetcher: pageFetcher,
+		nWorkers:    nWorkers,
+		client:      &http.Client{Timeout: time.Minute},
+	}
+}
+
+// Run crawls the URLs leased by the coordinator until the crawl ends. It returns an error if the
+// coordinator can't be reached for a while.
+func (worker *Worker) Run() error {
+	failures := 0
+	for {
+		response := LeaseResponse{}
+		err := worker.post("/lease", LeaseRequest{Worker: worker.id, Max: worker.nWorkers}, &response)
+		if err != nil {
+			failures++
+			if failures >= maxFailures {
+				return err
+			}
+			time.Sleep(pollInterval)
+			continue
+		}
+		failures = 0
+
+		if response.Done {
+			return nil
+		}
+		if len(response.Leases) == 0 {
+			time.Sleep(pollInterval)
+			continue
+		}
+
+		request := CompleteRequest{Worker: worker.id, Results: worker.crawl(response.Leases)}
+
+		// If the results can't be sent, their leases expire and the URLs are leased again:
+		for attempt := 1; ; attempt++ {
+			err := worker.post("/complete", request, &CompleteResponse{})
+			if err == nil {
+				break
+			}
+			if attempt >= maxFailures {
+				return err
+			}
+			time.Sleep(pollInterval)
+		}
+	}
+}
+
+// crawl fetches the URLs of some leases concurrently.
+func (worker *Worker) crawl(leases []Lease) []Result {
+	results := make([]Result, len(leases))
+
+	wg := sync.WaitGroup{}
+	for i, lease := range leases {
+		wg.Add(1)
+		go func(i int, lease Lease) {
+			defer wg.Done()
+			results[i] = worker.fetch(lease)
+		}(i, lease)
+	}
+	wg.Wait()
+	return results
+}
+
+// fetch fetches the URL of a lease (or only checks it, if it's external).
+func (worker *Worker) fetch(lease Lease) Result {
+	result := Result{Lease: lease.ID}
+
+	if lease.External {
+		result.Page = &fetcher.Page{URL: lease.URL, Size: -1, Links: []fetcher.Link{}}
+		checker, ok := worker.pageFetcher.(fetcher.Checker)
+		if !ok {
+			result.Errors = []string{"Worker::fetch() - Error: the fetcher can't check external URLs: " + lease.URL}
+			return result
+		}
+
+		statusCode, err := checker.Check(lease.URL)
+		if err != nil {
+			result.Errors = []string{err.Error()}
+		}
+		result.Page.StatusCode = statusCode
+		return result
+	}
+
+	page, errs := worker.pageFetcher.Fetch(urlwrapper.New(lease.URL))
+	for _, err := range errs {
+		result.Errors = append(result.Errors, err.Error())
+	}
+
+	// The text of the page isn't used by the coordinator, so it isn't sent:
+	if page != nil {
+		page.Text = ""
+	}
+	result.Page = page
+	return result
+}
+
+// post sends a request to the coordinator and decodes its response.
+func (worker *Worker) post(path string, request interface{}, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	resp, err := worker.client.Post(worker.coordinator+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("Worker::post() - Error: request to the coordinator failed: " + path +
+			" with error code: " + strconv.Itoa(resp.StatusCode))
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
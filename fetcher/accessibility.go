@@ -0,0 +1,199 @@
+package fetcher
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Rules of the accessibility checks:
+const (
+	AccessibilityImageAlt     = "img-alt"
+	AccessibilityInputLabel   = "input-label"
+	AccessibilityEmptyLink    = "empty-link"
+	AccessibilityEmptyButton  = "empty-button"
+	AccessibilityHTMLLang     = "html-lang"
+	AccessibilityHeadingOrder = "heading-order"
+	AccessibilityDuplicateID  = "duplicate-id"
+)
+
+// AccessibilityViolation is an accessibility problem found in an element of a page.
+type AccessibilityViolation struct {
+	Rule    string // identifier of the check that found the problem (e.g. "img-alt")
+	Element string // start tag of the element, with its identifying attributes (e.g. `<img src="logo.png">`)
+	Message string
+}
+
+// accessibilityChecker walks the DOM of a page looking for accessibility problems.
+type accessibilityChecker struct {
+	violations []AccessibilityViolation
+
+	labelled     map[string]bool // ids referenced by the for attribute of a <label>
+	ids          map[string]int  // number of elements with each id
+	idsOrder     []string
+	headingLevel int // level of the last heading found (0 before the first one)
+}
+
+// checkAccessibility parses an HTML page into its DOM and checks it for images without alt text,
+// form inputs without labels, empty links and buttons, a missing lang in <html>,
+// skipped heading levels and duplicate ids.
+func checkAccessibility(body []byte) ([]AccessibilityViolation, error) {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	checker := &accessibilityChecker{
+		violations: []AccessibilityViolation{},
+		labelled:   make(map[string]bool),
+		ids:        make(map[string]int),
+	}
+	checker.collect(doc)
+	checker.check(doc, false)
+
+	for _, id := range checker.idsOrder {
+		if n := checker.ids[id]; n > 1 {
+			checker.add(AccessibilityDuplicateID, `id="`+id+`"`, "is used by "+strconv.Itoa(n)+" elements")
+		}
+	}
+	return checker.violations, nil
+}
+
+func (checker *accessibilityChecker) add(rule string, element string, message string) {
+	checker.violations = append(checker.violations, AccessibilityViolation{Rule: rule, Element: element, Message: message})
+}
+
+// collect finds the ids of the elements and the ones referenced by labels, which are needed before
+// checking the elements (a <label for> can come after its input).
+func (checker *accessibilityChecker) collect(node *html.Node) {
+	if node.Type == html.ElementNode {
+		if id := nodeAttribute(node, "id"); id != "" {
+			if checker.ids[id] == 0 {
+				checker.idsOrder = append(checker.idsOrder, id)
+			}
+			checker.ids[id]++
+		}
+		if node.DataAtom == atom.Label {
+			if target := nodeAttribute(node, "for"); target != "" {
+				checker.labelled[target] = true
+			}
+		}
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		checker.collect(child)
+	}
+}
+
+// check checks an element and its descendants (inLabel is set if the element is inside a <label>).
+func (checker *accessibilityChecker) check(node *html.Node, inLabel bool) {
+	if node.Type == html.ElementNode {
+		switch node.DataAtom {
+		case atom.Html:
+			if strings.TrimSpace(nodeAttribute(node, "lang")) == "" {
+				checker.add(AccessibilityHTMLLang, "<html>", "has no lang attribute")
+			}
+		case atom.Img:
+			if !hasNodeAttribute(node, "alt") {
+				checker.add(AccessibilityImageAlt, describe(node), "has no alt attribute")
+			}
+		case atom.Input, atom.Select, atom.Textarea:
+			if needsLabel(node) && !inLabel && !hasAccessibleName(node) && !checker.labelled[nodeAttribute(node, "id")] {
+				checker.add(AccessibilityInputLabel, describe(node), "has no label")
+			}
+		case atom.A:
+			if hasNodeAttribute(node, "href") && !hasAccessibleName(node) && !hasContent(node) {
+				checker.add(AccessibilityEmptyLink, describe(node), "has no text")
+			}
+		case atom.Button:
+			if !hasAccessibleName(node) && !hasContent(node) {
+				checker.add(AccessibilityEmptyButton, describe(node), "has no text")
+			}
+		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+			level := int(node.Data[1] - '0')
+			if checker.headingLevel > 0 && level > checker.headingLevel+1 {
+				checker.add(AccessibilityHeadingOrder, describe(node),
+					"skips heading levels (follows <h"+strconv.Itoa(checker.headingLevel)+">)")
+			}
+			checker.headingLevel = level
+		case atom.Label:
+			inLabel = true
+		}
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		checker.check(child, inLabel)
+	}
+}
+
+// needsLabel checks if a form element is one the user fills in (i.e. not hidden nor a button).
+func needsLabel(node *html.Node) bool {
+	if node.DataAtom != atom.Input {
+		return true
+	}
+
+	switch strings.ToLower(nodeAttribute(node, "type")) {
+	case "hidden", "submit", "reset", "button", "image":
+		return false
+	}
+	return true
+}
+
+// hasAccessibleName checks if an element is named with ARIA attributes or a title.
+func hasAccessibleName(node *html.Node) bool {
+	for _, key := range []string{"aria-label", "aria-labelledby", "title"} {
+		if strings.TrimSpace(nodeAttribute(node, key)) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasContent checks if an element has text or an image with alt text inside it.
+func hasContent(node *html.Node) bool {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		switch {
+		case child.Type == html.TextNode && strings.TrimSpace(child.Data) != "":
+			return true
+		case child.Type == html.ElementNode && child.DataAtom == atom.Img && strings.TrimSpace(nodeAttribute(child, "alt")) != "":
+			return true
+		case child.Type == html.ElementNode && (hasAccessibleName(child) || hasContent(child)):
+			return true
+		}
+	}
+	return false
+}
+
+// describe returns the start tag of an element with the attributes that identify it (e.g. `<img src="logo.png">`).
+func describe(node *html.Node) string {
+	description := "<" + node.Data
+	for _, key := range []string{"id", "name", "type", "href", "src"} {
+		if value := nodeAttribute(node, key); value != "" {
+			description += " " + key + `="` + value + `"`
+		}
+	}
+	return description + ">"
+}
+
+// nodeAttribute gets the value of an attribute of an element ("" if it isn't present).
+func nodeAttribute(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// hasNodeAttribute checks if an element has an attribute (even if its value is empty).
+func hasNodeAttribute(node *html.Node, key string) bool {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
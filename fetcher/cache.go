@@ -0,0 +1,104 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// CacheEntry contains the validators of a page fetched in a previous crawl (ETag and Last-Modified)
+// and the page obtained then, without its text, to be reused if it didn't change.
+type CacheEntry struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Page         *Page  `json:"page"`
+}
+
+// Cache is a set of pages fetched in a previous crawl, indexed by their URL, that can be
+// persisted to a file between crawls. It is safe to be used concurrently.
+type Cache struct {
+	mutex   sync.Mutex
+	entries map[string]*CacheEntry
+}
+
+// NewCache returns an empty Cache.
+func NewCache() *Cache {
+	return &Cache{entries: make(map[string]*CacheEntry)}
+}
+
+// LoadCache reads a Cache previously saved to a file. If the file doesn't exist, an empty Cache is returned.
+func LoadCache(path string) (*Cache, error) {
+	cache := NewCache()
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(&cache.entries); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+// Save writes the Cache to a file.
+func (cache *Cache) Save(path string) error {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := json.NewEncoder(f).Encode(cache.entries); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Len returns the number of pages in the Cache.
+func (cache *Cache) Len() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return len(cache.entries)
+}
+
+func (cache *Cache) get(url string) *CacheEntry {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry, ok := cache.entries[url]
+	if !ok || entry.Page == nil {
+		return nil
+	}
+	return entry
+}
+
+func (cache *Cache) set(url string, etag string, lastModified string, page *Page) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	// The text of the page isn't kept, so that the cache doesn't grow with the contents of the site, nor the
+	// problems found by the checks, which may not be made in the next crawl:
+	cachedPage := *page
+	cachedPage.Text = ""
+	cachedPage.Accessibility = nil
+	cachedPage.Security = nil
+	cache.entries[url] = &CacheEntry{
+		ETag:         etag,
+		LastModified: lastModified,
+		Page:         &cachedPage,
+	}
+}
+
+func (cache *Cache) delete(url string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	delete(cache.entries, url)
+}
@@ -0,0 +1,199 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/msandim/web-crawler/fetcher/urlwrapper"
+)
+
+// Kinds of requests recorded in a Cassette:
+const (
+	interactionFetch    = "fetch"
+	interactionCheck    = "check"
+	interactionDownload = "download"
+)
+
+// Interaction is the outcome of a request recorded in a Cassette: the page obtained (or the status code,
+// when checking an URL) and the errors returned.
+type Interaction struct {
+	Page       *Page    `json:"page,omitempty"`
+	StatusCode int      `json:"status_code,omitempty"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// Cassette is a set of requests (pages fetched, URLs checked and assets downloaded) recorded during a crawl,
+// that can be persisted to a file to be replayed later without network. It is safe to be used concurrently.
+type Cassette struct {
+	mutex        sync.Mutex
+	interactions map[string]*Interaction // by kind of request and URL (e.g. "fetch http://a.com/")
+}
+
+// NewCassette returns an empty Cassette.
+func NewCassette() *Cassette {
+	return &Cassette{interactions: make(map[string]*Interaction)}
+}
+
+// LoadCassette reads a Cassette previously saved to a file.
+func LoadCassette(path string) (*Cassette, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cassette := NewCassette()
+	if err := json.NewDecoder(f).Decode(&cassette.interactions); err != nil {
+		return nil, err
+	}
+	return cassette, nil
+}
+
+// Save writes the Cassette to a file.
+func (cassette *Cassette) Save(path string) error {
+	cassette.mutex.Lock()
+	defer cassette.mutex.Unlock()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(cassette.interactions); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Len returns the number of requests recorded in the Cassette.
+func (cassette *Cassette) Len() int {
+	cassette.mutex.Lock()
+	defer cassette.mutex.Unlock()
+	return len(cassette.interactions)
+}
+
+func (cassette *Cassette) get(kind string, url string) (*Interaction, bool) {
+	cassette.mutex.Lock()
+	defer cassette.mutex.Unlock()
+
+	interaction, ok := cassette.interactions[kind+" "+url]
+	return interaction, ok
+}
+
+func (cassette *Cassette) record(kind string, url string, page *Page, statusCode int, errs []error) {
+	cassette.mutex.Lock()
+	defer cassette.mutex.Unlock()
+
+	interaction := &Interaction{StatusCode: statusCode}
+	if page != nil {
+		recordedPage := *page
+		interaction.Page = &recordedPage
+	}
+	for _, err := range errs {
+		if err != nil {
+			interaction.Errors = append(interaction.Errors, err.Error())
+		}
+	}
+	cassette.interactions[kind+" "+url] = interaction
+}
+
+// Recorder is a Fetcher that records every request made with another Fetcher (and its outcome) in a Cassette.
+type Recorder struct {
+	fetcher  Fetcher
+	cassette *Cassette
+}
+
+// NewRecorder returns a Recorder of the requests made with a Fetcher.
+func NewRecorder(fetcher Fetcher, cassette *Cassette) *Recorder {
+	return &Recorder{fetcher: fetcher, cassette: cassette}
+}
+
+// Fetch fetches a page with the Fetcher recorded and records it.
+func (recorder *Recorder) Fetch(urlArg *urlwrapper.URLWrapper) (*Page, []error) {
+	page, errs := recorder.fetcher.Fetch(urlArg)
+	recorder.cassette.record(interactionFetch, urlArg.URL, page, 0, errs)
+	return page, errs
+}
+
+// Check checks an URL with the Fetcher recorded (if it can check URLs) and records its status code.
+func (recorder *Recorder) Check(urlArg string) (int, error) {
+	checker, ok := recorder.fetcher.(Checker)
+	if !ok {
+		return 0, errors.New("Recorder::check() - Error: the fetcher recorded can't check URLs: " + urlArg)
+	}
+
+	statusCode, err := checker.Check(urlArg)
+	recorder.cassette.record(interactionCheck, urlArg, nil, statusCode, []error{err})
+	return statusCode, err
+}
+
+// Download downloads an URL with the Fetcher recorded (if it can download assets) and records it.
+func (recorder *Recorder) Download(urlArg string) (*Page, error) {
+	downloader, ok := recorder.fetcher.(Downloader)
+	if !ok {
+		return &Page{URL: urlArg, Size: -1, Links: []Link{}}, errors.New("Recorder::download() - Error: the fetcher recorded can't download assets: " + urlArg)
+	}
+
+	page, err := downloader.Download(urlArg)
+	recorder.cassette.record(interactionDownload, urlArg, page, 0, []error{err})
+	return page, err
+}
+
+// Replayer is a Fetcher that serves the requests recorded in a Cassette, without network.
+// The URLs that weren't recorded fail as if they were unreachable.
+type Replayer struct {
+	cassette *Cassette
+}
+
+// NewReplayer returns a Replayer of the requests recorded in a Cassette.
+func NewReplayer(cassette *Cassette) *Replayer {
+	return &Replayer{cassette: cassette}
+}
+
+// Fetch returns the page recorded for an URL, along with the errors returned when it was fetched.
+func (replayer *Replayer) Fetch(urlArg *urlwrapper.URLWrapper) (*Page, []error) {
+	interaction, ok := replayer.cassette.get(interactionFetch, urlArg.URL)
+	if !ok || interaction.Page == nil {
+		return &Page{URL: urlArg.URL, Size: -1, Links: []Link{}}, []error{errors.New("Replayer::fetch() - Error: URL not recorded: " + urlArg.URL)}
+	}
+
+	errs := []error{}
+	for _, err := range interaction.Errors {
+		errs = append(errs, errors.New(err))
+	}
+	page := *interaction.Page
+	return &page, errs
+}
+
+// Check returns the status code recorded for an URL, along with the error returned when it was checked.
+func (replayer *Replayer) Check(urlArg string) (int, error) {
+	interaction, ok := replayer.cassette.get(interactionCheck, urlArg)
+	if !ok {
+		return 0, errors.New("Replayer::check() - Error: URL not recorded: " + urlArg)
+	}
+	return interaction.StatusCode, interaction.err()
+}
+
+// Download returns the asset recorded for an URL, along with the error returned when it was downloaded.
+func (replayer *Replayer) Download(urlArg string) (*Page, error) {
+	interaction, ok := replayer.cassette.get(interactionDownload, urlArg)
+	if !ok || interaction.Page == nil {
+		return &Page{URL: urlArg, Size: -1, Links: []Link{}}, errors.New("Replayer::download() - Error: URL not recorded: " + urlArg)
+	}
+
+	page := *interaction.Page
+	return &page, interaction.err()
+}
+
+// err returns the error recorded for a request that returns a single error (nil if none).
+func (interaction *Interaction) err() error {
+	if len(interaction.Errors) == 0 {
+		return nil
+	}
+	return errors.New(interaction.Errors[0])
+}
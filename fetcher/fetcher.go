@@ -2,12 +2,16 @@ package fetcher
 
 import (
 	"errors"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/msandim/web-crawler/fetcher/urlwrapper"
+	"github.com/msandim/web-crawler/structured"
 
 	"golang.org/x/net/html"
 )
@@ -15,14 +19,111 @@ import (
 // Fetcher represents an entity that knows of to fetch the URLs
 // contained in the HTML page of an URL.
 type Fetcher interface {
-	Fetch(urlArg *urlwrapper.URLWrapper) ([]string, []error)
+	Fetch(urlArg *urlwrapper.URLWrapper) (*Page, []error)
 }
 
+// Checker represents an entity that knows how to check if an URL is reachable, without fetching its contents.
+type Checker interface {
+	Check(urlArg string) (int, error)
+}
+
+// Downloader represents an entity that knows how to download the contents of an URL that isn't a page
+// to crawl (e.g. an image or a stylesheet).
+type Downloader interface {
+	Download(urlArg string) (*Page, error)
+}
+
+// LinkKind identifies the kind of element in which a link was found.
+type LinkKind string
+
+const (
+	// LinkAnchor is a link found in the href of an <a> element.
+	LinkAnchor LinkKind = "anchor"
+	// LinkCanonical is a link to the canonical URL of a page (<link rel="canonical"> or Link header).
+	LinkCanonical LinkKind = "canonical"
+	// LinkAlternate is a link to a version of a page in another language (<link rel="alternate" hreflang>).
+	LinkAlternate LinkKind = "alternate"
+)
+
+// Alternate is a version of a page in another language.
+type Alternate struct {
+	Lang string // value of the hreflang (e.g. "en-gb" or "x-default")
+	URL  string
+}
+
+// Heading is a heading (<h1> to <h6>) of a page.
+type Heading struct {
+	Level int // 1 to 6
+	Text  string
+}
+
+// Link is a link found in a page, pointing to another page of the same domain
+// or, if External is set, to a page of another domain.
+type Link struct {
+	URL        string
+	Kind       LinkKind
+	AnchorText string
+	Nofollow   bool   // the link has rel="nofollow", "ugc" or "sponsored"
+	Rel        string // values of the rel attribute that ask robots not to follow the link (e.g. "nofollow ugc")
+	External   bool
+}
+
+// Page is the result of fetching an URL: the metadata of the response and the links found on it.
+type Page struct {
+	URL            string
+	StatusCode     int
+	ContentType    string
+	ResponseTime   time.Duration // time taken to receive the response headers
+	Size           int64         // size of the body in bytes (-1 if unknown)
+	Title          string
+	Redirects      []string                 // URLs the request was redirected to, in order (the last one is the final URL)
+	NotModified    bool                     // the page didn't change since the previous crawl, so its contents were reused
+	Text           string                   // visible text of the page, with its words separated by a single space
+	TextHash       string                   // SHA-256 of the visible text (hex encoded)
+	SimHash        uint64                   // SimHash fingerprint of the visible text, to detect near-duplicates
+	Robots         Robots                   // directives to robots from <meta name="robots"> tags and X-Robots-Tag headers
+	Canonical      string                   // canonical URL of the page, from <link rel="canonical"> or the Link header
+	Alternates     []Alternate              // versions of the page in other languages (hreflang)
+	Description    string                   // content of the <meta name="description"> tag
+	Headings       []Heading                // headings of the page, in order of appearance (its outline)
+	WordCount      int                      // number of words of the visible text
+	Images         int                      // number of <img> elements
+	ImagesWithAlt  int                      // number of <img> elements with an alt attribute (possibly empty, for decorative images)
+	Accessibility  []AccessibilityViolation // accessibility problems found in the page (if checked)
+	Security       []SecurityFinding        // security problems found in the page (if checked)
+	Assets         []string                 // resources loaded by the page (images, scripts, stylesheets, etc.)
+	StructuredData *structured.Data         // JSON-LD, microdata and OpenGraph/Twitter card meta tags (nil if there are none)
+	Links          []Link
+}
+
+// URLs returns the URLs of the links to the same domain found on the page, in order of appearance.
+func (page *Page) URLs() []string {
+	urls := []string{}
+	for _, link := range page.Links {
+		if !link.External {
+			urls = append(urls, link.URL)
+		}
+	}
+	return urls
+}
+
+// invisibleTags are the elements whose contents aren't part of the visible text of a page.
+var invisibleTags = map[string]bool{"script": true, "style": true, "noscript": true, "template": true}
+
+// maxRedirects is the maximum number of redirects followed when fetching a page.
+const maxRedirects = 10
+
 // HTTPFetcher implements the Fetcher interface and sends an HTTP GET to fetch
 // the contents of an url.
 type HTTPFetcher struct {
 	rateLimiter    *RateLimiter
 	timeoutSeconds int
+	cache          *Cache // pages fetched in a previous crawl (nil if not used)
+
+	checkAccessibility bool // check the DOM of the pages for accessibility problems
+	checkSecurity      bool // check the security headers, cookies and assets of the HTTPS pages
+
+	transport http.RoundTripper // used to send the requests (http.DefaultTransport if nil)
 }
 
 // NewHTTPFetcher returns a new HTTPFetcher with a given rate limit
@@ -35,85 +136,226 @@ func NewHTTPFetcher(rateLimit int, timeoutSeconds int) *HTTPFetcher {
 	}
 }
 
+// SetCache sets the cache of the pages fetched in a previous crawl, used to send conditional
+// requests and avoid downloading the pages again if they didn't change.
+// The cache is updated with the pages fetched.
+func (fetcher *HTTPFetcher) SetCache(cache *Cache) {
+	fetcher.cache = cache
+}
+
+// SetAccessibilityCheck sets whether the DOM of the pages fetched is checked for accessibility problems
+// (images without alt text, form inputs without labels, empty links and buttons, etc.).
+func (fetcher *HTTPFetcher) SetAccessibilityCheck(check bool) {
+	fetcher.checkAccessibility = check
+}
+
+// SetSecurityCheck sets whether the HTTPS pages fetched are checked for missing security headers,
+// insecure cookies and assets loaded over HTTP (mixed content).
+func (fetcher *HTTPFetcher) SetSecurityCheck(check bool) {
+	fetcher.checkSecurity = check
+}
+
+// SetTransport sets the transport used to send the requests (e.g. to trust the certificate of a test server).
+func (fetcher *HTTPFetcher) SetTransport(transport http.RoundTripper) {
+	fetcher.transport = transport
+}
+
 // Fetch sends an HTTP GET to fetch the contents of an url and determine what
 // urls are contained on that page.
-func (fetcher *HTTPFetcher) Fetch(urlArg *urlwrapper.URLWrapper) ([]string, []error) {
-	// URLs found in this page: avoid duplicates:
-	urlsFound := []string{}
-	urlsFoundMap := make(map[string]bool)
+func (fetcher *HTTPFetcher) Fetch(urlArg *urlwrapper.URLWrapper) (*Page, []error) {
+	page := &Page{URL: urlArg.URL, Size: -1, Links: []Link{}}
 	errorsFound := []error{}
 
 	// Parse the url we're trying to crawl, by extracting its url and path without url fragments:
 	parentURLParsed, err := url.Parse(urlArg.URL)
 	if err != nil {
+		fetchErrors.Inc("invalid_url")
 		errorsFound = append(errorsFound, errors.New("HTTPFetcher::fetch() - Error: failed to parse the URL to fetch: "+urlArg.URL))
-		return []string{}, errorsFound
+		return page, errorsFound
 	}
 
 	// Define a custom http client that has a timeout and get the HTML code:
-	var httpClient = &http.Client{Timeout: time.Duration(fetcher.timeoutSeconds) * time.Second}
+	var httpClient = &http.Client{
+		Transport: fetcher.transport,
+		Timeout:   time.Duration(fetcher.timeoutSeconds) * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("stopped after " + strconv.Itoa(maxRedirects) + " redirects")
+			}
+			page.Redirects = append(page.Redirects, req.URL.String())
+			return nil
+		},
+	}
+
+	req, err := http.NewRequest(http.MethodGet, urlArg.URLForRequest, nil)
+	if err != nil {
+		fetchErrors.Inc("invalid_url")
+		errorsFound = append(errorsFound, errors.New("HTTPFetcher::fetch() - Error: Failed to GET: "+urlArg.URL))
+		return page, errorsFound
+	}
+
+	// If the page was fetched in a previous crawl, only download it if it changed since then (unless it's checked
+	// for accessibility or security problems, which needs its body and headers, so it's always downloaded):
+	var cached *CacheEntry
+	if fetcher.cache != nil && !fetcher.checkAccessibility && !fetcher.checkSecurity {
+		cached = fetcher.cache.get(urlArg.URL)
+	}
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
 
 	fetcher.rateLimiter.Limit() // limit number of GET requests to be done at the same time
-	resp, err := httpClient.Get(urlArg.URLForRequest)
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	page.ResponseTime = time.Since(start)
 	fetcher.rateLimiter.Free()
+	fetchDuration.Observe(page.ResponseTime.Seconds())
 
 	if err != nil {
+		fetchErrors.Inc(errorClass(err))
 		errorsFound = append(errorsFound, errors.New("HTTPFetcher::fetch() - Error: Failed to GET: "+urlArg.URL))
-		return []string{}, errorsFound
+		return page, errorsFound
 	}
+
+	defer resp.Body.Close() // Close body when finishing reading from it
+	fetchesTotal.Inc(strconv.Itoa(resp.StatusCode))
+
+	// Not modified since the previous crawl: reuse what was extracted from the page then:
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		cachedPage := *cached.Page
+		cachedPage.ResponseTime = page.ResponseTime
+		cachedPage.NotModified = true
+		return &cachedPage, errorsFound
+	}
+
+	page.StatusCode = resp.StatusCode
+	page.ContentType = resp.Header.Get("Content-type")
+	page.Size = resp.ContentLength
+	for _, header := range resp.Header["X-Robots-Tag"] {
+		page.Robots.parseRobotsHeader(header)
+	}
+	for _, header := range resp.Header["Link"] {
+		page.parseLinkHeader(header, parentURLParsed)
+	}
+
 	if resp.StatusCode != http.StatusOK {
+		if fetcher.cache != nil {
+			fetcher.cache.delete(urlArg.URL)
+		}
+		fetchErrors.Inc("http_" + strconv.Itoa(resp.StatusCode/100) + "xx")
 		errorsFound = append(errorsFound, errors.New("HTTPFetcher::fetch() - Error: Failed to GET: "+urlArg.URL+" with error code: "+resp.Status))
-		return []string{}, errorsFound
+		return page, errorsFound
 	}
 
-	defer resp.Body.Close() // Close body when finishing reading from it
-
 	// Only proceed if it's an HTML document:
-	if !strings.Contains(resp.Header.Get("Content-type"), "text/html") {
-		errorsFound = append(errorsFound, errors.New("HTTPFetcher::fetch() - Error: Content type of "+urlArg.URL+" is "+resp.Header.Get("Content-type")))
-		return []string{}, errorsFound
+	if !strings.Contains(page.ContentType, "text/html") {
+		fetchErrors.Inc("not_html")
+		errorsFound = append(errorsFound, errors.New("HTTPFetcher::fetch() - Error: Content type of "+urlArg.URL+" is "+page.ContentType))
+		return page, errorsFound
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	fetchedBytes.Add(float64(len(body)))
+	if err != nil {
+		fetchErrors.Inc(errorClass(err))
+		errorsFound = append(errorsFound, errors.New("HTTPFetcher::fetch() - Error: Failed to read the body of: "+urlArg.URL))
+		return page, errorsFound
 	}
+	page.Size = int64(len(body))
 
-	tokenizer := html.NewTokenizer(resp.Body)
+	// The assets of a page that was redirected (e.g. from http to https) are relative to the URL it was redirected to:
+	baseURL := parentURLParsed
+	if len(page.Redirects) > 0 {
+		baseURL = resp.Request.URL
+	}
+	errorsFound = append(errorsFound, parseHTML(page, body, parentURLParsed, baseURL)...)
 
-	for {
-		tokenType := tokenizer.Next()
+	if fetcher.checkAccessibility {
+		page.Accessibility, err = checkAccessibility(body)
+		if err != nil {
+			errorsFound = append(errorsFound, errors.New("HTTPFetcher::fetch() - Warning: failed to parse the DOM of: "+urlArg.URL))
+		}
+	}
 
-		switch {
-		case tokenType == html.ErrorToken: // Reached the end of the document
-			return urlsFound, errorsFound
-		case tokenType == html.StartTagToken:
-			token := tokenizer.Token()
+	if fetcher.checkSecurity && resp.Request.URL.Scheme == "https" {
+		page.Security = checkSecurity(page, resp)
+	}
 
-			// Check if the token corresponds to a <a>:
-			if token.Data != "a" {
-				continue
-			}
+	if fetcher.cache != nil {
+		fetcher.cache.set(urlArg.URL, resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"), page)
+	}
+	return page, errorsFound
+}
 
-			// Extract the href value, if there is one:
-			childURL, ok := getHref(token)
-			if !ok {
-				errorsFound = append(errorsFound, errors.New("HTTPFetcher::fetch() - Warning: <a> detected but no href present"))
-				continue
-			}
+// Check sends an HTTP HEAD to an url to check if it is reachable, without downloading it, and
+// returns the status code obtained. Servers that don't support HEAD requests are sent a GET instead.
+func (fetcher *HTTPFetcher) Check(urlArg string) (int, error) {
+	var httpClient = &http.Client{Transport: fetcher.transport, Timeout: time.Duration(fetcher.timeoutSeconds) * time.Second}
 
-			childURLParsed, err := url.Parse(childURL)
-			if err != nil {
-				errorsFound = append(errorsFound, errors.New("HTTPFetcher::fetch() - Warning: failed to parse the URL found: "+childURL))
-				continue
-			}
+	fetcher.rateLimiter.Limit()
+	defer fetcher.rateLimiter.Free()
 
-			if !isChildURLValid(childURLParsed, *parentURLParsed) {
-				continue
-			}
+	resp, err := httpClient.Head(urlArg)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = httpClient.Get(urlArg)
+	}
 
-			// Only add to the map of found urls if we didn't add before:
-			if _, ok := urlsFoundMap[childURLParsed.String()]; !ok {
-				urlsFoundMap[childURLParsed.String()] = true
-				urlsFound = append(urlsFound, childURLParsed.String())
-			}
+	if err != nil {
+		return 0, errors.New("HTTPFetcher::check() - Error: Failed to check: " + urlArg)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return resp.StatusCode, errors.New("HTTPFetcher::check() - Error: Failed to check: " + urlArg + " with error code: " + resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Download sends an HTTP GET to download the contents of an url that isn't a page to crawl (e.g. an image),
+// without parsing them. The page returned only has the metadata of the response (status code, content type and size).
+func (fetcher *HTTPFetcher) Download(urlArg string) (*Page, error) {
+	page := &Page{URL: urlArg, Size: -1, Links: []Link{}}
+	var httpClient = &http.Client{Transport: fetcher.transport, Timeout: time.Duration(fetcher.timeoutSeconds) * time.Second}
+
+	fetcher.rateLimiter.Limit()
+	defer fetcher.rateLimiter.Free()
+
+	start := time.Now()
+	resp, err := httpClient.Get(urlArg)
+	page.ResponseTime = time.Since(start)
+	if err != nil {
+		return page, errors.New("HTTPFetcher::download() - Error: Failed to download: " + urlArg)
+	}
+	defer resp.Body.Close()
+
+	page.StatusCode = resp.StatusCode
+	page.ContentType = resp.Header.Get("Content-type")
+	if resp.StatusCode >= 400 {
+		return page, errors.New("HTTPFetcher::download() - Error: Failed to download: " + urlArg + " with error code: " + resp.Status)
+	}
+
+	page.Size, err = io.Copy(ioutil.Discard, resp.Body)
+	if err != nil {
+		return page, errors.New("HTTPFetcher::download() - Error: Failed to read the body of: " + urlArg)
+	}
+	return page, nil
+}
+
+// appendText appends a piece of text to another, collapsing any whitespace between words.
+func appendText(text string, piece string) string {
+	for _, word := range strings.Fields(piece) {
+		if text != "" {
+			text += " "
 		}
+		text += word
 	}
+	return text
 }
 
 // isChildURLValid checks if the child URL is valid given the aprent URL (e.g. if it's the same domain).
@@ -138,6 +380,65 @@ func isChildURLValid(childURL *url.URL, fatherURL url.URL) bool {
 	return false
 }
 
+// normalizeLink returns the form in which an URL found in a page is crawled (or checked, if it's of another
+// domain), and whether it's of another domain. The URL returned is empty if it's neither crawled nor checked.
+// Every URL found in a page (links, canonical URLs and alternates) is normalized by it, so that they match
+// the URLs of the pages crawled.
+func normalizeLink(rawURL string, parentURL *url.URL) (string, bool, error) {
+	childURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false, err
+	}
+
+	if isChildURLValid(childURL, *parentURL) {
+		return childURL.String(), false, nil
+	}
+	if isExternalURLValid(childURL) {
+		return childURL.String(), true, nil
+	}
+	return "", false, nil
+}
+
+// normalizeURL returns the form of an URL found in a page that matches the URL of the page crawled for it
+// (see normalizeLink), or the URL itself if it's neither crawled nor checked.
+func normalizeURL(rawURL string, parentURL *url.URL) string {
+	link, _, err := normalizeLink(strings.TrimSpace(rawURL), parentURL)
+	if err != nil || link == "" {
+		return rawURL
+	}
+	return link
+}
+
+// isExternalURLValid checks if an URL of another domain is worth checking (i.e. it's an http(s) URL).
+func isExternalURLValid(externalURL *url.URL) bool {
+	if externalURL.Scheme != "http" && externalURL.Scheme != "https" {
+		return false
+	}
+
+	externalURL.Fragment = "" // delete fragments (e.g. #paragraph1)
+	return true
+}
+
+// getAttribute gets the value of an attribute of a token ("" if it isn't present).
+func getAttribute(token html.Token, key string) string {
+	for _, v := range token.Attr {
+		if v.Key == key {
+			return v.Val
+		}
+	}
+	return ""
+}
+
+// hasAttribute checks if a token has an attribute (even if its value is empty).
+func hasAttribute(token html.Token, key string) bool {
+	for _, v := range token.Attr {
+		if v.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
 // getHref gets the href attribute from an <a> token.
 func getHref(token html.Token) (url string, ok bool) {
 	// Iterate over all of the Token's attributes until we find an "href":
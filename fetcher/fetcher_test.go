@@ -1,13 +1,22 @@
 package fetcher
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/msandim/web-crawler/fetcher/urlwrapper"
+	"github.com/msandim/web-crawler/structured"
+
+	"golang.org/x/net/html"
 )
 
 func TestHTTPFetcher_Fetch_InvalidURL(t *testing.T) {
@@ -15,7 +24,8 @@ func TestHTTPFetcher_Fetch_InvalidURL(t *testing.T) {
 	errorMsg := "HTTPFetcher::fetch() - Error: failed to parse the URL to fetch: " + domain
 
 	fetcher := NewHTTPFetcher(4, 10)
-	urls, errs := fetcher.Fetch(urlwrapper.New(domain))
+	page, errs := fetcher.Fetch(urlwrapper.New(domain))
+	urls := page.URLs()
 
 	if len(urls) != 0 {
 		t.Errorf("Length of URLs was invalid. Expected: %d, Got: %d", 0, len(urls))
@@ -35,7 +45,8 @@ func TestHTTPFetcher_Fetch_InvalidGET(t *testing.T) {
 	errorMsg := "HTTPFetcher::fetch() - Error: Failed to GET: " + domain
 
 	fetcher := NewHTTPFetcher(4, 10)
-	urls, errs := fetcher.Fetch(urlwrapper.New(domain))
+	page, errs := fetcher.Fetch(urlwrapper.New(domain))
+	urls := page.URLs()
 
 	if len(urls) != 0 {
 		t.Errorf("Length of URLs was invalid. Expected: %d, Got: %d", 0, len(urls))
@@ -60,7 +71,8 @@ func TestHTTPFetcher_Fetch_TimeoutGET(t *testing.T) {
 	errorMsg := "HTTPFetcher::fetch() - Error: Failed to GET: " + domain
 
 	fetcher := NewHTTPFetcher(4, 1)
-	urls, errs := fetcher.Fetch(urlwrapper.NewTesting(domain, server.URL))
+	page, errs := fetcher.Fetch(urlwrapper.NewTesting(domain, server.URL))
+	urls := page.URLs()
 
 	if len(urls) != 0 {
 		t.Errorf("Length of URLs was invalid. Expected: %d, Got: %d", 0, len(urls))
@@ -85,7 +97,12 @@ func TestHTTPFetcher_Fetch_ErrorMessage(t *testing.T) {
 	errorMsg := "HTTPFetcher::fetch() - Error: Failed to GET: " + domain + " with error code: 404 Not Found"
 
 	fetcher := NewHTTPFetcher(4, 10)
-	urls, errs := fetcher.Fetch(urlwrapper.NewTesting(domain, server.URL))
+	page, errs := fetcher.Fetch(urlwrapper.NewTesting(domain, server.URL))
+	urls := page.URLs()
+
+	if page.StatusCode != http.StatusNotFound {
+		t.Errorf("Status code was invalid. Expected: %d, Got: %d", http.StatusNotFound, page.StatusCode)
+	}
 
 	if len(urls) != 0 {
 		t.Errorf("Length of URLs was invalid. Expected: %d, Got: %d", 0, len(urls))
@@ -110,7 +127,8 @@ func TestHTTPFetcher_Fetch_ContentType(t *testing.T) {
 	errorMsg := "HTTPFetcher::fetch() - Error: Content type of " + domain + " is application/pdf"
 
 	fetcher := NewHTTPFetcher(4, 10)
-	urls, errs := fetcher.Fetch(urlwrapper.NewTesting(domain, server.URL))
+	page, errs := fetcher.Fetch(urlwrapper.NewTesting(domain, server.URL))
+	urls := page.URLs()
 
 	if len(urls) != 0 {
 		t.Errorf("Length of URLs was invalid. Expected: %d, Got: %d", 0, len(urls))
@@ -142,7 +160,8 @@ func TestHTTPFetcher_Fetch_Errors(t *testing.T) {
 	errorMsg := ""
 
 	fetcher := NewHTTPFetcher(4, 10)
-	urls, errs := fetcher.Fetch(urlwrapper.NewTesting(domain, server.URL))
+	fetchedPage, errs := fetcher.Fetch(urlwrapper.NewTesting(domain, server.URL))
+	urls := fetchedPage.URLs()
 
 	if len(urls) != 6 {
 		t.Errorf("Length of URLs was invalid. Expected: %d, Got: %d", 6, len(urls))
@@ -193,3 +212,903 @@ func TestHTTPFetcher_Fetch_Errors(t *testing.T) {
 	}
 
 }
+
+func TestHTTPFetcher_Fetch_Links(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-type", "text/html; charset=utf-8")
+		w.Write([]byte(`<a href="/a">First <b>link</b>
+			text</a> outside <a href="/b"></a><a href="/a">Duplicate</a>`))
+	}))
+	defer server.Close()
+
+	domain := "http://monzo.com/"
+
+	fetcher := NewHTTPFetcher(4, 10)
+	page, errs := fetcher.Fetch(urlwrapper.NewTesting(domain, server.URL))
+
+	if len(errs) != 0 {
+		t.Errorf("Length of errors was invalid. Expected: %d, Got: %d", 0, len(errs))
+	}
+
+	if page.StatusCode != http.StatusOK {
+		t.Errorf("Status code was invalid. Expected: %d, Got: %d", http.StatusOK, page.StatusCode)
+	}
+
+	if page.ContentType != "text/html; charset=utf-8" {
+		t.Errorf("Content type was invalid. Expected: %s, Got: %s", "text/html; charset=utf-8", page.ContentType)
+	}
+
+	expected := []Link{
+		{URL: "http://monzo.com/a", Kind: LinkAnchor, AnchorText: "First link text"},
+		{URL: "http://monzo.com/b", Kind: LinkAnchor, AnchorText: ""},
+	}
+
+	if len(page.Links) != len(expected) {
+		t.Fatalf("Length of links was invalid. Expected: %d, Got: %d", len(expected), len(page.Links))
+	}
+
+	for i := range expected {
+		if page.Links[i] != expected[i] {
+			t.Errorf("Invalid link. Expected: %+v, Got: %+v", expected[i], page.Links[i])
+		}
+	}
+}
+
+func TestHTTPFetcher_Fetch_PageMetadata(t *testing.T) {
+	body := `<html><head><title>
+		Monzo   home</title></head>
+		<body><a href="/a" rel="Nofollow ugc">A</a><a href="/b" rel="noopener">B</a></body></html>`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-type", "text/html")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(4, 10)
+	page, _ := fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/", server.URL))
+
+	if page.Title != "Monzo home" {
+		t.Errorf("Title was invalid. Expected: %s, Got: %s", "Monzo home", page.Title)
+	}
+
+	if page.Size != int64(len(body)) {
+		t.Errorf("Size was invalid. Expected: %d, Got: %d", len(body), page.Size)
+	}
+
+	if page.ResponseTime <= 0 {
+		t.Errorf("Response time was not measured")
+	}
+
+	if len(page.Links) != 2 {
+		t.Fatalf("Length of links was invalid. Expected: %d, Got: %d", 2, len(page.Links))
+	}
+
+	if !page.Links[0].Nofollow || page.Links[1].Nofollow {
+		t.Errorf("Nofollow was invalid. Expected: [true false], Got: [%t %t]", page.Links[0].Nofollow, page.Links[1].Nofollow)
+	}
+}
+
+func TestHTTPFetcher_Fetch_Redirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/older", http.StatusMovedPermanently)
+		case "/older":
+			http.Redirect(w, r, "/new", http.StatusFound)
+		default:
+			w.Header().Add("Content-type", "text/html")
+		}
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(4, 10)
+	page, errs := fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/old", server.URL+"/old"))
+
+	if len(errs) != 0 {
+		t.Errorf("Length of errors was invalid. Expected: %d, Got: %d", 0, len(errs))
+	}
+
+	expected := []string{server.URL + "/older", server.URL + "/new"}
+	if len(page.Redirects) != len(expected) || page.Redirects[0] != expected[0] || page.Redirects[1] != expected[1] {
+		t.Errorf("Redirects were invalid. Expected: %v, Got: %v", expected, page.Redirects)
+	}
+}
+
+func TestHTTPFetcher_Fetch_ExternalLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-type", "text/html")
+		w.Write([]byte(`<a href="/a">A</a><a href="https://sapo.pt/x?y=1#z">Sapo</a><a href="ftp://sapo.pt/">FTP</a>`))
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(4, 10)
+	page, _ := fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/", server.URL))
+
+	expected := []Link{
+		{URL: "http://monzo.com/a", Kind: LinkAnchor, AnchorText: "A"},
+		{URL: "https://sapo.pt/x?y=1", Kind: LinkAnchor, AnchorText: "Sapo", External: true},
+	}
+
+	if len(page.Links) != len(expected) {
+		t.Fatalf("Length of links was invalid. Expected: %d, Got: %d", len(expected), len(page.Links))
+	}
+
+	for i := range expected {
+		if page.Links[i] != expected[i] {
+			t.Errorf("Invalid link. Expected: %+v, Got: %+v", expected[i], page.Links[i])
+		}
+	}
+
+	if urls := page.URLs(); len(urls) != 1 || urls[0] != "http://monzo.com/a" {
+		t.Errorf("URLs should only contain the links of the same domain, Got: %v", urls)
+	}
+}
+
+func TestHTTPFetcher_Check(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/nohead" && r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(4, 10)
+
+	if status, err := fetcher.Check(server.URL + "/ok"); status != http.StatusOK || err != nil {
+		t.Errorf("Check of /ok was invalid. Expected: 200 <nil>, Got: %d %v", status, err)
+	}
+
+	if status, err := fetcher.Check(server.URL + "/nohead"); status != http.StatusOK || err != nil {
+		t.Errorf("Check of /nohead was invalid. Expected: 200 <nil>, Got: %d %v", status, err)
+	}
+
+	errorMsg := "HTTPFetcher::check() - Error: Failed to check: " + server.URL + "/missing with error code: 404 Not Found"
+	if status, err := fetcher.Check(server.URL + "/missing"); status != http.StatusNotFound || err == nil || err.Error() != errorMsg {
+		t.Errorf("Check of /missing was invalid. Expected: 404 %s, Got: %d %v", errorMsg, status, err)
+	}
+}
+
+func TestHTTPFetcher_Download(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/logo.png":
+			w.Header().Add("Content-type", "image/png")
+			w.Write([]byte("PNG image"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(4, 10)
+
+	page, err := fetcher.Download(server.URL + "/logo.png")
+	if err != nil || page.StatusCode != http.StatusOK || page.ContentType != "image/png" || page.Size != 9 {
+		t.Errorf("Download of /logo.png was invalid. Expected: 200 image/png 9 <nil>, Got: %d %s %d %v", page.StatusCode, page.ContentType, page.Size, err)
+	}
+
+	errorMsg := "HTTPFetcher::download() - Error: Failed to download: " + server.URL + "/missing.png with error code: 404 Not Found"
+	if page, err := fetcher.Download(server.URL + "/missing.png"); page.StatusCode != http.StatusNotFound || err == nil || err.Error() != errorMsg {
+		t.Errorf("Download of /missing.png was invalid. Expected: 404 %s, Got: %d %v", errorMsg, page.StatusCode, err)
+	}
+}
+
+func TestCassette_RecordReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Add("Content-type", "text/html; charset=utf-8")
+			w.Write([]byte(`<html><title>Home</title><a href="/missing">Missing</a><img src="/logo.png"></html>`))
+		case "/logo.png":
+			w.Header().Add("Content-type", "image/png")
+			w.Write([]byte("PNG"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	cassette := NewCassette()
+	recorder := NewRecorder(NewHTTPFetcher(4, 10), cassette)
+
+	recordedPage, _ := recorder.Fetch(urlwrapper.New(server.URL + "/"))
+	_, recordedErrs := recorder.Fetch(urlwrapper.New(server.URL + "/missing"))
+	recorder.Check(server.URL + "/missing")
+	recorder.Download(server.URL + "/logo.png")
+	server.Close()
+
+	dir, err := ioutil.TempDir("", "cassette")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "cassette.json")
+	if err := cassette.Save(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	loaded, err := LoadCassette(path)
+	if err != nil || loaded.Len() != 4 {
+		t.Fatalf("Invalid cassette loaded (error: %v)", err)
+	}
+
+	// The server is closed: every request is served from the cassette:
+	replayer := NewReplayer(loaded)
+
+	page, errs := replayer.Fetch(urlwrapper.New(server.URL + "/"))
+	if len(errs) != 0 || page.Title != "Home" || !reflect.DeepEqual(page.Links, recordedPage.Links) || !reflect.DeepEqual(page.Assets, recordedPage.Assets) {
+		t.Errorf("Invalid page replayed. Expected: %+v, Got: %+v %v", recordedPage, page, errs)
+	}
+
+	page, errs = replayer.Fetch(urlwrapper.New(server.URL + "/missing"))
+	if page.StatusCode != http.StatusNotFound || len(errs) != len(recordedErrs) || errs[0].Error() != recordedErrs[0].Error() {
+		t.Errorf("Invalid missing page replayed. Expected: 404 %v, Got: %d %v", recordedErrs, page.StatusCode, errs)
+	}
+
+	if status, err := replayer.Check(server.URL + "/missing"); status != http.StatusNotFound || err == nil {
+		t.Errorf("Invalid check replayed. Expected: 404 and an error, Got: %d %v", status, err)
+	}
+
+	if asset, err := replayer.Download(server.URL + "/logo.png"); err != nil || asset.Size != 3 || asset.ContentType != "image/png" {
+		t.Errorf("Invalid download replayed. Expected: image/png 3 <nil>, Got: %s %d %v", asset.ContentType, asset.Size, err)
+	}
+
+	errorMsg := "Replayer::fetch() - Error: URL not recorded: " + server.URL + "/other"
+	if _, errs := replayer.Fetch(urlwrapper.New(server.URL + "/other")); len(errs) != 1 || errs[0].Error() != errorMsg {
+		t.Errorf("Invalid errors for a page not recorded. Expected: %s, Got: %v", errorMsg, errs)
+	}
+}
+
+func TestFileFetcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "site")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"index.html":      `<html><title>Home</title><a href="/about">About</a><a href="/blog/">Blog</a><a href="/missing">Missing</a><img src="/logo.png"></html>`,
+		"about.html":      `<html><title>About</title></html>`,
+		"blog/index.html": `<html><title>Blog</title></html>`,
+		"logo.png":        "\x89PNG",
+	}
+	for file, content := range files {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(file)), 0755)
+		if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", file, err)
+		}
+	}
+
+	fetcher, err := NewFileFetcher(dir, "http://monzo.com/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	titles := map[string]string{
+		"http://monzo.com":       "Home",
+		"http://monzo.com/":      "Home",
+		"http://monzo.com/about": "About",
+		"http://monzo.com/blog":  "Blog",
+		"http://monzo.com/blog/": "Blog",
+	}
+	for pageURL, title := range titles {
+		page, errs := fetcher.Fetch(urlwrapper.New(pageURL))
+		if len(errs) != 0 || page.StatusCode != http.StatusOK || page.Title != title || !strings.HasPrefix(page.ContentType, "text/html") {
+			t.Errorf("Invalid page %s. Expected: 200 %s, Got: %d %s %v", pageURL, title, page.StatusCode, page.Title, errs)
+		}
+	}
+
+	page, _ := fetcher.Fetch(urlwrapper.New("http://monzo.com/"))
+	if len(page.Links) != 3 || !reflect.DeepEqual(page.Assets, []string{"http://monzo.com/logo.png"}) {
+		t.Errorf("Invalid links of the home page: %+v %v", page.Links, page.Assets)
+	}
+
+	errorMsg := "FileFetcher::fetch() - Error: Failed to GET: http://monzo.com/missing with error code: 404 Not Found"
+	page, errs := fetcher.Fetch(urlwrapper.New("http://monzo.com/missing"))
+	if page.StatusCode != http.StatusNotFound || len(errs) != 1 || errs[0].Error() != errorMsg {
+		t.Errorf("Invalid missing page. Expected: 404 %s, Got: %d %v", errorMsg, page.StatusCode, errs)
+	}
+
+	// Paths can't go outside of the directory:
+	if page, _ := fetcher.Fetch(urlwrapper.New("http://monzo.com/../" + filepath.Base(dir) + "/about.html")); page.StatusCode != http.StatusNotFound {
+		t.Errorf("Page outside of the directory was read. Expected: 404, Got: %d", page.StatusCode)
+	}
+
+	if status, err := fetcher.Check("http://monzo.com/missing"); status != http.StatusNotFound || err == nil {
+		t.Errorf("Invalid check of a missing page. Expected: 404 and an error, Got: %d %v", status, err)
+	}
+	if _, err := fetcher.Check("http://other.com/"); err == nil {
+		t.Errorf("URLs of other domains should not be checked")
+	}
+
+	asset, err := fetcher.Download("http://monzo.com/logo.png")
+	if err != nil || asset.ContentType != "image/png" || asset.Size != 4 {
+		t.Errorf("Invalid download of the logo. Expected: image/png 4 <nil>, Got: %s %d %v", asset.ContentType, asset.Size, err)
+	}
+}
+
+func TestHTTPFetcher_Fetch_Cache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Add("Content-type", "text/html")
+		w.Header().Add("ETag", `"v1"`)
+		w.Write([]byte(`<title>Cached</title><a href="/a">A</a>`))
+	}))
+	defer server.Close()
+
+	cache := NewCache()
+	fetcher := NewHTTPFetcher(4, 10)
+	fetcher.SetCache(cache)
+
+	page, _ := fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/", server.URL))
+	if page.NotModified || cache.Len() != 1 {
+		t.Fatalf("First fetch should download the page and cache it")
+	}
+
+	// Persist the cache, as done between two crawls:
+	f, err := ioutil.TempFile("", "cache")
+	if err != nil {
+		t.Fatalf("Failed to create temporary file: %v", err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	if err := cache.Save(f.Name()); err != nil {
+		t.Fatalf("Failed to save the cache: %v", err)
+	}
+
+	loadedCache, err := LoadCache(f.Name())
+	if err != nil {
+		t.Fatalf("Failed to load the cache: %v", err)
+	}
+	fetcher.SetCache(loadedCache)
+
+	cachedPage, errs := fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/", server.URL))
+
+	if len(errs) != 0 {
+		t.Errorf("Length of errors was invalid. Expected: %d, Got: %d", 0, len(errs))
+	}
+
+	if requests != 2 {
+		t.Errorf("Number of requests was invalid. Expected: %d, Got: %d", 2, requests)
+	}
+
+	if !cachedPage.NotModified || cachedPage.StatusCode != http.StatusOK {
+		t.Errorf("Second fetch should reuse the cached page. Got: %+v", cachedPage)
+	}
+
+	if cachedPage.Title != "Cached" || cachedPage.Size != page.Size {
+		t.Errorf("Cached page was invalid. Expected: %+v, Got: %+v", page, cachedPage)
+	}
+
+	if page.Text == "" || cachedPage.Text != "" {
+		t.Errorf("Text of the page shouldn't be cached. Got: %q", cachedPage.Text)
+	}
+
+	if urls := cachedPage.URLs(); len(urls) != 1 || urls[0] != "http://monzo.com/a" {
+		t.Errorf("Links of the cached page were invalid. Got: %v", urls)
+	}
+}
+
+func TestHTTPFetcher_Fetch_Cache_Checks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Add("Content-type", "text/html")
+		w.Header().Add("ETag", `"v1"`)
+		w.Write([]byte(`<html lang="en"><body><img src="logo.png"></body></html>`))
+	}))
+	defer server.Close()
+
+	cache := NewCache()
+	fetcher := NewHTTPFetcher(4, 10)
+	fetcher.SetCache(cache)
+	fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/", server.URL))
+
+	// The pages checked are downloaded again, even if they didn't change since the previous crawl:
+	fetcher.SetAccessibilityCheck(true)
+	page, _ := fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/", server.URL))
+	if page.NotModified || len(page.Accessibility) != 1 {
+		t.Errorf("Page checked was not downloaded again. Expected: %d violation, Got: %v", 1, page.Accessibility)
+	}
+
+	// The problems found aren't reused by a crawl without the checks:
+	fetcher.SetAccessibilityCheck(false)
+	page, _ = fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/", server.URL))
+	if !page.NotModified || page.Accessibility != nil {
+		t.Errorf("Cached page had the problems of a previous crawl: %v", page.Accessibility)
+	}
+}
+
+func TestLoadCache_MissingFile(t *testing.T) {
+	cache, err := LoadCache("does-not-exist.json")
+	if err != nil || cache == nil || cache.Len() != 0 {
+		t.Errorf("Loading a missing cache file should return an empty cache. Got: %v, %v", cache, err)
+	}
+}
+
+func TestHTTPFetcher_Fetch_Text(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-type", "text/html")
+		w.Write([]byte(`<html><head><title>Home</title><style>p { color: red; }</style>
+			<script>var x = "<p>hidden</p>";</script></head>
+			<body><h1>Hello</h1> <p>visible
+			text</p><noscript>Enable JS</noscript></body></html>`))
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(4, 10)
+	page, _ := fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/", server.URL))
+
+	if page.Text != "Home Hello visible text" {
+		t.Errorf("Text was invalid. Expected: %s, Got: %s", "Home Hello visible text", page.Text)
+	}
+
+	if page.TextHash == "" {
+		t.Errorf("Text hash was invalid: %s", page.TextHash)
+	}
+
+	if page.SimHash == 0 {
+		t.Errorf("SimHash was not computed")
+	}
+}
+
+func TestHTTPFetcher_Fetch_NoText(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-type", "text/html")
+		w.Write([]byte(`<html><body><img src="/a.png"><a href="/b"></a></body></html>`))
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(4, 10)
+	page, _ := fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/", server.URL))
+
+	// Pages without text aren't duplicates of each other:
+	if page.WordCount != 0 || page.TextHash != "" || page.SimHash != 0 {
+		t.Errorf("Page without text shouldn't be fingerprinted. Got: %d words, %q, %d", page.WordCount, page.TextHash, page.SimHash)
+	}
+}
+
+func TestHTTPFetcher_Fetch_Robots(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-type", "text/html")
+		switch r.URL.Path {
+		case "/meta":
+			w.Write([]byte(`<meta name="Robots" content="NOINDEX"><a href="/a" rel="ugc Sponsored noopener">A</a>`))
+		case "/none":
+			w.Write([]byte(`<meta name="robots" content="none">`))
+		case "/header":
+			w.Header().Add("X-Robots-Tag", "googlebot: noindex")
+			w.Header().Add("X-Robots-Tag", "nofollow, unavailable_after: 25 Jun 2010 15:00:00 PST")
+		}
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(4, 10)
+
+	tests := []struct {
+		path   string
+		robots Robots
+	}{
+		{"/meta", Robots{NoIndex: true}},
+		{"/none", Robots{NoIndex: true, NoFollow: true}},
+		{"/header", Robots{NoFollow: true}},
+	}
+
+	for _, test := range tests {
+		page, _ := fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com"+test.path, server.URL+test.path))
+		if page.Robots != test.robots {
+			t.Errorf("Robots directives of %s were invalid. Expected: %+v, Got: %+v", test.path, test.robots, page.Robots)
+		}
+	}
+
+	page, _ := fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/meta", server.URL+"/meta"))
+	if len(page.Links) != 1 || !page.Links[0].Nofollow || page.Links[0].Rel != "ugc sponsored" {
+		t.Errorf("Rel of the link was invalid. Expected: %s, Got: %+v", "ugc sponsored", page.Links)
+	}
+}
+
+func TestHTTPFetcher_Fetch_Canonical(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-type", "text/html")
+		switch r.URL.Path {
+		case "/html":
+			w.Write([]byte(`<head><link rel="canonical" href="/main#top" />
+				<link rel="alternate" hreflang="en" href="http://monzo.com/en">
+				<link rel="alternate" hreflang="x-default" href="/html">
+				<link rel="alternate" type="application/rss+xml" href="/feed"></head>`))
+		case "/header":
+			w.Header().Add("Link", `<http://monzo.com/main>; rel="canonical", <http://monzo.com/pt>; rel="alternate"; hreflang="pt"`)
+			w.Write([]byte(`<link rel="canonical" href="/other">`))
+		}
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(4, 10)
+
+	page, _ := fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/html", server.URL+"/html"))
+
+	if page.Canonical != "http://monzo.com/main" {
+		t.Errorf("Canonical URL was invalid. Expected: %s, Got: %s", "http://monzo.com/main", page.Canonical)
+	}
+
+	expectedAlternates := []Alternate{{Lang: "en", URL: "http://monzo.com/en"}, {Lang: "x-default", URL: "http://monzo.com/html"}}
+	if !reflect.DeepEqual(page.Alternates, expectedAlternates) {
+		t.Errorf("Alternates were invalid. Expected: %v, Got: %v", expectedAlternates, page.Alternates)
+	}
+
+	// The page itself isn't a link:
+	expectedLinks := []Link{{URL: "http://monzo.com/main", Kind: LinkCanonical}, {URL: "http://monzo.com/en", Kind: LinkAlternate}}
+	if !reflect.DeepEqual(page.Links, expectedLinks) {
+		t.Errorf("Links were invalid. Expected: %v, Got: %v", expectedLinks, page.Links)
+	}
+
+	// The canonical URL of the Link header has precedence over the one of the HTML:
+	page, _ = fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/header", server.URL+"/header"))
+
+	if page.Canonical != "http://monzo.com/main" {
+		t.Errorf("Canonical URL was invalid. Expected: %s, Got: %s", "http://monzo.com/main", page.Canonical)
+	}
+
+	if len(page.Alternates) != 1 || page.Alternates[0] != (Alternate{Lang: "pt", URL: "http://monzo.com/pt"}) {
+		t.Errorf("Alternates were invalid: %v", page.Alternates)
+	}
+}
+
+func TestHTTPFetcher_Fetch_Canonical_HTTPS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-type", "text/html")
+		switch r.URL.Path {
+		case "/html":
+			w.Write([]byte(`<head><link rel="canonical" href="/main?utm_source=feed" />
+				<link rel="alternate" hreflang="pt" href="https://monzo.com/pt?lang=pt"></head>`))
+		case "/header":
+			w.Header().Add("Link", `</main?utm_source=feed>; rel="canonical", <https://monzo.com/pt?lang=pt>; rel="alternate"; hreflang="pt"`)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(4, 10)
+
+	// The canonical URLs and alternates are the URLs of the pages crawled for them (the ones of their links):
+	for _, path := range []string{"/html", "/header"} {
+		page, _ := fetcher.Fetch(urlwrapper.NewTesting("https://monzo.com"+path+"?page=2", server.URL+path))
+
+		if page.Canonical != "http://monzo.com/main" {
+			t.Errorf("Canonical URL of %s was invalid. Expected: %s, Got: %s", path, "http://monzo.com/main", page.Canonical)
+		}
+
+		expectedAlternates := []Alternate{{Lang: "pt", URL: "https://monzo.com/pt"}}
+		if !reflect.DeepEqual(page.Alternates, expectedAlternates) {
+			t.Errorf("Alternates of %s were invalid. Expected: %v, Got: %v", path, expectedAlternates, page.Alternates)
+		}
+
+		expectedLinks := []Link{{URL: page.Canonical, Kind: LinkCanonical}, {URL: page.Alternates[0].URL, Kind: LinkAlternate}}
+		if !reflect.DeepEqual(page.Links, expectedLinks) {
+			t.Errorf("Links of %s were invalid. Expected: %v, Got: %v", path, expectedLinks, page.Links)
+		}
+	}
+}
+
+func TestHTTPFetcher_Fetch_SEO(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-type", "text/html")
+		w.Write([]byte(`<html><head><title>Home</title>
+			<meta name="description" content="  Banking made
+			easy "><meta name="description" content="Other"></head>
+			<body><h1>Hello <em>world</em></h1><h3>Sub</h3><p>Some text</p>
+			<img src="a.png" alt="A"><img src="b.png" alt=""><img src="c.png"></body></html>`))
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(4, 10)
+	page, _ := fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/", server.URL))
+
+	if page.Description != "Banking made easy" {
+		t.Errorf("Description was invalid. Expected: %s, Got: %s", "Banking made easy", page.Description)
+	}
+
+	expectedHeadings := []Heading{{Level: 1, Text: "Hello world"}, {Level: 3, Text: "Sub"}}
+	if !reflect.DeepEqual(page.Headings, expectedHeadings) {
+		t.Errorf("Headings were invalid. Expected: %v, Got: %v", expectedHeadings, page.Headings)
+	}
+
+	if page.WordCount != 6 {
+		t.Errorf("Word count was invalid. Expected: %d, Got: %d", 6, page.WordCount)
+	}
+
+	if page.Images != 3 || page.ImagesWithAlt != 2 {
+		t.Errorf("Images were invalid. Expected: 3 (2 with alt), Got: %d (%d with alt)", page.Images, page.ImagesWithAlt)
+	}
+}
+
+func TestHTTPFetcher_Fetch_Accessibility(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-type", "text/html")
+		w.Write([]byte(`<html><body>
+			<h1>Title</h1><h3 id="x">Skipped</h3><h2 id="x">Back</h2><h3>Fine</h3>
+			<img src="logo.png"><img src="spacer.png" alt="">
+			<a href="/a"></a><a href="/b"><img src="b.png" alt="B"></a><a href="/c" aria-label="C"></a><a name="anchor"></a>
+			<button></button><button><span>OK</span></button>
+			<form><input type="text" name="q"><input type="hidden" name="h"><input type="submit">
+			<label>Name <input type="text" name="name"></label>
+			<label for="email">Email</label><input id="email" type="email"><textarea name="msg"></textarea></form>
+			</body></html>`))
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(4, 10)
+	page, _ := fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/", server.URL))
+
+	if page.Accessibility != nil {
+		t.Errorf("Accessibility should not be checked by default")
+	}
+
+	fetcher.SetAccessibilityCheck(true)
+	page, _ = fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/", server.URL))
+
+	expected := []AccessibilityViolation{
+		{Rule: AccessibilityHTMLLang, Element: "<html>", Message: "has no lang attribute"},
+		{Rule: AccessibilityHeadingOrder, Element: `<h3 id="x">`, Message: "skips heading levels (follows <h1>)"},
+		{Rule: AccessibilityImageAlt, Element: `<img src="logo.png">`, Message: "has no alt attribute"},
+		{Rule: AccessibilityEmptyLink, Element: `<a href="/a">`, Message: "has no text"},
+		{Rule: AccessibilityEmptyButton, Element: "<button>", Message: "has no text"},
+		{Rule: AccessibilityInputLabel, Element: `<input name="q" type="text">`, Message: "has no label"},
+		{Rule: AccessibilityInputLabel, Element: `<textarea name="msg">`, Message: "has no label"},
+		{Rule: AccessibilityDuplicateID, Element: `id="x"`, Message: "is used by 2 elements"},
+	}
+	if !reflect.DeepEqual(page.Accessibility, expected) {
+		t.Errorf("Accessibility violations were invalid.\nExpected: %v\nGot: %v", expected, page.Accessibility)
+	}
+}
+
+func TestHTTPFetcher_Fetch_Assets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-type", "text/html")
+		w.Write([]byte(`<head><link rel="stylesheet" href="/style.css"><link rel="canonical" href="/">
+			<script src="http://cdn.com/app.js"></script></head>
+			<body><img src="a.png" srcset="a.png 1x, /b.png 2x"><video src="v.mp4" poster="p.jpg"></video>
+			<script>var inline;</script><img src="/style.css#x"></body>`))
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(4, 10)
+	page, _ := fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/blog/", server.URL))
+
+	expected := []string{
+		"http://monzo.com/style.css",
+		"http://cdn.com/app.js",
+		"http://monzo.com/blog/a.png",
+		"http://monzo.com/b.png",
+		"http://monzo.com/blog/v.mp4",
+		"http://monzo.com/blog/p.jpg",
+	}
+	if !reflect.DeepEqual(page.Assets, expected) {
+		t.Errorf("Assets were invalid. Expected: %v, Got: %v", expected, page.Assets)
+	}
+}
+
+func TestHTTPFetcher_Fetch_Security(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-type", "text/html")
+		if r.URL.Path == "/secure" {
+			w.Header().Add("Strict-Transport-Security", "max-age=31536000")
+			w.Header().Add("Content-Security-Policy", "default-src 'self'")
+			w.Header().Add("X-Content-Type-Options", "nosniff")
+			w.Header().Add("Referrer-Policy", "no-referrer")
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "1", Secure: true})
+			w.Write([]byte(`<img src="/logo.png"><script src="https://cdn.com/app.js"></script>`))
+			return
+		}
+		w.Header().Add("X-Content-Type-Options", "sniff")
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+		w.Write([]byte(`<img src="http://monzo.com/logo.png"><a href="http://monzo.com/page">Page</a>`))
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(4, 10)
+	fetcher.SetTransport(server.Client().Transport)
+
+	page, errs := fetcher.Fetch(urlwrapper.NewTesting("https://monzo.com/", server.URL))
+	if len(errs) != 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if page.Security != nil {
+		t.Errorf("Security should not be checked by default")
+	}
+
+	fetcher.SetSecurityCheck(true)
+	page, _ = fetcher.Fetch(urlwrapper.NewTesting("https://monzo.com/", server.URL))
+
+	expected := []SecurityFinding{
+		{Rule: SecurityHSTSMissing, Message: "the Strict-Transport-Security header is missing"},
+		{Rule: SecurityCSPMissing, Message: "the Content-Security-Policy header is missing"},
+		{Rule: SecurityContentTypeOptions, Message: "the X-Content-Type-Options header isn't set to nosniff"},
+		{Rule: SecurityReferrerPolicyMissing, Message: "the Referrer-Policy header is missing"},
+		{Rule: SecurityInsecureCookie, Message: "the cookie session is set without the Secure attribute"},
+		{Rule: SecurityMixedContent, Message: "the asset http://monzo.com/logo.png is loaded over HTTP"},
+	}
+	if !reflect.DeepEqual(page.Security, expected) {
+		t.Errorf("Security findings were invalid.\nExpected: %v\nGot: %v", expected, page.Security)
+	}
+
+	page, _ = fetcher.Fetch(urlwrapper.NewTesting("https://monzo.com/secure", server.URL+"/secure"))
+	if len(page.Security) != 0 {
+		t.Errorf("Secure page should have no findings, Got: %v", page.Security)
+	}
+
+	// Pages fetched over HTTP aren't checked:
+	httpServer := httptest.NewServer(server.Config.Handler)
+	defer httpServer.Close()
+
+	page, _ = fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/", httpServer.URL))
+	if len(page.Security) != 0 {
+		t.Errorf("HTTP page should not be checked, Got: %v", page.Security)
+	}
+}
+
+func TestHTTPFetcher_Fetch_Security_Redirect(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-type", "text/html")
+		w.Write([]byte(`<img src="/logo.png"><script src="http://cdn.com/app.js"></script>`))
+	}))
+	defer server.Close()
+
+	// The HTTP version of the site redirects to the HTTPS one:
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, server.URL+r.URL.Path, http.StatusMovedPermanently)
+	}))
+	defer httpServer.Close()
+
+	fetcher := NewHTTPFetcher(4, 10)
+	fetcher.SetTransport(server.Client().Transport)
+	fetcher.SetSecurityCheck(true)
+
+	page, _ := fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/", httpServer.URL+"/"))
+
+	// The relative assets are loaded over HTTPS, from the URL the page was redirected to:
+	expectedAssets := []string{server.URL + "/logo.png", "http://cdn.com/app.js"}
+	if !reflect.DeepEqual(page.Assets, expectedAssets) {
+		t.Errorf("Assets were invalid. Expected: %v, Got: %v", expectedAssets, page.Assets)
+	}
+
+	mixedContent := []SecurityFinding{}
+	for _, finding := range page.Security {
+		if finding.Rule == SecurityMixedContent {
+			mixedContent = append(mixedContent, finding)
+		}
+	}
+	expected := []SecurityFinding{{Rule: SecurityMixedContent, Message: "the asset http://cdn.com/app.js is loaded over HTTP"}}
+	if !reflect.DeepEqual(mixedContent, expected) {
+		t.Errorf("Mixed content findings were invalid.\nExpected: %v\nGot: %v", expected, mixedContent)
+	}
+}
+
+func TestHTTPFetcher_Fetch_StructuredData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-type", "text/html")
+		switch r.URL.Path {
+		case "/data":
+			w.Write([]byte(`<head><meta property="og:title" content="Monzo"><meta name="twitter:card" content="summary">
+				<script type="application/ld+json">{"@type": "Organization", "name": "Monzo"}</script>
+				<script type="application/ld+json">{"@type": </script></head>
+				<body><div itemscope itemtype="https://schema.org/Person"><span itemprop="name">Tom</span></div></body>`))
+		default:
+			w.Write([]byte(`<p>Nothing</p>`))
+		}
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(4, 10)
+
+	page, _ := fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/data", server.URL+"/data"))
+	data := page.StructuredData
+	if data == nil {
+		t.Fatalf("Structured data was not extracted")
+	}
+
+	if len(data.JSONLD) != 1 || string(data.JSONLD[0]) != `{"@type":"Organization","name":"Monzo"}` || len(data.Errors) != 1 {
+		t.Errorf("JSON-LD was invalid: %s (errors: %v)", data.JSONLD, data.Errors)
+	}
+
+	if data.OpenGraph["title"] != "Monzo" || data.Twitter["card"] != "summary" {
+		t.Errorf("Meta tags were invalid: %v, %v", data.OpenGraph, data.Twitter)
+	}
+
+	if len(data.Microdata) != 1 || data.Microdata[0].Properties["name"][0] != "Tom" {
+		t.Errorf("Microdata was invalid: %v", data.Microdata)
+	}
+
+	if page.Text != "Tom" {
+		t.Errorf("JSON-LD should not be part of the text, Got: %s", page.Text)
+	}
+
+	page, _ = fetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/", server.URL))
+	if page.StructuredData != nil {
+		t.Errorf("Page without structured data should have none, Got: %+v", page.StructuredData)
+	}
+}
+
+func TestHTTPFetcher_Fetch_Metrics(t *testing.T) {
+	body := "<html><body><a href=\"/a\">A</a></body></html>"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	fetched, missing, bytes := fetchesTotal.Value("200"), fetchErrors.Value("http_4xx"), fetchedBytes.Value()
+
+	fetcher := NewHTTPFetcher(4, 10)
+	fetcher.Fetch(urlwrapper.New(server.URL + "/"))
+	fetcher.Fetch(urlwrapper.New(server.URL + "/missing"))
+
+	if value := fetchesTotal.Value("200") - fetched; value != 1 {
+		t.Errorf("Number of pages fetched with status 200 was invalid. Expected: %v, Got: %v", 1, value)
+	}
+	if value := fetchErrors.Value("http_4xx") - missing; value != 1 {
+		t.Errorf("Number of 4xx errors was invalid. Expected: %v, Got: %v", 1, value)
+	}
+	if value := fetchedBytes.Value() - bytes; value != float64(len(body)) {
+		t.Errorf("Number of bytes fetched was invalid. Expected: %v, Got: %v", len(body), value)
+	}
+}
+
+func TestExtractMicrodata(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><body>
+		<div itemscope itemtype="https://schema.org/Product" itemid="urn:1">
+			<h1 itemprop="name">Monzo <b>Plus</b></h1>
+			<img itemprop="image" src="/plus.png">
+			<a itemprop="url sameAs" href="plus">More</a>
+			<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+				<meta itemprop="price" content="5">
+				<span itemprop="name">Monthly</span>
+			</div>
+			<time itemprop="releaseDate" datetime="2020-07-01">July</time>
+		</div>
+		<p itemscope><span itemprop="name">Other</span></p>
+		</body></html>`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	base, _ := url.Parse("http://monzo.com/products/")
+	items := extractMicrodata(doc, base)
+
+	expected := []*structured.Item{
+		{
+			Type: []string{"https://schema.org/Product"},
+			ID:   "urn:1",
+			Properties: map[string][]interface{}{
+				"name":   {"Monzo Plus"},
+				"image":  {"http://monzo.com/plus.png"},
+				"url":    {"http://monzo.com/products/plus"},
+				"sameAs": {"http://monzo.com/products/plus"},
+				"offers": {&structured.Item{
+					Type:       []string{"https://schema.org/Offer"},
+					Properties: map[string][]interface{}{"price": {"5"}, "name": {"Monthly"}},
+				}},
+				"releaseDate": {"2020-07-01"},
+			},
+		},
+		{Properties: map[string][]interface{}{"name": {"Other"}}},
+	}
+	if !reflect.DeepEqual(items, expected) {
+		got, _ := json.Marshal(items)
+		t.Errorf("Microdata was invalid. Got: %s", got)
+	}
+}
@@ -0,0 +1,177 @@
+package fetcher
+
+import (
+	"errors"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/msandim/web-crawler/fetcher/urlwrapper"
+)
+
+// FileFetcher is a Fetcher that reads the pages of a domain from a directory (e.g. the output of a static
+// site generator) instead of making HTTP requests: the path of every URL of the domain is mapped onto the
+// directory, directories are resolved to their index.html and missing files are reported as 404s.
+type FileFetcher struct {
+	root   string
+	domain *url.URL
+
+	checkAccessibility bool
+}
+
+// NewFileFetcher returns a FileFetcher of the pages of a domain, read from a directory.
+func NewFileFetcher(root string, domain string) (*FileFetcher, error) {
+	domainURL, err := url.Parse(domain)
+	if err != nil {
+		return nil, errors.New("FileFetcher::New() - Error: invalid domain: " + domain)
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, errors.New("FileFetcher::New() - Error: not a directory: " + root)
+	}
+	return &FileFetcher{root: root, domain: domainURL}, nil
+}
+
+// SetAccessibilityCheck sets if the DOM of the pages fetched is checked for accessibility problems.
+func (fetcher *FileFetcher) SetAccessibilityCheck(check bool) {
+	fetcher.checkAccessibility = check
+}
+
+// Fetch reads the file of an url and, if it's an HTML page, extracts its contents.
+func (fetcher *FileFetcher) Fetch(urlArg *urlwrapper.URLWrapper) (*Page, []error) {
+	page := &Page{URL: urlArg.URL, Size: -1, Links: []Link{}}
+	errorsFound := []error{}
+
+	parentURLParsed, err := url.Parse(urlArg.URL)
+	if err != nil {
+		errorsFound = append(errorsFound, errors.New("FileFetcher::fetch() - Error: failed to parse the URL to fetch: "+urlArg.URL))
+		return page, errorsFound
+	}
+
+	start := time.Now()
+	body, contentType, statusCode := fetcher.read(parentURLParsed)
+	page.ResponseTime = time.Since(start)
+	page.StatusCode = statusCode
+
+	if statusCode != http.StatusOK {
+		errorsFound = append(errorsFound, errors.New("FileFetcher::fetch() - Error: Failed to GET: "+urlArg.URL+" with error code: "+statusText(statusCode)))
+		return page, errorsFound
+	}
+
+	page.ContentType = contentType
+	page.Size = int64(len(body))
+
+	// Only proceed if it's an HTML document:
+	if !strings.Contains(page.ContentType, "text/html") {
+		errorsFound = append(errorsFound, errors.New("FileFetcher::fetch() - Error: Content type of "+urlArg.URL+" is "+page.ContentType))
+		return page, errorsFound
+	}
+
+	errorsFound = append(errorsFound, parseHTML(page, body, parentURLParsed, parentURLParsed)...)
+
+	if fetcher.checkAccessibility {
+		page.Accessibility, err = checkAccessibility(body)
+		if err != nil {
+			errorsFound = append(errorsFound, errors.New("FileFetcher::fetch() - Warning: failed to parse the DOM of: "+urlArg.URL))
+		}
+	}
+	return page, errorsFound
+}
+
+// Check returns the status code of an url of the domain: 200 if it has a file and 404 otherwise.
+// URLs of other domains can't be checked.
+func (fetcher *FileFetcher) Check(urlArg string) (int, error) {
+	parsed, err := url.Parse(urlArg)
+	if err != nil || parsed.Host != fetcher.domain.Host {
+		return 0, errors.New("FileFetcher::check() - Error: Failed to check: " + urlArg)
+	}
+
+	if _, ok := fetcher.resolve(parsed); !ok {
+		return http.StatusNotFound, errors.New("FileFetcher::check() - Error: Failed to check: " + urlArg + " with error code: " + statusText(http.StatusNotFound))
+	}
+	return http.StatusOK, nil
+}
+
+// Download reads the file of an url that isn't a page to crawl (e.g. an image), returning its status code,
+// content type and size.
+func (fetcher *FileFetcher) Download(urlArg string) (*Page, error) {
+	page := &Page{URL: urlArg, Size: -1, Links: []Link{}}
+
+	parsed, err := url.Parse(urlArg)
+	if err != nil {
+		return page, errors.New("FileFetcher::download() - Error: Failed to download: " + urlArg)
+	}
+
+	body, contentType, statusCode := fetcher.read(parsed)
+	page.StatusCode = statusCode
+	if statusCode != http.StatusOK {
+		return page, errors.New("FileFetcher::download() - Error: Failed to download: " + urlArg + " with error code: " + statusText(statusCode))
+	}
+
+	page.ContentType = contentType
+	page.Size = int64(len(body))
+	return page, nil
+}
+
+// read returns the contents of the file of an url, its content type and the status code of the "response".
+func (fetcher *FileFetcher) read(urlParsed *url.URL) ([]byte, string, int) {
+	if urlParsed.Host != fetcher.domain.Host {
+		return nil, "", http.StatusNotFound
+	}
+
+	file, ok := fetcher.resolve(urlParsed)
+	if !ok {
+		return nil, "", http.StatusNotFound
+	}
+
+	body, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, "", http.StatusForbidden
+	}
+	return body, fileContentType(file, body), http.StatusOK
+}
+
+// resolve returns the file of an url of the domain: the file at its path, the index.html of the directory
+// at its path or, for paths without an extension, the file at its path with the .html extension (e.g. "/about"
+// to "about.html").
+func (fetcher *FileFetcher) resolve(urlParsed *url.URL) (string, bool) {
+	// The path is cleaned as an absolute path, so it can't go outside of the root directory:
+	urlPath := path.Clean("/" + urlParsed.Path)
+	file := filepath.Join(fetcher.root, filepath.FromSlash(urlPath))
+
+	candidates := []string{file, filepath.Join(file, "index.html")}
+	if path.Ext(urlPath) == "" {
+		candidates = append(candidates, file+".html")
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// fileContentType guesses the content type of a file from its extension or, if unknown, from its contents.
+func fileContentType(file string, body []byte) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(file)); contentType != "" {
+		return contentType
+	}
+	return http.DetectContentType(body)
+}
+
+// statusText returns the status line of an HTTP status code (e.g. "404 Not Found").
+func statusText(statusCode int) string {
+	return strconv.Itoa(statusCode) + " " + http.StatusText(statusCode)
+}
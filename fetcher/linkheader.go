@@ -0,0 +1,63 @@
+package fetcher
+
+import (
+	"net/url"
+	"strings"
+)
+
+// parseLinkHeader extracts the canonical URL and the alternate versions in other languages of a page
+// from an HTTP Link header (e.g. `<https://a.com/en>; rel="alternate"; hreflang="en", <https://a.com/>; rel="canonical"`).
+func (page *Page) parseLinkHeader(header string, parentURLParsed *url.URL) {
+	for header != "" {
+		// Every link starts with its URL between angle brackets:
+		start := strings.Index(header, "<")
+		end := strings.Index(header, ">")
+		if start < 0 || end < start {
+			return
+		}
+		target := normalizeURL(header[start+1:end], parentURLParsed)
+		header = header[end+1:]
+
+		// Followed by its parameters, until the next link:
+		params := header
+		if next := strings.Index(header, "<"); next >= 0 {
+			params = header[:next]
+			header = header[next:]
+		} else {
+			header = ""
+		}
+
+		rels, hreflang := parseLinkParams(params)
+		for _, rel := range rels {
+			switch {
+			case rel == "canonical" && page.Canonical == "":
+				page.Canonical = target
+			case rel == "alternate" && hreflang != "":
+				page.Alternates = append(page.Alternates, Alternate{Lang: hreflang, URL: target})
+			}
+		}
+	}
+}
+
+// parseLinkParams extracts the rel and hreflang parameters of a link of an HTTP Link header
+// (e.g. `; rel="alternate"; hreflang="en",`).
+func parseLinkParams(params string) (rels []string, hreflang string) {
+	for _, param := range strings.Split(params, ";") {
+		param = strings.Trim(strings.TrimSpace(param), ",")
+		i := strings.Index(param, "=")
+		if i < 0 {
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSpace(param[:i]))
+		value := strings.Trim(strings.TrimSpace(param[i+1:]), `"`)
+
+		switch key {
+		case "rel":
+			rels = strings.Fields(strings.ToLower(value))
+		case "hreflang":
+			hreflang = value
+		}
+	}
+	return
+}
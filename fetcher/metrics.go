@@ -0,0 +1,48 @@
+package fetcher
+
+import (
+	"crypto/x509"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/msandim/web-crawler/metrics"
+)
+
+// Metrics of the requests of the fetchers:
+var (
+	fetchesTotal = metrics.NewCounter("web_crawler_fetches_total",
+		"Number of pages fetched, by the status code of their response.", "code")
+	fetchedBytes = metrics.NewCounter("web_crawler_fetched_bytes_total",
+		"Number of bytes of the bodies of the pages fetched.")
+	fetchErrors = metrics.NewCounter("web_crawler_fetch_errors_total",
+		"Number of pages that failed to be fetched, by the class of the error (e.g. timeout, dns or not_html).", "class")
+	fetchDuration = metrics.NewHistogram("web_crawler_fetch_duration_seconds",
+		"Time taken to receive the response headers of the pages fetched.", metrics.DefBuckets)
+	rateLimiterWait = metrics.NewHistogram("web_crawler_rate_limiter_wait_seconds",
+		"Time waited for the rate limiter before sending a request.", metrics.DefBuckets)
+)
+
+// errorClass returns the class of the error of a request that got no response, for the fetch errors metric.
+func errorClass(err error) string {
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	var certErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.As(err, &certErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) || strings.Contains(err.Error(), "tls:"):
+		return "tls"
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return "connection"
+	case strings.Contains(err.Error(), "redirects"):
+		return "redirects"
+	}
+	return "request"
+}
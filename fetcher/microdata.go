@@ -0,0 +1,107 @@
+package fetcher
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/msandim/web-crawler/structured"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// extractMicrodata extracts the top-level microdata items (i.e. the ones that aren't the value of a property)
+// of the DOM of a page. URLs in the values of properties are resolved relative to a base URL (the one of the page).
+// The itemref attribute isn't supported.
+func extractMicrodata(doc *html.Node, base *url.URL) []*structured.Item {
+	items := []*structured.Item{}
+
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && hasNodeAttribute(node, "itemscope") && !hasNodeAttribute(node, "itemprop") {
+			items = append(items, parseItem(node, base))
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+	return items
+}
+
+// parseItem parses the item of an element with the itemscope attribute.
+func parseItem(node *html.Node, base *url.URL) *structured.Item {
+	item := &structured.Item{
+		ID:         nodeAttribute(node, "itemid"),
+		Properties: make(map[string][]interface{}),
+	}
+	if itemType := nodeAttribute(node, "itemtype"); itemType != "" {
+		item.Type = strings.Fields(itemType)
+	}
+
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+
+			if names := strings.Fields(nodeAttribute(child, "itemprop")); len(names) > 0 {
+				var value interface{}
+				if hasNodeAttribute(child, "itemscope") {
+					value = parseItem(child, base)
+				} else {
+					value = propertyValue(child, base)
+				}
+				for _, name := range names {
+					item.Properties[name] = append(item.Properties[name], value)
+				}
+			}
+
+			// The properties inside a nested item belong to it:
+			if !hasNodeAttribute(child, "itemscope") {
+				walk(child)
+			}
+		}
+	}
+	walk(node)
+	return item
+}
+
+// propertyValue returns the value of a property, which depends on the element it's in.
+func propertyValue(node *html.Node, base *url.URL) string {
+	switch node.DataAtom {
+	case atom.Meta:
+		return nodeAttribute(node, "content")
+	case atom.A, atom.Area, atom.Link:
+		return resolveURL(base, nodeAttribute(node, "href"))
+	case atom.Img, atom.Audio, atom.Video, atom.Source, atom.Iframe, atom.Embed, atom.Track:
+		return resolveURL(base, nodeAttribute(node, "src"))
+	case atom.Object:
+		return resolveURL(base, nodeAttribute(node, "data"))
+	case atom.Data, atom.Meter:
+		return nodeAttribute(node, "value")
+	case atom.Time:
+		if hasNodeAttribute(node, "datetime") {
+			return nodeAttribute(node, "datetime")
+		}
+	}
+	return nodeText(node)
+}
+
+// nodeText returns the text inside an element, with its words separated by a single space.
+func nodeText(node *html.Node) string {
+	words := []string{}
+
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			words = append(words, strings.Fields(node.Data)...)
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,336 @@
+package fetcher
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/msandim/web-crawler/simhash"
+	"github.com/msandim/web-crawler/structured"
+
+	"golang.org/x/net/html"
+)
+
+// pageParser extracts the contents of an HTML page (title, visible text, directives and links)
+// while its tokens are read.
+type pageParser struct {
+	page            *Page
+	parentURLParsed *url.URL
+	baseURL         *url.URL // against which the resources of the page are resolved (the URL it was redirected to, if any)
+	errorsFound     []error
+
+	// URLs found in this page: avoid duplicates:
+	urlsFoundMap   map[string]bool
+	assetsFoundMap map[string]bool
+
+	// Index of the link whose anchor text is being read (-1 when outside of an <a>):
+	anchorIndex int
+	inTitle     bool
+
+	// Index of the heading whose text is being read (-1 when outside of a heading):
+	headingIndex int
+
+	// Structured data of the page and the contents of the JSON-LD block being read, if any:
+	structuredData *structured.Data
+	inJSONLD       bool
+	jsonLD         string
+
+	// Words of the visible text and the element whose contents aren't visible being read, if any:
+	words        []string
+	invisibleTag string
+}
+
+// parseHTML extracts the title, visible text, directives to robots and links of an HTML page from its body.
+// The links are normalized like the URL of the page (parentURLParsed), while the assets are resolved against
+// the URL from which the page was served (baseURL), which differs from it if the page was redirected.
+func parseHTML(page *Page, body []byte, parentURLParsed *url.URL, baseURL *url.URL) []error {
+	parser := &pageParser{
+		page:            page,
+		parentURLParsed: parentURLParsed,
+		baseURL:         baseURL,
+		errorsFound:     []error{},
+		urlsFoundMap:    make(map[string]bool),
+		assetsFoundMap:  make(map[string]bool),
+		anchorIndex:     -1,
+		headingIndex:    -1,
+		structuredData:  &structured.Data{},
+		words:           []string{},
+	}
+
+	// The canonical URL and alternates given by the HTTP headers are also links to crawl:
+	if page.Canonical != "" {
+		parser.addLink(page.Canonical, LinkCanonical, "")
+	}
+	for _, alternate := range page.Alternates {
+		parser.addLink(alternate.URL, LinkAlternate, "")
+	}
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+
+	for {
+		tokenType := tokenizer.Next()
+
+		switch {
+		case tokenType == html.ErrorToken: // Reached the end of the document
+			parser.end(body)
+			return parser.errorsFound
+		case tokenType == html.TextToken:
+			parser.text(string(tokenizer.Text()))
+		case tokenType == html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			parser.endTag(string(name))
+		case tokenType == html.StartTagToken:
+			parser.startTag(tokenizer.Token())
+		case tokenType == html.SelfClosingTagToken: // e.g. <link ... />
+			token := tokenizer.Token()
+			parser.startTag(token)
+			parser.endTag(token.Data)
+		}
+	}
+}
+
+func (parser *pageParser) text(text string) {
+	page := parser.page
+
+	if parser.invisibleTag == "" {
+		parser.words = append(parser.words, strings.Fields(text)...)
+	}
+	if parser.anchorIndex >= 0 {
+		page.Links[parser.anchorIndex].AnchorText = appendText(page.Links[parser.anchorIndex].AnchorText, text)
+	}
+	if parser.inTitle {
+		page.Title = appendText(page.Title, text)
+	}
+	if parser.headingIndex >= 0 {
+		page.Headings[parser.headingIndex].Text = appendText(page.Headings[parser.headingIndex].Text, text)
+	}
+	if parser.inJSONLD {
+		parser.jsonLD += text
+	}
+}
+
+func (parser *pageParser) endTag(name string) {
+	switch name {
+	case "a":
+		parser.anchorIndex = -1
+	case "title":
+		parser.inTitle = false
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		parser.headingIndex = -1
+	case "script":
+		if parser.inJSONLD {
+			parser.structuredData.AddJSONLD(parser.jsonLD)
+			parser.inJSONLD, parser.jsonLD = false, ""
+		}
+	}
+
+	if name == parser.invisibleTag {
+		parser.invisibleTag = ""
+	}
+}
+
+func (parser *pageParser) startTag(token html.Token) {
+	page := parser.page
+
+	if invisibleTags[token.Data] && parser.invisibleTag == "" {
+		parser.invisibleTag = token.Data
+	}
+
+	switch token.Data {
+	case "meta":
+		parser.structuredData.AddMeta(getAttribute(token, "property"), getAttribute(token, "content"))
+		parser.structuredData.AddMeta(getAttribute(token, "name"), getAttribute(token, "content"))
+
+		switch strings.ToLower(getAttribute(token, "name")) {
+		case "robots":
+			page.Robots.parseRobotsDirectives(getAttribute(token, "content"))
+		case "description":
+			// The description is only read from its first occurrence:
+			if page.Description == "" {
+				page.Description = appendText("", getAttribute(token, "content"))
+			}
+		}
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		page.Headings = append(page.Headings, Heading{Level: int(token.Data[1] - '0')})
+		parser.headingIndex = len(page.Headings) - 1
+	case "img":
+		page.Images++
+		if hasAttribute(token, "alt") {
+			page.ImagesWithAlt++
+		}
+		parser.addAsset(getAttribute(token, "src"))
+		parser.addSrcset(getAttribute(token, "srcset"))
+	case "script":
+		if strings.ToLower(strings.TrimSpace(getAttribute(token, "type"))) == "application/ld+json" {
+			parser.inJSONLD = true
+		}
+		parser.addAsset(getAttribute(token, "src"))
+	case "iframe", "embed", "audio", "video", "track":
+		parser.addAsset(getAttribute(token, "src"))
+		parser.addAsset(getAttribute(token, "poster"))
+	case "source":
+		parser.addAsset(getAttribute(token, "src"))
+		parser.addSrcset(getAttribute(token, "srcset"))
+	case "object":
+		parser.addAsset(getAttribute(token, "data"))
+	case "title":
+		// The title is only read from its first occurrence:
+		if page.Title == "" {
+			parser.inTitle = true
+		}
+	case "link":
+		parser.linkTag(token)
+	case "a":
+		parser.anchorIndex = -1
+
+		// Extract the href value, if there is one:
+		childURL, ok := getHref(token)
+		if !ok {
+			parser.errorsFound = append(parser.errorsFound, errors.New("HTTPFetcher::fetch() - Warning: <a> detected but no href present"))
+			return
+		}
+
+		rel := getNofollowRels(getAttribute(token, "rel"))
+		if index, ok := parser.addLink(childURL, LinkAnchor, rel); ok {
+			parser.anchorIndex = index
+		}
+	}
+}
+
+// linkTag extracts the canonical URL and the alternate versions in other languages of the page from a <link>.
+func (parser *pageParser) linkTag(token html.Token) {
+	page := parser.page
+	href := getAttribute(token, "href")
+	rels := strings.Fields(strings.ToLower(getAttribute(token, "rel")))
+
+	for _, rel := range rels {
+		switch rel {
+		case "stylesheet", "icon", "apple-touch-icon", "preload", "modulepreload", "manifest":
+			parser.addAsset(href)
+		case "canonical":
+			if page.Canonical == "" {
+				page.Canonical = normalizeURL(href, parser.parentURLParsed)
+			}
+			parser.addLink(href, LinkCanonical, "")
+		case "alternate":
+			hreflang := getAttribute(token, "hreflang")
+			if hreflang == "" {
+				continue
+			}
+			page.Alternates = append(page.Alternates, Alternate{Lang: hreflang, URL: normalizeURL(href, parser.parentURLParsed)})
+			parser.addLink(href, LinkAlternate, "")
+		}
+	}
+}
+
+// addLink adds a link found in the page, if it's a valid URL that wasn't found before (or the page itself).
+// It returns the index of the link in the links of the page.
+func (parser *pageParser) addLink(childURL string, kind LinkKind, rel string) (int, bool) {
+	page := parser.page
+
+	link, external, err := normalizeLink(childURL, parser.parentURLParsed)
+	if err != nil {
+		parser.errorsFound = append(parser.errorsFound, errors.New("HTTPFetcher::fetch() - Warning: failed to parse the URL found: "+childURL))
+		return -1, false
+	}
+	if link == "" {
+		return -1, false
+	}
+
+	// Links to the page itself (e.g. a self-referencing canonical) aren't links to other pages:
+	if kind != LinkAnchor && link == page.URL {
+		return -1, false
+	}
+
+	// Only add to the map of found urls if we didn't add before:
+	if _, ok := parser.urlsFoundMap[link]; ok {
+		return -1, false
+	}
+
+	parser.urlsFoundMap[link] = true
+	page.Links = append(page.Links, Link{
+		URL:      link,
+		Kind:     kind,
+		Nofollow: rel != "",
+		Rel:      rel,
+		External: external,
+	})
+	return len(page.Links) - 1, true
+}
+
+// addAsset adds a resource loaded by the page (e.g. an image or a script), if it wasn't found before.
+func (parser *pageParser) addAsset(rawURL string) {
+	if strings.TrimSpace(rawURL) == "" {
+		return
+	}
+
+	asset := parser.resolve(rawURL)
+	if parser.assetsFoundMap[asset] {
+		return
+	}
+	parser.assetsFoundMap[asset] = true
+	parser.page.Assets = append(parser.page.Assets, asset)
+}
+
+// addSrcset adds the images of a srcset attribute (e.g. "a.png 1x, b.png 2x").
+func (parser *pageParser) addSrcset(srcset string) {
+	for _, candidate := range strings.Split(srcset, ",") {
+		if fields := strings.Fields(candidate); len(fields) > 0 {
+			parser.addAsset(fields[0])
+		}
+	}
+}
+
+// resolve returns the absolute form of an URL found in the page (without its fragment),
+// or the URL itself if it can't be parsed.
+func (parser *pageParser) resolve(rawURL string) string {
+	return resolveURL(parser.baseURL, rawURL)
+}
+
+func (parser *pageParser) end(body []byte) {
+	page := parser.page
+	page.Text = strings.Join(parser.words, " ")
+	page.WordCount = len(parser.words)
+
+	// Pages without text aren't fingerprinted, since they'd all be duplicates of each other:
+	if page.WordCount > 0 {
+		page.TextHash = textHash(page.Text)
+		page.SimHash = simhash.Compute(page.Text)
+	}
+
+	// Microdata items are nested, so they're extracted from the DOM (only parsed if there are items):
+	if bytes.Contains(body, []byte("itemscope")) {
+		doc, err := html.Parse(bytes.NewReader(body))
+		if err != nil {
+			parser.errorsFound = append(parser.errorsFound, errors.New("HTTPFetcher::fetch() - Warning: failed to parse the DOM of: "+page.URL))
+		} else {
+			parser.structuredData.Microdata = extractMicrodata(doc, parser.baseURL)
+		}
+	}
+
+	if !parser.structuredData.IsEmpty() {
+		page.StructuredData = parser.structuredData
+	}
+}
+
+// resolveURL returns the absolute form of an URL relative to a base URL (without its fragment),
+// or the URL itself if it can't be parsed.
+func resolveURL(base *url.URL, rawURL string) string {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return rawURL
+	}
+
+	resolved := base.ResolveReference(parsed)
+	resolved.Fragment = ""
+	return resolved.String()
+}
+
+// textHash returns the SHA-256 of the visible text of a page, hex encoded.
+func textHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
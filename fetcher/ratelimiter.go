@@ -1,5 +1,7 @@
 package fetcher
 
+import "time"
+
 // RateLimiter is a struct that controlls how many concurrent requests can
 // be executed in a given context, by calling the function Limit() and Free()
 // when the request starts and ends.
@@ -22,7 +24,9 @@ func NewRateLimiter(capacity int) *RateLimiter {
 // Limit limits the number of concurrent requests by 1 and blocks
 // if the number of concurrent requests reached a maximum.
 func (rater *RateLimiter) Limit() {
+	start := time.Now()
 	<-rater.semaphore
+	rateLimiterWait.Observe(time.Since(start).Seconds())
 }
 
 // Free increases the number of concurrent requests by 1
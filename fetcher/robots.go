@@ -0,0 +1,62 @@
+package fetcher
+
+import "strings"
+
+// Robots contains the directives given to robots by a page, in its <meta name="robots"> tags
+// or X-Robots-Tag headers.
+type Robots struct {
+	NoIndex  bool // the page shouldn't be indexed (reported)
+	NoFollow bool // the links of the page shouldn't be followed
+}
+
+// nofollowRels are the values of the rel attribute of a link that ask robots not to follow it.
+var nofollowRels = map[string]bool{"nofollow": true, "ugc": true, "sponsored": true}
+
+// valuedRobotsDirectives are the directives that have a value after a colon (e.g. "max-snippet: 20"),
+// which can't be confused with user agents.
+var valuedRobotsDirectives = map[string]bool{
+	"unavailable_after": true, "max-snippet": true, "max-image-preview": true, "max-video-preview": true,
+}
+
+// parseRobotsDirectives adds the directives of a comma separated list (e.g. "noindex, nofollow")
+// to the robots directives of a page.
+func (robots *Robots) parseRobotsDirectives(directives string) {
+	for _, directive := range strings.Split(directives, ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "noindex":
+			robots.NoIndex = true
+		case "nofollow":
+			robots.NoFollow = true
+		case "none":
+			robots.NoIndex = true
+			robots.NoFollow = true
+		}
+	}
+}
+
+// parseRobotsHeader adds the directives of an X-Robots-Tag header to the robots directives of a page.
+// Directives for a specific user agent (e.g. "googlebot: noindex") are ignored, unless they are for all of them ("*").
+func (robots *Robots) parseRobotsHeader(header string) {
+	if i := strings.Index(header, ":"); i >= 0 && !strings.Contains(header[:i], ",") {
+		userAgent := strings.ToLower(strings.TrimSpace(header[:i]))
+		if !valuedRobotsDirectives[userAgent] {
+			if userAgent != "*" {
+				return
+			}
+			header = header[i+1:]
+		}
+	}
+	robots.parseRobotsDirectives(header)
+}
+
+// getNofollowRels returns the values of the rel attribute of a link that ask robots not to follow it,
+// separated by spaces (e.g. "nofollow ugc").
+func getNofollowRels(rel string) string {
+	rels := []string{}
+	for _, value := range strings.Fields(strings.ToLower(rel)) {
+		if nofollowRels[value] {
+			rels = append(rels, value)
+		}
+	}
+	return strings.Join(rels, " ")
+}
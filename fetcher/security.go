@@ -0,0 +1,59 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Rules of the security checks:
+const (
+	SecurityHSTSMissing           = "hsts-missing"
+	SecurityCSPMissing            = "csp-missing"
+	SecurityContentTypeOptions    = "x-content-type-options-missing"
+	SecurityReferrerPolicyMissing = "referrer-policy-missing"
+	SecurityInsecureCookie        = "insecure-cookie"
+	SecurityMixedContent          = "mixed-content"
+)
+
+// SecurityFinding is a security problem found in an HTTPS page.
+type SecurityFinding struct {
+	Rule    string // identifier of the check that found the problem (e.g. "hsts-missing")
+	Message string
+}
+
+// checkSecurity checks the response of an HTTPS page for missing security headers (Strict-Transport-Security,
+// Content-Security-Policy, X-Content-Type-Options and Referrer-Policy), cookies set without the Secure attribute
+// and assets of the page loaded over HTTP (mixed content).
+func checkSecurity(page *Page, resp *http.Response) []SecurityFinding {
+	findings := []SecurityFinding{}
+	add := func(rule string, message string) {
+		findings = append(findings, SecurityFinding{Rule: rule, Message: message})
+	}
+
+	if resp.Header.Get("Strict-Transport-Security") == "" {
+		add(SecurityHSTSMissing, "the Strict-Transport-Security header is missing")
+	}
+	if resp.Header.Get("Content-Security-Policy") == "" {
+		add(SecurityCSPMissing, "the Content-Security-Policy header is missing")
+	}
+	if strings.ToLower(strings.TrimSpace(resp.Header.Get("X-Content-Type-Options"))) != "nosniff" {
+		add(SecurityContentTypeOptions, "the X-Content-Type-Options header isn't set to nosniff")
+	}
+	if resp.Header.Get("Referrer-Policy") == "" {
+		add(SecurityReferrerPolicyMissing, "the Referrer-Policy header is missing")
+	}
+
+	for _, cookie := range resp.Cookies() {
+		if !cookie.Secure {
+			add(SecurityInsecureCookie, "the cookie "+cookie.Name+" is set without the Secure attribute")
+		}
+	}
+
+	for _, asset := range page.Assets {
+		if assetURL, err := url.Parse(asset); err == nil && assetURL.Scheme == "http" {
+			add(SecurityMixedContent, "the asset "+asset+" is loaded over HTTP")
+		}
+	}
+	return findings
+}
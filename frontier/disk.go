@@ -0,0 +1,470 @@
+package frontier
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/msandim/web-crawler/seen"
+)
+
+// Parameters of the files of a Disk frontier:
+const (
+	indexFile          = "index.json"
+	seenDir            = "seen"  // subdirectory of the set of the URLs seen
+	seenBuffer         = 1 << 20 // number of URLs seen kept in memory before writing them to disk
+	segmentItems       = 100000  // default number of items from which a new segment is started
+	checkpointInterval = 1000    // number of items popped from which the index is saved
+)
+
+// Disk is a Frontier kept on disk, with a bounded memory, that survives restarts. The items of every
+// priority are appended to segment files (JSON Lines, with up to 100000 items each) and read in order,
+// deleting the segments read. An index with the segments of every priority, how much of the first one
+// was read and the items popped that weren't done yet is saved every 1000 items popped, when a segment
+// is started or deleted and when closed: when opened again, the items that weren't done are popped first,
+// and after a crash, the items popped since the index was saved last are popped again.
+//
+// The frontier also keeps the set of the URLs seen by the crawl (a seen.DiskSet), saved along with its index,
+// so that the URLs crawled before aren't pushed again when it's resumed. It is safe for concurrent use.
+type Disk struct {
+	dir          string
+	segmentItems int
+
+	mutex       sync.Mutex
+	queues      map[int]*diskQueue // by priority
+	requeued    []Item             // items that weren't done when the frontier was closed, popped first
+	inFlight    map[string]popped  // items popped that weren't done, by URL
+	pops        int                // items popped, to keep the order of the ones in flight
+	seen        *seen.DiskSet
+	nextSegment int
+	n           int
+	popped      int // items popped since the index was saved
+	closed      bool
+}
+
+// diskQueue are the segments of the items of a priority.
+type diskQueue struct {
+	Segments []string `json:"segments"` // the first one is being read and the last one written
+	Offset   int64    `json:"offset"`   // bytes of the first segment already read
+
+	n         int // items not read yet
+	tailItems int // items of the last segment
+
+	reader    *bufio.Reader
+	readFile  *os.File
+	writer    *bufio.Writer
+	writeFile *os.File
+	unflushed bool // items were written to the writer since it was flushed
+}
+
+// diskIndex is the index of a Disk frontier, saved to index.json.
+type diskIndex struct {
+	NextSegment int                   `json:"next_segment"`
+	Queues      map[string]*diskQueue `json:"queues"`
+	InFlight    []Item                `json:"in_flight,omitempty"` // items popped that weren't done
+}
+
+// popped is an item popped that wasn't done.
+type popped struct {
+	item  Item
+	order int
+}
+
+// errClosed is the error of the operations on a closed Disk frontier.
+func errClosed(method string) error {
+	return errors.New("Disk::" + method + "() - Error: the frontier is closed")
+}
+
+// OpenDisk opens the Disk frontier of a directory, with the items left when it was closed (or when its
+// index was saved last), or creates an empty one if the directory doesn't have a frontier.
+func OpenDisk(dir string) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	set, err := seen.OpenDiskSet(filepath.Join(dir, seenDir), seenBuffer)
+	if err != nil {
+		return nil, err
+	}
+	frontier := &Disk{dir: dir, segmentItems: segmentItems, queues: make(map[int]*diskQueue), inFlight: make(map[string]popped), seen: set}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, indexFile))
+	if os.IsNotExist(err) {
+		return frontier, nil
+	}
+	if err != nil {
+		set.Close()
+		return nil, err
+	}
+
+	index := diskIndex{}
+	if err := json.Unmarshal(data, &index); err != nil {
+		set.Close()
+		return nil, err
+	}
+	frontier.nextSegment = index.NextSegment
+	frontier.requeued = index.InFlight
+	frontier.n = len(index.InFlight)
+
+	for key, queue := range index.Queues {
+		priority, err := strconv.Atoi(key)
+		if err != nil {
+			set.Close()
+			return nil, errors.New("Disk::OpenDisk() - Error: invalid priority in the index: " + key)
+		}
+		if err := frontier.countItems(queue); err != nil {
+			set.Close()
+			return nil, err
+		}
+		frontier.queues[priority] = queue
+		frontier.n += queue.n
+	}
+	return frontier, nil
+}
+
+// Push adds an item to the frontier.
+func (frontier *Disk) Push(item Item) error {
+	if item.Priority < 0 {
+		return errors.New("Disk::Push() - Error: negative priority: " + strconv.Itoa(item.Priority))
+	}
+	line, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	frontier.mutex.Lock()
+	defer frontier.mutex.Unlock()
+
+	if frontier.closed {
+		return errClosed("Push")
+	}
+
+	queue, ok := frontier.queues[item.Priority]
+	if !ok {
+		queue = &diskQueue{}
+		frontier.queues[item.Priority] = queue
+	}
+
+	if queue.writer == nil || queue.tailItems >= frontier.segmentItems {
+		if err := frontier.startSegment(queue); err != nil {
+			return err
+		}
+	}
+
+	if _, err := queue.writer.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	queue.unflushed = true
+	queue.tailItems++
+	queue.n++
+	frontier.n++
+	return nil
+}
+
+// Pop removes the item with the lowest priority from the frontier, returning false if it's empty.
+// The item is kept in the index until it's done.
+func (frontier *Disk) Pop() (Item, bool, error) {
+	frontier.mutex.Lock()
+	defer frontier.mutex.Unlock()
+
+	if frontier.closed {
+		return Item{}, false, errClosed("Pop")
+	}
+	if frontier.n == 0 {
+		return Item{}, false, nil
+	}
+
+	if len(frontier.requeued) > 0 {
+		item := frontier.requeued[0]
+		frontier.requeued = frontier.requeued[1:]
+		frontier.addInFlight(item)
+		frontier.n--
+		return item, true, nil
+	}
+
+	priorities := []int{}
+	for priority, queue := range frontier.queues {
+		if queue.n > 0 {
+			priorities = append(priorities, priority)
+		}
+	}
+	sort.Ints(priorities)
+	queue := frontier.queues[priorities[0]]
+
+	item, err := frontier.read(queue)
+	if err != nil {
+		return Item{}, false, err
+	}
+	queue.n--
+	frontier.n--
+	frontier.addInFlight(item)
+
+	frontier.popped++
+	if frontier.popped >= checkpointInterval {
+		if err := frontier.saveIndex(); err != nil {
+			return item, true, err
+		}
+	}
+	return item, true, nil
+}
+
+// Done removes an item popped from the index of the frontier, as it was crawled.
+func (frontier *Disk) Done(item Item) error {
+	frontier.mutex.Lock()
+	defer frontier.mutex.Unlock()
+
+	if frontier.closed {
+		return errClosed("Done")
+	}
+	delete(frontier.inFlight, item.URL)
+	return nil
+}
+
+// addInFlight keeps an item popped in the index until it's done.
+func (frontier *Disk) addInFlight(item Item) {
+	frontier.pops++
+	frontier.inFlight[item.URL] = popped{item: item, order: frontier.pops}
+}
+
+// Len returns the number of items in the frontier.
+func (frontier *Disk) Len() int {
+	frontier.mutex.Lock()
+	defer frontier.mutex.Unlock()
+	return frontier.n
+}
+
+// Seen returns the set of the URLs seen by the crawl, kept by the frontier. The set is safe for concurrent
+// use, and every URL is in it once the frontier is closed, so that none is pushed.
+func (frontier *Disk) Seen() seen.Set {
+	return diskSeen{frontier: frontier}
+}
+
+// Close saves the index and the set of the URLs seen of the frontier, and closes their files.
+func (frontier *Disk) Close() error {
+	frontier.mutex.Lock()
+	defer frontier.mutex.Unlock()
+
+	if frontier.closed {
+		return nil
+	}
+	frontier.closed = true
+
+	err := frontier.seen.Close()
+	if indexErr := frontier.writeIndex(); err == nil {
+		err = indexErr
+	}
+	for _, queue := range frontier.queues {
+		if queue.readFile != nil {
+			queue.readFile.Close()
+		}
+		if queue.writeFile != nil {
+			queue.writeFile.Close()
+		}
+	}
+	frontier.queues = make(map[int]*diskQueue)
+	frontier.requeued = nil
+	frontier.inFlight = make(map[string]popped)
+	frontier.n = 0
+	return err
+}
+
+// diskSeen is the set of the URLs seen kept by a Disk frontier, guarded by its mutex.
+type diskSeen struct {
+	frontier *Disk
+}
+
+func (set diskSeen) Add(url string) bool {
+	set.frontier.mutex.Lock()
+	defer set.frontier.mutex.Unlock()
+	return !set.frontier.closed && set.frontier.seen.Add(url)
+}
+
+func (set diskSeen) Contains(url string) bool {
+	set.frontier.mutex.Lock()
+	defer set.frontier.mutex.Unlock()
+	return set.frontier.closed || set.frontier.seen.Contains(url)
+}
+
+func (set diskSeen) Len() int {
+	set.frontier.mutex.Lock()
+	defer set.frontier.mutex.Unlock()
+	return set.frontier.seen.Len()
+}
+
+// startSegment starts a new segment to which the items of a queue are written.
+func (frontier *Disk) startSegment(queue *diskQueue) error {
+	if err := flush(queue); err != nil {
+		return err
+	}
+	if queue.writeFile != nil {
+		queue.writeFile.Close()
+	}
+
+	frontier.nextSegment++
+	name := fmt.Sprintf("segment-%08d.jsonl", frontier.nextSegment)
+	file, err := os.OpenFile(filepath.Join(frontier.dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	queue.Segments = append(queue.Segments, name)
+	queue.writeFile, queue.writer = file, bufio.NewWriter(file)
+	queue.tailItems = 0
+
+	// The index always lists all the segments, so that none is lost:
+	return frontier.saveIndex()
+}
+
+// read reads the next item of a queue, deleting the segments read.
+func (frontier *Disk) read(queue *diskQueue) (Item, error) {
+	for {
+		// The items written to the last segment have to be in the file to be read:
+		if len(queue.Segments) == 1 {
+			if err := flush(queue); err != nil {
+				return Item{}, err
+			}
+		}
+
+		if queue.readFile == nil {
+			file, err := os.Open(filepath.Join(frontier.dir, queue.Segments[0]))
+			if err != nil {
+				return Item{}, err
+			}
+			if _, err := file.Seek(queue.Offset, io.SeekStart); err != nil {
+				file.Close()
+				return Item{}, err
+			}
+			queue.readFile, queue.reader = file, bufio.NewReader(file)
+		}
+
+		line, err := queue.reader.ReadBytes('\n')
+		if err == nil {
+			queue.Offset += int64(len(line))
+			item := Item{}
+			return item, json.Unmarshal(line, &item)
+		}
+		if err != io.EOF || len(queue.Segments) == 1 {
+			return Item{}, errors.New("Disk::Pop() - Error: failed to read the segment: " + queue.Segments[0])
+		}
+
+		// The first segment was read: move on to the next one, and delete it once it's not in the index:
+		queue.readFile.Close()
+		read := queue.Segments[0]
+		queue.Segments = queue.Segments[1:]
+		queue.Offset = 0
+		queue.readFile, queue.reader = nil, nil
+		if err := frontier.saveIndex(); err != nil {
+			return Item{}, err
+		}
+		if err := os.Remove(filepath.Join(frontier.dir, read)); err != nil {
+			return Item{}, err
+		}
+	}
+}
+
+// saveIndex saves the manifest of the set of the URLs seen of the frontier and its index.
+func (frontier *Disk) saveIndex() error {
+	if err := frontier.seen.Checkpoint(); err != nil {
+		return err
+	}
+	return frontier.writeIndex()
+}
+
+// writeIndex writes the index of the frontier (to a temporary file first, so that it's never left incomplete).
+func (frontier *Disk) writeIndex() error {
+	index := diskIndex{NextSegment: frontier.nextSegment, Queues: make(map[string]*diskQueue)}
+	for priority, queue := range frontier.queues {
+		if err := flush(queue); err != nil {
+			return err
+		}
+		index.Queues[strconv.Itoa(priority)] = queue
+	}
+
+	// The items that weren't done are popped again, in the order in which they were popped:
+	inFlight := make([]popped, 0, len(frontier.inFlight))
+	for _, item := range frontier.inFlight {
+		inFlight = append(inFlight, item)
+	}
+	sort.Slice(inFlight, func(i, j int) bool { return inFlight[i].order < inFlight[j].order })
+	for _, item := range inFlight {
+		index.InFlight = append(index.InFlight, item.item)
+	}
+	index.InFlight = append(index.InFlight, frontier.requeued...)
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(frontier.dir, indexFile)
+	if err := ioutil.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	frontier.popped = 0
+	return os.Rename(path+".tmp", path)
+}
+
+// countItems counts the items not read of a queue opened from the index, discarding the last item
+// of its last segment if it was left incomplete.
+func (frontier *Disk) countItems(queue *diskQueue) error {
+	for i, name := range queue.Segments {
+		path := filepath.Join(frontier.dir, name)
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+
+		offset := int64(0)
+		if i == 0 {
+			offset = queue.Offset
+		}
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			file.Close()
+			return err
+		}
+
+		items, complete := 0, offset
+		reader := bufio.NewReader(file)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				break
+			}
+			items++
+			complete += int64(len(line))
+		}
+		file.Close()
+
+		if err := os.Truncate(path, complete); err != nil {
+			return err
+		}
+		queue.n += items
+		queue.tailItems = items // only the last segment's are kept (a segment read partially may grow a bit larger)
+	}
+
+	// The last segment is appended to:
+	if len(queue.Segments) > 0 {
+		file, err := os.OpenFile(filepath.Join(frontier.dir, queue.Segments[len(queue.Segments)-1]), os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		queue.writeFile, queue.writer = file, bufio.NewWriter(file)
+	}
+	return nil
+}
+
+// flush writes the items buffered of a queue to its last segment.
+func flush(queue *diskQueue) error {
+	if queue.writer == nil || !queue.unflushed {
+		return nil
+	}
+	queue.unflushed = false
+	return queue.writer.Flush()
+}
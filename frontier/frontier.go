@@ -0,0 +1,99 @@
+// Package frontier implements the frontier of a crawl: the queue of the URLs waiting to be crawled,
+// ordered by priority. The frontier is kept in memory by default, or on disk for crawls whose pending
+// URLs don't fit in memory or that have to survive restarts.
+package frontier
+
+import "sort"
+
+// Item is an URL waiting to be crawled, along with how it's crawled.
+type Item struct {
+	URL      string `json:"url"`
+	Depth    int    `json:"depth"`
+	External bool   `json:"external,omitempty"` // the URL is from another domain, so it's only checked
+	Asset    bool   `json:"asset,omitempty"`    // the URL is a resource of a page, so it's only downloaded
+
+	// Items with a lower priority are popped first, and the ones with the same priority in the order
+	// in which they were pushed (e.g. the depth as priority crawls breadth first). It can't be negative.
+	Priority int `json:"priority"`
+}
+
+// Frontier is a queue of URLs waiting to be crawled, ordered by priority.
+type Frontier interface {
+	// Push adds an item to the frontier.
+	Push(item Item) error
+	// Pop removes the item with the lowest priority from the frontier, returning false if it's empty.
+	Pop() (Item, bool, error)
+	// Done tells the frontier that an item popped was crawled. A persistent frontier pops again the items
+	// that weren't done when it was closed.
+	Done(item Item) error
+	// Len returns the number of items in the frontier.
+	Len() int
+	// Close releases the resources of the frontier (persisting it, if it's persistent).
+	Close() error
+}
+
+// Memory is a Frontier kept in memory. It is not safe for concurrent use.
+type Memory struct {
+	queues     map[int][]Item // by priority
+	priorities []int          // priorities with items, in ascending order
+	n          int
+}
+
+// NewMemory returns an empty Frontier kept in memory.
+func NewMemory() *Memory {
+	return &Memory{queues: make(map[int][]Item)}
+}
+
+// Push adds an item to the frontier.
+func (frontier *Memory) Push(item Item) error {
+	queue, ok := frontier.queues[item.Priority]
+	if !ok || len(queue) == 0 {
+		i := sort.SearchInts(frontier.priorities, item.Priority)
+		if i == len(frontier.priorities) || frontier.priorities[i] != item.Priority {
+			frontier.priorities = append(frontier.priorities, 0)
+			copy(frontier.priorities[i+1:], frontier.priorities[i:])
+			frontier.priorities[i] = item.Priority
+		}
+	}
+
+	frontier.queues[item.Priority] = append(queue, item)
+	frontier.n++
+	return nil
+}
+
+// Pop removes the item with the lowest priority from the frontier, returning false if it's empty.
+func (frontier *Memory) Pop() (Item, bool, error) {
+	if frontier.n == 0 {
+		return Item{}, false, nil
+	}
+
+	priority := frontier.priorities[0]
+	queue := frontier.queues[priority]
+	item := queue[0]
+	queue[0] = Item{} // don't keep a reference to the URL popped
+	queue = queue[1:]
+
+	if len(queue) == 0 {
+		delete(frontier.queues, priority)
+		frontier.priorities = frontier.priorities[1:]
+	} else {
+		frontier.queues[priority] = queue
+	}
+	frontier.n--
+	return item, true, nil
+}
+
+// Done does nothing, as the frontier isn't persisted.
+func (frontier *Memory) Done(item Item) error {
+	return nil
+}
+
+// Len returns the number of items in the frontier.
+func (frontier *Memory) Len() int {
+	return frontier.n
+}
+
+// Close does nothing, as the frontier isn't persisted.
+func (frontier *Memory) Close() error {
+	return nil
+}
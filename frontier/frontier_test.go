@@ -0,0 +1,177 @@
+package frontier
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func testItem(i int, priority int) Item {
+	return Item{URL: "https://monzo.com/" + strconv.Itoa(i), Depth: priority, Priority: priority}
+}
+
+// testOrder pushes items with priorities 2, 1 and 0 and checks that they're popped by priority and in the order pushed.
+func testOrder(t *testing.T, name string, frontier Frontier) {
+	for i := 0; i < 300; i++ {
+		if err := frontier.Push(testItem(i, 2-i/100)); err != nil {
+			t.Fatalf("%s: Unexpected error: %v", name, err)
+		}
+	}
+	if frontier.Len() != 300 {
+		t.Errorf("%s: Length of the frontier was invalid. Expected: %d, Got: %d", name, 300, frontier.Len())
+	}
+	popAll(t, name, frontier, 300)
+}
+
+// popAll pops the n items pushed by testOrder from a frontier and checks that they're popped in order.
+func popAll(t *testing.T, name string, frontier Frontier, n int) {
+	for j := 0; j < n; j++ {
+		item, ok, err := frontier.Pop()
+		if err != nil || !ok {
+			t.Fatalf("%s: Failed to pop item %d: %v", name, j, err)
+		}
+		expected := testItem(200+j%100-j/100*100, j/100)
+		if item != expected {
+			t.Errorf("%s: Item popped was invalid. Expected: %v, Got: %v", name, expected, item)
+		}
+	}
+
+	if _, ok, _ := frontier.Pop(); ok {
+		t.Errorf("%s: Item popped from an empty frontier", name)
+	}
+	if frontier.Len() != 0 {
+		t.Errorf("%s: Length of the frontier was invalid. Expected: %d, Got: %d", name, 0, frontier.Len())
+	}
+}
+
+func TestFrontiers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "frontier")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	disk, err := OpenDisk(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	disk.segmentItems = 30
+
+	testOrder(t, "memory", NewMemory())
+	testOrder(t, "disk", disk)
+
+	// The segments read were deleted (only the last one of each priority is kept):
+	if segments, _ := filepath.Glob(filepath.Join(dir, "segment-*")); len(segments) != 3 {
+		t.Errorf("Number of segments was invalid. Expected: %d, Got: %d", 3, len(segments))
+	}
+	if err := disk.Close(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestDisk_Reopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "frontier")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	disk, err := OpenDisk(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	disk.segmentItems = 30
+
+	for i := 0; i < 300; i++ {
+		disk.Push(testItem(i, 2-i/100))
+	}
+	for j := 0; j < 60; j++ {
+		item, _, _ := disk.Pop()
+		if j < 50 {
+			disk.Done(item)
+		}
+	}
+	disk.Seen().Add("https://monzo.com/")
+	if err := disk.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Nothing is pushed nor popped once the frontier is closed (so that its index isn't overwritten):
+	if err := disk.Push(testItem(300, 0)); err == nil {
+		t.Errorf("Item pushed to a closed frontier")
+	}
+	if _, _, err := disk.Pop(); err == nil {
+		t.Errorf("Item popped from a closed frontier")
+	}
+	if disk.Seen().Add("https://monzo.com/about") {
+		t.Errorf("URL added to the set of a closed frontier")
+	}
+
+	// The items left, with the ones popped that weren't done first, are popped after reopening the frontier,
+	// in the same order, and the URLs seen are kept:
+	disk, err = OpenDisk(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if disk.Len() != 250 {
+		t.Errorf("Length of the frontier was invalid. Expected: %d, Got: %d", 250, disk.Len())
+	}
+	item, _, err := disk.Pop()
+	if expected := testItem(250, 0); err != nil || item != expected {
+		t.Errorf("Item popped was invalid. Expected: %v, Got: %v (%v)", expected, item, err)
+	}
+	if !disk.Seen().Contains("https://monzo.com/") || disk.Seen().Contains("https://monzo.com/about") {
+		t.Errorf("URLs seen were not kept by the frontier")
+	}
+	disk.Close()
+}
+
+func TestDisk_IncompleteItem(t *testing.T) {
+	dir, err := ioutil.TempDir("", "frontier")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	disk, err := OpenDisk(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	disk.Push(testItem(1, 0))
+	disk.Push(testItem(2, 0))
+	disk.Close()
+
+	// An item left incomplete (e.g. the process was killed while writing it) is discarded:
+	segments, _ := filepath.Glob(filepath.Join(dir, "segment-*"))
+	file, _ := os.OpenFile(segments[0], os.O_WRONLY|os.O_APPEND, 0644)
+	file.WriteString(`{"url":"https://monzo.com/3","dep`)
+	file.Close()
+
+	disk, err = OpenDisk(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if disk.Len() != 2 {
+		t.Errorf("Length of the frontier was invalid. Expected: %d, Got: %d", 2, disk.Len())
+	}
+	disk.Push(testItem(4, 0))
+
+	urls := []string{}
+	for {
+		item, ok, err := disk.Pop()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !ok {
+			break
+		}
+		urls = append(urls, strings.TrimPrefix(item.URL, "https://monzo.com/"))
+	}
+	if strings.Join(urls, ",") != "1,2,4" {
+		t.Errorf("Items popped were invalid. Expected: %s, Got: %s", "1,2,4", strings.Join(urls, ","))
+	}
+	disk.Close()
+}
@@ -0,0 +1,87 @@
+package graph
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// WriteCSV writes the edge list and the node list of the graph to the files
+// "edges.csv" and "pages.csv" of a directory, creating it if needed.
+func (graph *Graph) WriteCSV(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	if err := writeCSVFile(filepath.Join(dir, "edges.csv"), graph.WriteEdgesCSV); err != nil {
+		return err
+	}
+	return writeCSVFile(filepath.Join(dir, "pages.csv"), graph.WritePagesCSV)
+}
+
+// WriteEdgesCSV writes the edges of the graph as CSV, with a header row.
+func (graph *Graph) WriteEdgesCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"source", "target", "link_type", "anchor_text", "nofollow"})
+
+	for _, edge := range graph.Edges {
+		writer.Write([]string{
+			edge.Source,
+			edge.Target,
+			edge.Kind,
+			edge.AnchorText,
+			strconv.FormatBool(edge.Nofollow),
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WritePagesCSV writes the nodes of the graph as CSV, with a header row.
+// The response time is written in milliseconds and the size in bytes.
+func (graph *Graph) WritePagesCSV(w io.Writer) error {
+	inlinks := make(map[string]int)
+	outlinks := make(map[string]int)
+	for _, edge := range graph.Edges {
+		inlinks[edge.Target]++
+		outlinks[edge.Source]++
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"url", "status", "depth", "inlinks", "outlinks", "response_time_ms", "size", "title", "noindex", "nofollow"})
+
+	for _, node := range graph.Nodes {
+		writer.Write([]string{
+			node.URL,
+			strconv.Itoa(node.StatusCode),
+			strconv.Itoa(node.Depth),
+			strconv.Itoa(inlinks[node.URL]),
+			strconv.Itoa(outlinks[node.URL]),
+			strconv.FormatInt(node.ResponseTime.Nanoseconds()/1e6, 10),
+			strconv.FormatInt(node.Size, 10),
+			node.Title,
+			strconv.FormatBool(node.NoIndex),
+			strconv.FormatBool(node.NoFollow),
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeCSVFile creates a file and writes to it with the given function.
+func writeCSVFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,217 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Diff contains the changes between two crawls of a domain.
+type Diff struct {
+	AddedPages     []string         `json:"added_pages"`
+	RemovedPages   []string         `json:"removed_pages"`
+	StatusChanges  []StatusChange   `json:"status_changes"`
+	NewBrokenLinks []Edge           `json:"new_broken_links"`
+	OutlinkChanges []OutlinkChange  `json:"outlink_changes"`
+	NewRedirects   []RedirectChange `json:"new_redirects"`
+}
+
+// StatusChange is a page whose status code changed between two crawls.
+type StatusChange struct {
+	URL       string `json:"url"`
+	OldStatus int    `json:"old_status"`
+	NewStatus int    `json:"new_status"`
+}
+
+// OutlinkChange is a page whose links to other pages changed between two crawls.
+type OutlinkChange struct {
+	URL     string   `json:"url"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+// RedirectChange is a page that redirects through a chain that it didn't before.
+type RedirectChange struct {
+	URL          string   `json:"url"`
+	OldRedirects []string `json:"old_redirects"`
+	NewRedirects []string `json:"new_redirects"`
+}
+
+// Compare returns the changes from a crawl of a domain (before) to a more recent one (after).
+// Pages are reported in the order they were crawled in the recent crawl (or the old one, for removed pages).
+func Compare(before *Graph, after *Graph) *Diff {
+	diff := &Diff{
+		AddedPages:     []string{},
+		RemovedPages:   []string{},
+		StatusChanges:  []StatusChange{},
+		NewBrokenLinks: []Edge{},
+		OutlinkChanges: []OutlinkChange{},
+		NewRedirects:   []RedirectChange{},
+	}
+
+	oldOutlinks := before.outlinks()
+	newOutlinks := after.outlinks()
+
+	for _, node := range after.Nodes {
+		oldNode, ok := before.Node(node.URL)
+		if !ok {
+			diff.AddedPages = append(diff.AddedPages, node.URL)
+			continue
+		}
+
+		if node.StatusCode != oldNode.StatusCode {
+			diff.StatusChanges = append(diff.StatusChanges, StatusChange{
+				URL:       node.URL,
+				OldStatus: oldNode.StatusCode,
+				NewStatus: node.StatusCode,
+			})
+		}
+
+		added, removed := compareLists(oldOutlinks[node.URL], newOutlinks[node.URL])
+		if len(added) > 0 || len(removed) > 0 {
+			diff.OutlinkChanges = append(diff.OutlinkChanges, OutlinkChange{URL: node.URL, Added: added, Removed: removed})
+		}
+
+		if len(node.Redirects) > 0 && !equalLists(node.Redirects, oldNode.Redirects) {
+			diff.NewRedirects = append(diff.NewRedirects, RedirectChange{
+				URL:          node.URL,
+				OldRedirects: oldNode.Redirects,
+				NewRedirects: node.Redirects,
+			})
+		}
+	}
+
+	// Pages that were added (and redirect) also have new redirect chains:
+	for _, u := range diff.AddedPages {
+		if node, _ := after.Node(u); len(node.Redirects) > 0 {
+			diff.NewRedirects = append(diff.NewRedirects, RedirectChange{URL: u, NewRedirects: node.Redirects})
+		}
+	}
+
+	for _, node := range before.Nodes {
+		if _, ok := after.Node(node.URL); !ok {
+			diff.RemovedPages = append(diff.RemovedPages, node.URL)
+		}
+	}
+
+	// A broken link is new if the same link (source and target) wasn't broken before:
+	oldBroken := make(map[[2]string]bool)
+	for _, edge := range before.Edges {
+		if target, ok := before.Node(edge.Target); ok && target.IsBroken() {
+			oldBroken[[2]string{edge.Source, edge.Target}] = true
+		}
+	}
+	for _, edge := range after.Edges {
+		if target, ok := after.Node(edge.Target); ok && target.IsBroken() && !oldBroken[[2]string{edge.Source, edge.Target}] {
+			diff.NewBrokenLinks = append(diff.NewBrokenLinks, edge)
+		}
+	}
+
+	return diff
+}
+
+// IsEmpty checks if there are no changes between the crawls.
+func (diff *Diff) IsEmpty() bool {
+	return len(diff.AddedPages) == 0 && len(diff.RemovedPages) == 0 && len(diff.StatusChanges) == 0 &&
+		len(diff.NewBrokenLinks) == 0 && len(diff.OutlinkChanges) == 0 && len(diff.NewRedirects) == 0
+}
+
+// WriteJSON writes the changes as JSON.
+func (diff *Diff) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(diff)
+}
+
+// WriteText writes the changes as human readable text, one section per kind of change.
+func (diff *Diff) WriteText(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Added pages (%d):\n", len(diff.AddedPages))
+	for _, u := range diff.AddedPages {
+		fmt.Fprintf(&b, "  + %s\n", u)
+	}
+
+	fmt.Fprintf(&b, "Removed pages (%d):\n", len(diff.RemovedPages))
+	for _, u := range diff.RemovedPages {
+		fmt.Fprintf(&b, "  - %s\n", u)
+	}
+
+	fmt.Fprintf(&b, "Status changes (%d):\n", len(diff.StatusChanges))
+	for _, change := range diff.StatusChanges {
+		fmt.Fprintf(&b, "  ~ %s %d -> %d\n", change.URL, change.OldStatus, change.NewStatus)
+	}
+
+	fmt.Fprintf(&b, "New broken links (%d):\n", len(diff.NewBrokenLinks))
+	for _, edge := range diff.NewBrokenLinks {
+		fmt.Fprintf(&b, "  x %s <- %s %q\n", edge.Target, edge.Source, edge.AnchorText)
+	}
+
+	fmt.Fprintf(&b, "Changed outlinks (%d):\n", len(diff.OutlinkChanges))
+	for _, change := range diff.OutlinkChanges {
+		fmt.Fprintf(&b, "  * %s\n", change.URL)
+		for _, u := range change.Added {
+			fmt.Fprintf(&b, "      + %s\n", u)
+		}
+		for _, u := range change.Removed {
+			fmt.Fprintf(&b, "      - %s\n", u)
+		}
+	}
+
+	fmt.Fprintf(&b, "New redirect chains (%d):\n", len(diff.NewRedirects))
+	for _, change := range diff.NewRedirects {
+		fmt.Fprintf(&b, "  > %s -> %s\n", change.URL, strings.Join(change.NewRedirects, " -> "))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// outlinks returns the targets of the links of every page, without duplicates.
+func (graph *Graph) outlinks() map[string][]string {
+	outlinks := make(map[string][]string)
+	seen := make(map[[2]string]bool)
+	for _, edge := range graph.Edges {
+		key := [2]string{edge.Source, edge.Target}
+		if !seen[key] {
+			seen[key] = true
+			outlinks[edge.Source] = append(outlinks[edge.Source], edge.Target)
+		}
+	}
+	return outlinks
+}
+
+// compareLists returns the elements that are only in the list after (added) and only in the list before (removed).
+func compareLists(before []string, after []string) (added []string, removed []string) {
+	inBefore := make(map[string]bool)
+	for _, s := range before {
+		inBefore[s] = true
+	}
+	inAfter := make(map[string]bool)
+	for _, s := range after {
+		inAfter[s] = true
+		if !inBefore[s] {
+			added = append(added, s)
+		}
+	}
+	for _, s := range before {
+		if !inAfter[s] {
+			removed = append(removed, s)
+		}
+	}
+	return
+}
+
+// equalLists checks if two lists have the same elements in the same order.
+func equalLists(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// WriteDOT writes the graph in the Graphviz DOT format. The status code, depth and content type of the pages
+// and the kind and anchor text of the links are in the tooltips of their nodes and edges.
+// If clusterDepth is greater than 0, nodes are grouped in clusters according to the
+// first clusterDepth segments of their path (e.g. with 1, "/blog/a" and "/blog/b" are
+// placed together in the "/blog" cluster).
+func (graph *Graph) WriteDOT(w io.Writer, clusterDepth int) error {
+	ids, urls := graph.nodeIDs()
+	buf := bufio.NewWriter(w)
+
+	fmt.Fprintln(buf, "digraph sitemap {")
+	fmt.Fprintln(buf, "  node [shape=box];")
+
+	if clusterDepth > 0 {
+		// Group the URLs by their path prefix, keeping the clusters sorted by prefix:
+		clusters := make(map[string][]string)
+		prefixes := []string{}
+		for _, u := range urls {
+			prefix := pathPrefix(u, clusterDepth)
+			if _, ok := clusters[prefix]; !ok {
+				prefixes = append(prefixes, prefix)
+			}
+			clusters[prefix] = append(clusters[prefix], u)
+		}
+		sort.Strings(prefixes)
+
+		for i, prefix := range prefixes {
+			fmt.Fprintf(buf, "  subgraph cluster_%d {\n", i)
+			fmt.Fprintf(buf, "    label=%s;\n", dotQuote(prefix))
+			for _, u := range clusters[prefix] {
+				graph.writeDOTNode(buf, "    ", ids[u], u)
+			}
+			fmt.Fprintln(buf, "  }")
+		}
+	} else {
+		for _, u := range urls {
+			graph.writeDOTNode(buf, "  ", ids[u], u)
+		}
+	}
+
+	for _, edge := range graph.Edges {
+		tooltip := edge.Kind
+		if edge.AnchorText != "" {
+			tooltip += ": " + edge.AnchorText
+		}
+		fmt.Fprintf(buf, "  %s -> %s [tooltip=%s];\n", ids[edge.Source], ids[edge.Target], dotQuote(tooltip))
+	}
+
+	fmt.Fprintln(buf, "}")
+	return buf.Flush()
+}
+
+// writeDOTNode writes a single node statement, with its attributes if the node was crawled.
+func (graph *Graph) writeDOTNode(w io.Writer, indent string, id string, u string) {
+	node, ok := graph.Node(u)
+	if !ok {
+		fmt.Fprintf(w, "%s%s [label=%s, style=dashed];\n", indent, id, dotQuote(u))
+		return
+	}
+
+	color := "black"
+	if node.StatusCode != 200 {
+		color = "red"
+	}
+
+	tooltip := fmt.Sprintf("status %d, depth %d, %s", node.StatusCode, node.Depth, node.ContentType)
+	fmt.Fprintf(w, "%s%s [label=%s, tooltip=%s, color=%s];\n", indent, id, dotQuote(u), dotQuote(tooltip), color)
+}
+
+// pathPrefix returns the first "depth" segments of the path of an URL (e.g. "/blog").
+func pathPrefix(u string, depth int) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "/"
+	}
+
+	segments := []string{}
+	for _, segment := range strings.Split(parsed.Path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	// The last segment is the page itself, not a directory:
+	if len(segments) > 0 && !strings.HasSuffix(parsed.Path, "/") {
+		segments = segments[:len(segments)-1]
+	}
+
+	if len(segments) > depth {
+		segments = segments[:depth]
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// dotQuote returns a DOT quoted string.
+func dotQuote(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+	return `"` + replacer.Replace(s) + `"`
+}
@@ -0,0 +1,104 @@
+package graph
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+type gexfDocument struct {
+	XMLName xml.Name  `xml:"gexf"`
+	XMLNS   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+type gexfGraph struct {
+	Mode            string           `xml:"mode,attr"`
+	DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+	Attributes      []gexfAttributes `xml:"attributes"`
+	Nodes           []gexfNode       `xml:"nodes>node"`
+	Edges           []gexfEdge       `xml:"edges>edge"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfNode struct {
+	ID        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue,omitempty"`
+}
+
+type gexfEdge struct {
+	ID        string         `xml:"id,attr"`
+	Source    string         `xml:"source,attr"`
+	Target    string         `xml:"target,attr"`
+	Label     string         `xml:"label,attr,omitempty"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// WriteGEXF writes the graph in the GEXF 1.3 format (e.g. to be opened in Gephi).
+func (graph *Graph) WriteGEXF(w io.Writer) error {
+	ids, urls := graph.nodeIDs()
+
+	doc := gexfDocument{
+		XMLNS:   "http://gexf.net/1.3",
+		Version: "1.3",
+		Graph: gexfGraph{
+			Mode:            "static",
+			DefaultEdgeType: "directed",
+			Attributes: []gexfAttributes{
+				{Class: "node", Attributes: []gexfAttribute{
+					{ID: "status", Title: "status", Type: "integer"},
+					{ID: "depth", Title: "depth", Type: "integer"},
+					{ID: "content_type", Title: "content_type", Type: "string"},
+				}},
+				{Class: "edge", Attributes: []gexfAttribute{
+					{ID: "kind", Title: "kind", Type: "string"},
+					{ID: "anchor", Title: "anchor", Type: "string"},
+				}},
+			},
+		},
+	}
+
+	for _, u := range urls {
+		node := gexfNode{ID: ids[u], Label: u}
+		if n, ok := graph.Node(u); ok {
+			node.AttValues = []gexfAttValue{
+				{For: "status", Value: strconv.Itoa(n.StatusCode)},
+				{For: "depth", Value: strconv.Itoa(n.Depth)},
+				{For: "content_type", Value: n.ContentType},
+			}
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node)
+	}
+
+	for i, edge := range graph.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, gexfEdge{
+			ID:     "e" + strconv.Itoa(i),
+			Source: ids[edge.Source],
+			Target: ids[edge.Target],
+			Label:  edge.AnchorText,
+			AttValues: []gexfAttValue{
+				{For: "kind", Value: edge.Kind},
+				{For: "anchor", Value: edge.AnchorText},
+			},
+		})
+	}
+
+	return writeXML(w, doc)
+}
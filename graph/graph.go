@@ -0,0 +1,192 @@
+package graph
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/msandim/web-crawler/structured"
+)
+
+// Node is a page of the crawled domain, along with the metadata obtained when crawling it.
+type Node struct {
+	URL          string        `json:"url"`
+	StatusCode   int           `json:"status"`
+	Depth        int           `json:"depth"`
+	ContentType  string        `json:"content_type,omitempty"`
+	ResponseTime time.Duration `json:"response_time"`
+	Size         int64         `json:"size"`
+	Title        string        `json:"title,omitempty"`
+	Redirects    []string      `json:"redirects,omitempty"`
+	DuplicateOf  string        `json:"duplicate_of,omitempty"` // first page crawled with the same content
+	NoIndex      bool          `json:"noindex,omitempty"`      // the page asks robots not to index it
+	NoFollow     bool          `json:"nofollow,omitempty"`     // the page asks robots not to follow its links
+	Canonical    string        `json:"canonical,omitempty"`    // canonical URL declared by the page
+	Alternates   []Alternate   `json:"alternates,omitempty"`   // versions of the page in other languages (hreflang)
+
+	// On-page SEO metadata of HTML pages:
+	Description   string    `json:"description,omitempty"`
+	Headings      []Heading `json:"headings,omitempty"` // outline of the page
+	WordCount     int       `json:"word_count,omitempty"`
+	Images        int       `json:"images,omitempty"`
+	ImagesWithAlt int       `json:"images_with_alt,omitempty"`
+
+	StructuredData *structured.Data `json:"structured_data,omitempty"` // JSON-LD, microdata and OpenGraph/Twitter cards
+}
+
+// Heading is a heading (<h1> to <h6>) of a page.
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+}
+
+// H1s returns the text of the top-level headings (<h1>) of the page.
+func (node *Node) H1s() []string {
+	h1s := []string{}
+	for _, heading := range node.Headings {
+		if heading.Level == 1 {
+			h1s = append(h1s, heading.Text)
+		}
+	}
+	return h1s
+}
+
+// Alternate is a version of a page in another language.
+type Alternate struct {
+	Lang string `json:"lang"`
+	URL  string `json:"url"`
+}
+
+// IsBroken checks if the node corresponds to a page that couldn't be fetched successfully.
+func (node *Node) IsBroken() bool {
+	return node.StatusCode == 0 || node.StatusCode >= 400
+}
+
+// Edge is a link from a page (Source) to another (Target).
+type Edge struct {
+	Source     string `json:"source"`
+	Target     string `json:"target"`
+	Kind       string `json:"kind"`
+	AnchorText string `json:"anchor_text,omitempty"`
+	Nofollow   bool   `json:"nofollow,omitempty"`
+}
+
+// Graph is the link graph of a crawled domain: its pages and the links between them.
+type Graph struct {
+	Nodes []*Node
+	Edges []Edge
+	index map[string]int
+}
+
+// New returns an empty Graph.
+func New() *Graph {
+	return &Graph{
+		Nodes: []*Node{},
+		Edges: []Edge{},
+		index: make(map[string]int),
+	}
+}
+
+// AddNode adds a node to the graph. If a node with the same URL was already added, it is replaced.
+func (graph *Graph) AddNode(node *Node) {
+	if i, ok := graph.index[node.URL]; ok {
+		graph.Nodes[i] = node
+		return
+	}
+	graph.index[node.URL] = len(graph.Nodes)
+	graph.Nodes = append(graph.Nodes, node)
+}
+
+// AddEdge adds an edge to the graph.
+func (graph *Graph) AddEdge(edge Edge) {
+	graph.Edges = append(graph.Edges, edge)
+}
+
+// RemoveNodes removes nodes from the graph, along with the edges from and to them.
+func (graph *Graph) RemoveNodes(urls []string) {
+	removed := make(map[string]bool)
+	for _, url := range urls {
+		removed[url] = true
+	}
+
+	nodes := []*Node{}
+	graph.index = make(map[string]int)
+	for _, node := range graph.Nodes {
+		if !removed[node.URL] {
+			graph.index[node.URL] = len(nodes)
+			nodes = append(nodes, node)
+		}
+	}
+	graph.Nodes = nodes
+
+	edges := []Edge{}
+	for _, edge := range graph.Edges {
+		if !removed[edge.Source] && !removed[edge.Target] {
+			edges = append(edges, edge)
+		}
+	}
+	graph.Edges = edges
+}
+
+// MergeAliases merges nodes into other nodes of the graph (e.g. pages into their canonical page), given a map
+// from the URL of each node merged to the URL of the node it is merged into. The merged nodes are removed and
+// the edges from and to them are moved to the nodes they were merged into (dropping the ones that become
+// duplicates or links of a page to itself).
+func (graph *Graph) MergeAliases(aliases map[string]string) {
+	resolve := func(url string) string {
+		if target, ok := aliases[url]; ok {
+			return target
+		}
+		return url
+	}
+
+	edges := []Edge{}
+	found := make(map[Edge]bool)
+	for _, edge := range graph.Edges {
+		edge.Source = resolve(edge.Source)
+		edge.Target = resolve(edge.Target)
+		if edge.Source == edge.Target || found[edge] {
+			continue
+		}
+		found[edge] = true
+		edges = append(edges, edge)
+	}
+
+	urls := []string{}
+	for url := range aliases {
+		urls = append(urls, url)
+	}
+	graph.Edges = nil
+	graph.RemoveNodes(urls)
+	graph.Edges = edges
+}
+
+// Node returns the node with the given URL, if there is one.
+func (graph *Graph) Node(url string) (*Node, bool) {
+	i, ok := graph.index[url]
+	if !ok {
+		return nil, false
+	}
+	return graph.Nodes[i], true
+}
+
+// nodeIDs assigns an identifier to every URL of the graph, including the targets
+// of edges that don't have a node (e.g. pages not crawled).
+// The URLs are returned in the order their identifiers were assigned.
+func (graph *Graph) nodeIDs() (ids map[string]string, urls []string) {
+	ids = make(map[string]string)
+	add := func(url string) {
+		if _, ok := ids[url]; !ok {
+			ids[url] = "n" + strconv.Itoa(len(urls))
+			urls = append(urls, url)
+		}
+	}
+
+	for _, node := range graph.Nodes {
+		add(node.URL)
+	}
+	for _, edge := range graph.Edges {
+		add(edge.Source)
+		add(edge.Target)
+	}
+	return
+}
@@ -0,0 +1,349 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/msandim/web-crawler/structured"
+)
+
+func testGraph() *Graph {
+	graph := New()
+	graph.AddNode(&Node{URL: "http://monzo.com/", StatusCode: 200, Depth: 0, ContentType: "text/html"})
+	graph.AddNode(&Node{URL: "http://monzo.com/blog/a", StatusCode: 200, Depth: 1, ContentType: "text/html"})
+	graph.AddNode(&Node{URL: "http://monzo.com/blog/b", StatusCode: 404, Depth: 1, ContentType: "text/plain"})
+	graph.AddEdge(Edge{Source: "http://monzo.com/", Target: "http://monzo.com/blog/a", Kind: "anchor", AnchorText: `The "A" post`})
+	graph.AddEdge(Edge{Source: "http://monzo.com/", Target: "http://monzo.com/blog/b", Kind: "anchor", AnchorText: "B & co"})
+	return graph
+}
+
+func TestGraph_AddNode(t *testing.T) {
+	graph := New()
+	graph.AddNode(&Node{URL: "A", StatusCode: 500})
+	graph.AddNode(&Node{URL: "A", StatusCode: 200})
+
+	if len(graph.Nodes) != 1 {
+		t.Errorf("Number of nodes was invalid. Expected: %d, Got: %d", 1, len(graph.Nodes))
+	}
+
+	node, ok := graph.Node("A")
+	if !ok || node.StatusCode != 200 {
+		t.Errorf("Node was not replaced")
+	}
+
+	if _, ok := graph.Node("B"); ok {
+		t.Errorf("Node B should not exist")
+	}
+}
+
+func TestGraph_RemoveNodes(t *testing.T) {
+	graph := testGraph()
+	graph.RemoveNodes([]string{"http://monzo.com/blog/a"})
+
+	if len(graph.Nodes) != 2 || len(graph.Edges) != 1 {
+		t.Fatalf("Invalid number of nodes/edges. Expected: 2/1, Got: %d/%d", len(graph.Nodes), len(graph.Edges))
+	}
+
+	if _, ok := graph.Node("http://monzo.com/blog/a"); ok {
+		t.Errorf("Removed node is still indexed")
+	}
+
+	if node, ok := graph.Node("http://monzo.com/blog/b"); !ok || node.StatusCode != 404 {
+		t.Errorf("Nodes were not reindexed")
+	}
+
+	if graph.Edges[0].Target != "http://monzo.com/blog/b" {
+		t.Errorf("Invalid remaining edge: %+v", graph.Edges[0])
+	}
+}
+
+func TestGraph_MergeAliases(t *testing.T) {
+	graph := testGraph()
+	graph.AddNode(&Node{URL: "http://monzo.com/blog/a?print=1", StatusCode: 200, Depth: 2})
+	graph.AddEdge(Edge{Source: "http://monzo.com/blog/a", Target: "http://monzo.com/blog/a?print=1", Kind: "anchor"})
+	graph.AddEdge(Edge{Source: "http://monzo.com/blog/a?print=1", Target: "http://monzo.com/blog/b", Kind: "anchor", AnchorText: "B & co"})
+	graph.AddEdge(Edge{Source: "http://monzo.com/blog/a?print=1", Target: "http://monzo.com/", Kind: "anchor"})
+
+	graph.MergeAliases(map[string]string{"http://monzo.com/blog/a?print=1": "http://monzo.com/blog/a"})
+
+	if _, ok := graph.Node("http://monzo.com/blog/a?print=1"); ok || len(graph.Nodes) != 3 {
+		t.Errorf("Merged node was not removed")
+	}
+
+	expected := []Edge{
+		{Source: "http://monzo.com/", Target: "http://monzo.com/blog/a", Kind: "anchor", AnchorText: `The "A" post`},
+		{Source: "http://monzo.com/", Target: "http://monzo.com/blog/b", Kind: "anchor", AnchorText: "B & co"},
+		{Source: "http://monzo.com/blog/a", Target: "http://monzo.com/blog/b", Kind: "anchor", AnchorText: "B & co"},
+		{Source: "http://monzo.com/blog/a", Target: "http://monzo.com/", Kind: "anchor"},
+	}
+	if !reflect.DeepEqual(graph.Edges, expected) {
+		t.Errorf("Edges were not merged. Expected: %v, Got: %v", expected, graph.Edges)
+	}
+}
+
+func TestGraph_WriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testGraph().WriteDOT(&buf, 0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out := buf.String()
+
+	expected := []string{
+		"digraph sitemap {",
+		`n0 [label="http://monzo.com/", tooltip="status 200, depth 0, text/html", color=black];`,
+		`n2 [label="http://monzo.com/blog/b", tooltip="status 404, depth 1, text/plain", color=red];`,
+		`n0 -> n1 [tooltip="anchor: The \"A\" post"];`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line) {
+			t.Errorf("DOT output does not contain: %s\nGot:\n%s", line, out)
+		}
+	}
+
+	if strings.Contains(out, "subgraph") {
+		t.Errorf("DOT output should not contain clusters:\n%s", out)
+	}
+}
+
+func TestGraph_WriteDOT_Clusters(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testGraph().WriteDOT(&buf, 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out := buf.String()
+
+	if strings.Count(out, "subgraph cluster_") != 2 {
+		t.Errorf("DOT output should contain 2 clusters:\n%s", out)
+	}
+
+	if !strings.Contains(out, `label="/blog";`) || !strings.Contains(out, `label="/";`) {
+		t.Errorf("DOT output does not contain the cluster labels:\n%s", out)
+	}
+}
+
+func TestGraph_WriteGraphML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testGraph().WriteGraphML(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var doc graphMLDocument
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("GraphML output is not valid XML: %v", err)
+	}
+
+	if len(doc.Graph.Nodes) != 3 || len(doc.Graph.Edges) != 2 {
+		t.Fatalf("Invalid number of nodes/edges. Expected: 3/2, Got: %d/%d", len(doc.Graph.Nodes), len(doc.Graph.Edges))
+	}
+
+	edge := doc.Graph.Edges[1]
+	if edge.Source != "n0" || edge.Target != "n2" {
+		t.Errorf("Invalid edge. Expected: n0 -> n2, Got: %s -> %s", edge.Source, edge.Target)
+	}
+	if edge.Data[1].Value != "B & co" {
+		t.Errorf("Invalid anchor text. Expected: %s, Got: %s", "B & co", edge.Data[1].Value)
+	}
+
+	node := doc.Graph.Nodes[2]
+	if node.Data[1].Key != "status" || node.Data[1].Value != "404" {
+		t.Errorf("Invalid status attribute: %+v", node.Data[1])
+	}
+}
+
+func TestGraph_WriteGEXF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testGraph().WriteGEXF(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var doc gexfDocument
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("GEXF output is not valid XML: %v", err)
+	}
+
+	if len(doc.Graph.Nodes) != 3 || len(doc.Graph.Edges) != 2 {
+		t.Fatalf("Invalid number of nodes/edges. Expected: 3/2, Got: %d/%d", len(doc.Graph.Nodes), len(doc.Graph.Edges))
+	}
+
+	if doc.Graph.Nodes[1].Label != "http://monzo.com/blog/a" {
+		t.Errorf("Invalid node label: %s", doc.Graph.Nodes[1].Label)
+	}
+
+	if doc.Graph.Nodes[1].AttValues[1].Value != "1" {
+		t.Errorf("Invalid depth attribute: %+v", doc.Graph.Nodes[1].AttValues[1])
+	}
+
+	if doc.Graph.Edges[0].Label != `The "A" post` {
+		t.Errorf("Invalid edge label: %s", doc.Graph.Edges[0].Label)
+	}
+}
+
+func TestGraph_WriteCSV(t *testing.T) {
+	graph := testGraph()
+	graph.Nodes[0].Title = "Monzo, the bank"
+	graph.Nodes[0].ResponseTime = 1500 * time.Millisecond
+	graph.Nodes[0].Size = 1024
+	graph.Edges[0].Nofollow = true
+	graph.Nodes[2].NoIndex = true
+
+	dir, err := ioutil.TempDir("", "graph")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := graph.WriteCSV(dir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	edges := readCSV(t, filepath.Join(dir, "edges.csv"))
+	if len(edges) != 3 {
+		t.Fatalf("Number of rows in edges.csv was invalid. Expected: %d, Got: %d", 3, len(edges))
+	}
+
+	expectedEdge := []string{"http://monzo.com/", "http://monzo.com/blog/a", "anchor", `The "A" post`, "true"}
+	if !reflect.DeepEqual(edges[1], expectedEdge) {
+		t.Errorf("Invalid edge row. Expected: %v, Got: %v", expectedEdge, edges[1])
+	}
+
+	pages := readCSV(t, filepath.Join(dir, "pages.csv"))
+	if len(pages) != 4 {
+		t.Fatalf("Number of rows in pages.csv was invalid. Expected: %d, Got: %d", 4, len(pages))
+	}
+
+	expectedPage := []string{"http://monzo.com/", "200", "0", "0", "2", "1500", "1024", "Monzo, the bank", "false", "false"}
+	if !reflect.DeepEqual(pages[1], expectedPage) {
+		t.Errorf("Invalid page row. Expected: %v, Got: %v", expectedPage, pages[1])
+	}
+
+	expectedPage = []string{"http://monzo.com/blog/b", "404", "1", "1", "0", "0", "0", "", "true", "false"}
+	if !reflect.DeepEqual(pages[3], expectedPage) {
+		t.Errorf("Invalid page row. Expected: %v, Got: %v", expectedPage, pages[3])
+	}
+}
+
+func readCSV(t *testing.T, path string) [][]string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return rows
+}
+
+func TestGraph_JSON(t *testing.T) {
+	graph := testGraph()
+	graph.Nodes[1].Redirects = []string{"http://monzo.com/blog/a/"}
+
+	var buf bytes.Buffer
+	if err := graph.WriteJSON(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	read, err := ReadJSON(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(read.Nodes, graph.Nodes) || !reflect.DeepEqual(read.Edges, graph.Edges) {
+		t.Errorf("Graph read is different from the one written.\nExpected: %+v\nGot: %+v", graph, read)
+	}
+
+	if node, ok := read.Node("http://monzo.com/blog/b"); !ok || node.StatusCode != 404 {
+		t.Errorf("Nodes of the graph read are not indexed")
+	}
+}
+
+func TestGraph_WriteStructuredData(t *testing.T) {
+	graph := testGraph()
+	graph.Nodes[1].StructuredData = &structured.Data{
+		JSONLD:    []json.RawMessage{json.RawMessage(`{"@type":"BlogPosting"}`)},
+		OpenGraph: map[string]string{"title": "A"},
+	}
+
+	var buf bytes.Buffer
+	if err := graph.WriteStructuredData(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `{"url":"http://monzo.com/blog/a","json_ld":[{"@type":"BlogPosting"}],"opengraph":{"title":"A"}}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("Invalid structured data.\nExpected: %s\nGot: %s", expected, buf.String())
+	}
+}
+
+func TestCompare(t *testing.T) {
+	before := testGraph()
+	before.AddNode(&Node{URL: "http://monzo.com/old", StatusCode: 200, Depth: 1})
+	before.AddEdge(Edge{Source: "http://monzo.com/", Target: "http://monzo.com/old"})
+
+	after := testGraph()
+	after.Nodes[1].StatusCode = 500
+	after.Nodes[0].Redirects = []string{"https://monzo.com/"}
+	after.AddNode(&Node{URL: "http://monzo.com/new", StatusCode: 200, Depth: 1})
+	after.AddEdge(Edge{Source: "http://monzo.com/", Target: "http://monzo.com/new"})
+
+	diff := Compare(before, after)
+
+	if !reflect.DeepEqual(diff.AddedPages, []string{"http://monzo.com/new"}) {
+		t.Errorf("Invalid added pages: %v", diff.AddedPages)
+	}
+
+	if !reflect.DeepEqual(diff.RemovedPages, []string{"http://monzo.com/old"}) {
+		t.Errorf("Invalid removed pages: %v", diff.RemovedPages)
+	}
+
+	expectedStatus := []StatusChange{{URL: "http://monzo.com/blog/a", OldStatus: 200, NewStatus: 500}}
+	if !reflect.DeepEqual(diff.StatusChanges, expectedStatus) {
+		t.Errorf("Invalid status changes: %+v", diff.StatusChanges)
+	}
+
+	// The link to /blog/b was already broken, only the one to /blog/a is new:
+	if len(diff.NewBrokenLinks) != 1 || diff.NewBrokenLinks[0].Target != "http://monzo.com/blog/a" {
+		t.Errorf("Invalid new broken links: %+v", diff.NewBrokenLinks)
+	}
+
+	expectedOutlinks := []OutlinkChange{{URL: "http://monzo.com/", Added: []string{"http://monzo.com/new"}, Removed: []string{"http://monzo.com/old"}}}
+	if !reflect.DeepEqual(diff.OutlinkChanges, expectedOutlinks) {
+		t.Errorf("Invalid outlink changes: %+v", diff.OutlinkChanges)
+	}
+
+	if len(diff.NewRedirects) != 1 || diff.NewRedirects[0].URL != "http://monzo.com/" {
+		t.Errorf("Invalid new redirects: %+v", diff.NewRedirects)
+	}
+
+	var buf bytes.Buffer
+	if err := diff.WriteText(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"Added pages (1):\n  + http://monzo.com/new\n",
+		"Status changes (1):\n  ~ http://monzo.com/blog/a 200 -> 500\n",
+		"  * http://monzo.com/\n      + http://monzo.com/new\n      - http://monzo.com/old\n",
+		"  > http://monzo.com/ -> https://monzo.com/\n",
+	}
+	for _, s := range expected {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("Text diff does not contain: %s\nGot:\n%s", s, buf.String())
+		}
+	}
+
+	if !Compare(before, before).IsEmpty() {
+		t.Errorf("Diff of a graph with itself should be empty")
+	}
+}
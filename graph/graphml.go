@@ -0,0 +1,104 @@
+package graph
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML writes the graph in the GraphML format (e.g. to be opened in yEd or Gephi).
+func (graph *Graph) WriteGraphML(w io.Writer) error {
+	ids, urls := graph.nodeIDs()
+
+	doc := graphMLDocument{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "url", For: "node", AttrName: "url", AttrType: "string"},
+			{ID: "status", For: "node", AttrName: "status", AttrType: "int"},
+			{ID: "depth", For: "node", AttrName: "depth", AttrType: "int"},
+			{ID: "content_type", For: "node", AttrName: "content_type", AttrType: "string"},
+			{ID: "kind", For: "edge", AttrName: "kind", AttrType: "string"},
+			{ID: "anchor", For: "edge", AttrName: "anchor", AttrType: "string"},
+		},
+		Graph: graphMLGraph{ID: "sitemap", EdgeDefault: "directed"},
+	}
+
+	for _, u := range urls {
+		node := graphMLNode{ID: ids[u], Data: []graphMLData{{Key: "url", Value: u}}}
+		if n, ok := graph.Node(u); ok {
+			node.Data = append(node.Data,
+				graphMLData{Key: "status", Value: strconv.Itoa(n.StatusCode)},
+				graphMLData{Key: "depth", Value: strconv.Itoa(n.Depth)},
+				graphMLData{Key: "content_type", Value: n.ContentType})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node)
+	}
+
+	for i, edge := range graph.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:     "e" + strconv.Itoa(i),
+			Source: ids[edge.Source],
+			Target: ids[edge.Target],
+			Data: []graphMLData{
+				{Key: "kind", Value: edge.Kind},
+				{Key: "anchor", Value: edge.AnchorText},
+			},
+		})
+	}
+
+	return writeXML(w, doc)
+}
+
+// writeXML writes an XML document with its header, indented.
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,36 @@
+package graph
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jsonGraph is the JSON representation of a Graph.
+type jsonGraph struct {
+	Nodes []*Node `json:"nodes"`
+	Edges []Edge  `json:"edges"`
+}
+
+// WriteJSON writes the graph as JSON, so that it can be read later with ReadJSON (e.g. to compare crawls).
+func (graph *Graph) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(jsonGraph{Nodes: graph.Nodes, Edges: graph.Edges})
+}
+
+// ReadJSON reads a graph written with WriteJSON.
+func ReadJSON(r io.Reader) (*Graph, error) {
+	var decoded jsonGraph
+	if err := json.NewDecoder(r).Decode(&decoded); err != nil {
+		return nil, err
+	}
+
+	graph := New()
+	for _, node := range decoded.Nodes {
+		graph.AddNode(node)
+	}
+	for _, edge := range decoded.Edges {
+		graph.AddEdge(edge)
+	}
+	return graph, nil
+}
@@ -0,0 +1,29 @@
+package graph
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/msandim/web-crawler/structured"
+)
+
+// structuredDataLine is a line of the structured data export: the URL of a page and its structured data.
+type structuredDataLine struct {
+	URL string `json:"url"`
+	*structured.Data
+}
+
+// WriteStructuredData writes the structured data (JSON-LD, microdata and OpenGraph/Twitter cards) of the pages
+// of the graph as JSON Lines, with a JSON object per page that has any.
+func (graph *Graph) WriteStructuredData(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	for _, node := range graph.Nodes {
+		if node.StructuredData == nil {
+			continue
+		}
+		if err := encoder.Encode(structuredDataLine{URL: node.URL, Data: node.StructuredData}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
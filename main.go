@@ -3,47 +3,559 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/msandim/web-crawler/audit"
 	"github.com/msandim/web-crawler/crawler"
+	"github.com/msandim/web-crawler/fetcher"
+	"github.com/msandim/web-crawler/frontier"
+	"github.com/msandim/web-crawler/graph"
+	"github.com/msandim/web-crawler/metrics"
+	"github.com/msandim/web-crawler/mirror"
+	"github.com/msandim/web-crawler/report"
+	"github.com/msandim/web-crawler/search"
+	"github.com/msandim/web-crawler/seen"
+	"github.com/msandim/web-crawler/traps"
+	"github.com/msandim/web-crawler/warc"
 )
 
-func parseArguments() (nWorkers int, rateLimit int, timeoutSeconds int, domain string) {
-	flag.IntVar(&nWorkers, "nworkers", 4, "the number of workers to crawl the domain")
-	flag.IntVar(&rateLimit, "ratelimit", 4, "the number of HTTP requests that can be done at the same time")
-	flag.IntVar(&timeoutSeconds, "timeoutseconds", 10, "The number of seconds to wait for a HTTP GET request")
-	flag.StringVar(&domain, "domain", "https://www.monzo.com", "the domain to crawl")
-	flag.Parse()
+// outputFiles contains the files to which the results of the crawl are exported (empty if not wanted).
+type outputFiles struct {
+	dot        string
+	dotCluster int
+	graphML    string
+	gexf       string
+	csvDir     string
+	report     string
+	json       string
+
+	structuredData string
+	index          string
+	warcDir        string
+	warcMaxSize    int
+	mirrorDir      string
+}
+
+// crawlArguments contains the arguments of the crawling process, shared by all the commands that crawl a domain.
+type crawlArguments struct {
+	nWorkers       int
+	rateLimit      int
+	timeoutSeconds int
+	domain         string
+	stateFile      string
+	recordFile     string
+	replayFile     string
+	siteDir        string
+
+	duplicateDistance int
+	skipDuplicates    bool
+	robotsPolicy      string
+	canonicalDedupe   bool
+	canonicalAudit    bool
+	seoAudit          bool
+	accessibility     bool
+	security          bool
+	traps             bool
+	trapBudget        int
+	seenSet           string
+	seenCapacity      int
+	seenFalsePositive float64
+	frontierDir       string
+	metricsAddr       string
+}
+
+// addCrawlFlags defines the flags of the crawling process in a flag set.
+func addCrawlFlags(flags *flag.FlagSet) *crawlArguments {
+	args := &crawlArguments{}
+	flags.IntVar(&args.nWorkers, "nworkers", 4, "the number of workers to crawl the domain")
+	flags.IntVar(&args.rateLimit, "ratelimit", 4, "the number of HTTP requests that can be done at the same time")
+	flags.IntVar(&args.timeoutSeconds, "timeoutseconds", 10, "The number of seconds to wait for a HTTP GET request")
+	flags.StringVar(&args.domain, "domain", "https://www.monzo.com", "the domain to crawl")
+	flags.StringVar(&args.recordFile, "record", "", "file to which every request made (and its outcome) is recorded, to be replayed later with -replay")
+	flags.StringVar(&args.replayFile, "replay", "", "file with the requests recorded with -record, served instead of making HTTP requests")
+	flags.StringVar(&args.siteDir, "sitedir", "", "directory with the build of a static site, whose files are read instead of making HTTP requests (the domain is its base URL)")
+	flags.StringVar(&args.stateFile, "statefile", "", "file with the state of the previous crawl, used to only download the pages that changed (updated at the end of the crawl)")
+	flags.IntVar(&args.duplicateDistance, "duplicatedistance", 3, "maximum number of bits in which the content fingerprints (SimHash) of two pages can differ for them to be duplicates")
+	flags.BoolVar(&args.skipDuplicates, "skipduplicates", false, "don't follow the links of pages that are duplicates of a page already crawled")
+	flags.StringVar(&args.robotsPolicy, "robots", "ignore", "what to do with the noindex and nofollow directives to robots: ignore, annotate (in the sitemap) or obey")
+	flags.BoolVar(&args.canonicalDedupe, "canonicaldedupe", false, "merge the pages that declare another canonical URL into the canonical page in the results")
+	flags.BoolVar(&args.canonicalAudit, "canonicals", false, "check the canonical URLs and hreflang alternates declared by the pages (canonical URLs that fail or are chains, alternates that don't link back)")
+	flags.BoolVar(&args.seoAudit, "seo", false, "check the on-page SEO metadata of the pages (titles, meta descriptions, headings and content)")
+	flags.BoolVar(&args.accessibility, "accessibility", false, "check the DOM of the pages for accessibility problems (images without alt text, inputs without labels, etc.)")
+	flags.BoolVar(&args.traps, "traps", false, "skip the URLs of crawler traps (repeating path segments, very deep paths, many URLs differing in one path segment and patterns over budget)")
+	flags.IntVar(&args.trapBudget, "trapbudget", traps.DefaultLimits.MaxPerPattern, "maximum number of URLs crawled with the same pattern (IDs, numbers and dates replaced), when skipping crawler traps")
+	flags.StringVar(&args.seenSet, "seenset", "map", "set of the URLs seen: map (exact), hash (compact set of hashes), bloom (Bloom filter) or disk (hashes kept on disk)")
+	flags.IntVar(&args.seenCapacity, "seencapacity", 10000000, "number of URLs for which the Bloom filter of the URLs seen is sized")
+	flags.Float64Var(&args.seenFalsePositive, "seenfalsepositive", 0.001, "false positive rate of the Bloom filter of the URLs seen (URLs skipped as if they were seen)")
+	flags.StringVar(&args.frontierDir, "frontierdir", "", "directory in which the URLs waiting to be crawled are kept (instead of memory), to resume the crawl from it if it's interrupted")
+	flags.BoolVar(&args.security, "security", false, "check the HTTPS pages for missing security headers, insecure cookies and assets loaded over HTTP (mixed content)")
+	flags.StringVar(&args.metricsAddr, "metrics-addr", "", "address on which the metrics of the crawl are served at /metrics, in the Prometheus text format (e.g. :9090)")
+	return args
+}
+
+// options returns the optional settings of the crawler given by the arguments.
+func (args *crawlArguments) options(cache *fetcher.Cache) crawler.Options {
+	var detector *traps.Detector
+	if args.traps {
+		limits := traps.DefaultLimits
+		limits.MaxPerPattern = args.trapBudget
+		detector = traps.New(limits)
+	}
+
+	pending := openFrontier(args.frontierDir)
+	return crawler.Options{
+		Cache:             cache,
+		DuplicateDistance: args.duplicateDistance,
+		SkipDuplicates:    args.skipDuplicates,
+		RobotsPolicy:      crawler.RobotsPolicies[args.robotsPolicy],
+		DedupeCanonical:   args.canonicalDedupe,
+		CanonicalAudit:    args.canonicalAudit,
+		SEOAudit:          args.seoAudit,
+		Accessibility:     args.accessibility,
+		Security:          args.security,
+		Traps:             detector,
+		Seen:              args.newSeenSet(pending),
+		Frontier:          pending,
+	}
+}
+
+// openFrontier opens the frontier on disk in a directory, if one is given (the crawler keeps it in memory otherwise).
+func openFrontier(dir string) frontier.Frontier {
+	if dir == "" {
+		return nil
+	}
+
+	disk, err := frontier.OpenDisk(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "main::openFrontier() - Error: failed to open the frontier: ", dir, err)
+		os.Exit(-1)
+	}
+	if disk.Len() > 0 {
+		fmt.Println("Resuming the crawl from the frontier with ", disk.Len(), " URLs: ", dir)
+	}
+
+	// The frontier is saved if the crawl is interrupted, to be resumed later:
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupted
+		closeFrontier(disk)
+		os.Exit(1)
+	}()
+	return disk
+}
+
+// closeFrontier saves the frontier, if it's kept on disk.
+func closeFrontier(pending frontier.Frontier) {
+	if pending == nil {
+		return
+	}
+
+	if err := pending.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "main::closeFrontier() - Error: failed to save the frontier: ", err)
+	}
+}
+
+// serveMetrics serves the metrics of the crawls at /metrics on an address, if one is given, while the program runs.
+func serveMetrics(addr string) {
+	if addr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Default)
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			fmt.Fprintln(os.Stderr, "main::serveMetrics() - Error: failed to serve the metrics: ", addr, err)
+		}
+	}()
+}
+
+// newSeenSet creates the set of the URLs seen given by the arguments, or returns the one kept by the frontier
+// on disk, so that it's resumed along with it.
+func (args *crawlArguments) newSeenSet(pending frontier.Frontier) seen.Set {
+	if disk, ok := pending.(*frontier.Disk); ok {
+		return disk.Seen()
+	}
+
+	switch args.seenSet {
+	case "hash":
+		return seen.NewHashSet()
+	case "bloom":
+		return seen.NewBloomFilter(args.seenCapacity, args.seenFalsePositive)
+	case "disk":
+		dir, err := ioutil.TempDir("", "web-crawler-seen")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "main::newSeenSet() - Error: failed to create the directory of the URLs seen: ", err)
+			os.Exit(-1)
+		}
+		set, err := seen.NewDiskSet(dir, diskSeenSetBuffer)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "main::newSeenSet() - Error: failed to create the set of the URLs seen: ", dir, err)
+			os.Exit(-1)
+		}
+		return set
+	default:
+		return seen.NewMap()
+	}
+}
+
+// diskSeenSetBuffer is the number of URLs seen kept in memory by the disk set before writing them to disk.
+const diskSeenSetBuffer = 1 << 20
+
+// closeSeenSet removes the files of the set of the URLs seen, if it's kept on disk.
+func closeSeenSet(set seen.Set) {
+	disk, ok := set.(*seen.DiskSet)
+	if !ok {
+		return
+	}
+
+	if err := disk.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "main::closeSeenSet() - Error: failed to use the set of the URLs seen on disk: ", err)
+	}
+	os.Remove(disk.Dir())
+}
+
+// loadState loads the state of the previous crawl from the state file, if there is one.
+func loadState(args *crawlArguments) *fetcher.Cache {
+	if args.stateFile == "" {
+		return nil
+	}
+
+	cache, err := fetcher.LoadCache(args.stateFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "main::loadState() - Error: failed to load the state file: ", args.stateFile, err)
+		os.Exit(-1)
+	}
+	return cache
+}
+
+// saveState saves the state of the crawl to the state file, if there is one.
+func saveState(args *crawlArguments, cache *fetcher.Cache) {
+	if cache == nil {
+		return
+	}
+
+	if err := cache.Save(args.stateFile); err != nil {
+		fmt.Fprintln(os.Stderr, "main::saveState() - Error: failed to save the state file: ", args.stateFile, err)
+	}
+}
+
+// loadFetcher returns the fetcher used instead of making HTTP requests, if any: the requests recorded in a
+// previous crawl or the files of a static site build.
+func loadFetcher(args *crawlArguments) fetcher.Fetcher {
+	if args.siteDir != "" {
+		fileFetcher, err := fetcher.NewFileFetcher(args.siteDir, args.domain)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "main::loadFetcher() - Error: failed to open the site directory: ", args.siteDir, err)
+			os.Exit(-1)
+		}
+		fileFetcher.SetAccessibilityCheck(args.accessibility)
+		return fileFetcher
+	}
+
+	if args.replayFile == "" {
+		return nil
+	}
+
+	cassette, err := fetcher.LoadCassette(args.replayFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "main::loadFetcher() - Error: failed to load the file to replay: ", args.replayFile, err)
+		os.Exit(-1)
+	}
+	return fetcher.NewReplayer(cassette)
+}
+
+// newRecording creates the recording of the requests of the crawl, if there is a file to record them to.
+func newRecording(args *crawlArguments) *fetcher.Cassette {
+	if args.recordFile == "" {
+		return nil
+	}
+	return fetcher.NewCassette()
+}
+
+// saveRecording saves the requests recorded to their file, if they're being recorded.
+func saveRecording(args *crawlArguments, cassette *fetcher.Cassette) {
+	if cassette == nil {
+		return
+	}
+
+	if err := cassette.Save(args.recordFile); err != nil {
+		fmt.Fprintln(os.Stderr, "main::saveRecording() - Error: failed to save the recorded requests: ", args.recordFile, err)
+	}
+}
+
+// createIndex creates the full-text index of the pages in a directory, if one is given.
+func createIndex(dir string) *search.Writer {
+	if dir == "" {
+		return nil
+	}
+
+	index, err := search.NewWriter(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "main::createIndex() - Error: failed to create the index: ", dir, err)
+		os.Exit(-1)
+	}
+	return index
+}
+
+// closeIndex finishes writing the full-text index of the pages, if there is one.
+func closeIndex(index *search.Writer) {
+	if index == nil {
+		return
+	}
 
-	if !isnWorkersValid(nWorkers) {
-		fmt.Fprintln(os.Stderr, "main::parseArguments() - Error: Number of workers is invalid: ", nWorkers)
+	if err := index.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "main::closeIndex() - Error: failed to write the index: ", err)
+	}
+}
+
+// createArchive creates the writer of the WARC files in which the requests are archived, if a directory is given.
+func createArchive(outputs outputFiles) *warc.Writer {
+	if outputs.warcDir == "" {
+		return nil
+	}
+
+	archive, err := warc.NewWriter(outputs.warcDir, "crawl-"+time.Now().UTC().Format("20060102150405"), int64(outputs.warcMaxSize)<<20)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "main::createArchive() - Error: failed to create the WARC directory: ", outputs.warcDir, err)
+		os.Exit(-1)
+	}
+	return archive
+}
+
+// createMirror creates the mirror of the domain in a directory, if one is given.
+func createMirror(dir string, domain string) *mirror.Mirror {
+	if dir == "" {
+		return nil
+	}
+
+	siteMirror, err := mirror.New(dir, domain)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "main::createMirror() - Error: failed to create the mirror directory: ", dir, err)
+		os.Exit(-1)
+	}
+	return siteMirror
+}
+
+// closeMirror rewrites the links of the pages saved to the mirror, if it's being written.
+func closeMirror(siteMirror *mirror.Mirror) {
+	if siteMirror == nil {
+		return
+	}
+
+	if err := siteMirror.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "main::closeMirror() - Error: failed to rewrite the links of the mirror: ", err)
+	}
+}
+
+// closeArchive finishes writing the WARC files and their CDX index, if they're being written.
+func closeArchive(archive *warc.Writer) {
+	if archive == nil {
+		return
+	}
+
+	if err := archive.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "main::closeArchive() - Error: failed to write the WARC files: ", err)
+	}
+}
+
+// validateCrawlArguments exits the program if any of the arguments of the crawling process is invalid.
+func validateCrawlArguments(args *crawlArguments) {
+	if !isnWorkersValid(args.nWorkers) {
+		fmt.Fprintln(os.Stderr, "main::validateCrawlArguments() - Error: Number of workers is invalid: ", args.nWorkers)
+		os.Exit(-1)
+	}
+
+	if !isRateLimitValid(args.rateLimit) {
+		fmt.Fprintln(os.Stderr, "main::validateCrawlArguments() - Error: Rate limit is invalid: ", args.rateLimit)
+		os.Exit(-1)
+	}
+
+	if !isTimeoutSecondsValid(args.timeoutSeconds) {
+		fmt.Fprintln(os.Stderr, "main::validateCrawlArguments() - Error: Timeout (seconds) is invalid: ", args.timeoutSeconds)
+		os.Exit(-1)
+	}
+
+	if !isDomainValid(args.domain) {
+		fmt.Fprintln(os.Stderr, "main::validateCrawlArguments() - Error: Domain is invalid: ", args.domain)
+		os.Exit(-1)
+	}
+
+	if args.trapBudget <= 0 {
+		fmt.Fprintln(os.Stderr, "main::validateCrawlArguments() - Error: Trap budget is invalid: ", args.trapBudget)
+		os.Exit(-1)
+	}
+
+	if args.siteDir != "" && args.replayFile != "" {
+		fmt.Fprintln(os.Stderr, "main::validateCrawlArguments() - Error: a site directory can't be crawled while replaying a recorded crawl")
+		os.Exit(-1)
+	}
+
+	if args.duplicateDistance < 0 || args.duplicateDistance > 64 {
+		fmt.Fprintln(os.Stderr, "main::validateCrawlArguments() - Error: Duplicate distance is invalid: ", args.duplicateDistance)
+		os.Exit(-1)
+	}
+
+	if args.seenSet != "map" && args.seenSet != "hash" && args.seenSet != "bloom" && args.seenSet != "disk" {
+		fmt.Fprintln(os.Stderr, "main::validateCrawlArguments() - Error: Set of the URLs seen is invalid: ", args.seenSet)
+		os.Exit(-1)
+	}
+
+	if args.seenSet != "map" && args.frontierDir != "" {
+		fmt.Fprintln(os.Stderr, "main::validateCrawlArguments() - Error: Set of the URLs seen can't be chosen with a frontier directory (it's kept in it): ", args.seenSet)
 		os.Exit(-1)
 	}
 
-	if !isRateLimitValid(rateLimit) {
-		fmt.Fprintln(os.Stderr, "main::parseArguments() - Error: Rate limit is invalid: ", rateLimit)
+	if args.seenCapacity <= 0 || args.seenFalsePositive <= 0 || args.seenFalsePositive >= 1 {
+		fmt.Fprintln(os.Stderr, "main::validateCrawlArguments() - Error: Bloom filter of the URLs seen is invalid: ", args.seenCapacity, args.seenFalsePositive)
 		os.Exit(-1)
 	}
 
-	if !isTimeoutSecondsValid(timeoutSeconds) {
-		fmt.Fprintln(os.Stderr, "main::parseArguments() - Error: Timeout (seconds) is invalid: ", rateLimit)
+	if _, ok := crawler.RobotsPolicies[args.robotsPolicy]; !ok {
+		fmt.Fprintln(os.Stderr, "main::validateCrawlArguments() - Error: Robots policy is invalid: ", args.robotsPolicy)
+		os.Exit(-1)
+	}
+}
+
+func parseArguments() (args *crawlArguments, outputs outputFiles) {
+	args = addCrawlFlags(flag.CommandLine)
+	flag.StringVar(&outputs.dot, "dot", "", "file to which the link graph is exported in the Graphviz DOT format")
+	flag.IntVar(&outputs.dotCluster, "dotcluster", 0, "number of path segments used to cluster the nodes of the DOT graph (0 disables clustering)")
+	flag.StringVar(&outputs.graphML, "graphml", "", "file to which the link graph is exported in the GraphML format")
+	flag.StringVar(&outputs.gexf, "gexf", "", "file to which the link graph is exported in the GEXF format")
+	flag.StringVar(&outputs.csvDir, "csvdir", "", "directory to which edges.csv and pages.csv are written")
+	flag.StringVar(&outputs.report, "report", "", "file to which a self-contained HTML report of the crawl is written")
+	flag.StringVar(&outputs.json, "json", "", "file to which the crawl is saved as JSON (e.g. to be compared with the diff command)")
+	flag.StringVar(&outputs.structuredData, "structureddata", "", "file to which the structured data of the pages (JSON-LD, microdata and OpenGraph/Twitter cards) is written as JSON Lines")
+	flag.StringVar(&outputs.index, "index", "", "directory in which a full-text index of the pages is built during the crawl (to be queried with the search command)")
+	flag.StringVar(&outputs.warcDir, "warcdir", "", "directory to which the HTTP requests and responses are archived in WARC files, with a CDX index")
+	flag.IntVar(&outputs.warcMaxSize, "warcmaxsize", 1024, "size in MB from which a new WARC file is started")
+	flag.StringVar(&outputs.mirrorDir, "mirror", "", "directory to which the pages and assets of the domain are saved, with their links rewritten to browse them offline")
+	flag.Parse()
+
+	validateCrawlArguments(args)
+
+	if outputs.warcMaxSize <= 0 {
+		fmt.Fprintln(os.Stderr, "main::parseArguments() - Error: WARC file size is invalid: ", outputs.warcMaxSize)
 		os.Exit(-1)
 	}
 
-	if !isDomainValid(domain) {
-		fmt.Fprintln(os.Stderr, "main::parseArguments() - Error: Domain is invalid: ", domain)
+	if outputs.dotCluster < 0 {
+		fmt.Fprintln(os.Stderr, "main::parseArguments() - Error: DOT cluster depth is invalid: ", outputs.dotCluster)
 		os.Exit(-1)
 	}
 	return
 }
 
 func main() {
-	nWorkers, rateLimit, timeoutSeconds, domain := parseArguments()
+	// Commands other than crawling the domain and printing its sitemap:
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "check-links":
+			os.Exit(runCheckLinks(os.Args[2:]))
+		case "diff":
+			os.Exit(runDiff(os.Args[2:]))
+		case "search":
+			os.Exit(runSearch(os.Args[2:]))
+		case "coordinator":
+			os.Exit(runCoordinator(os.Args[2:]))
+		case "worker":
+			os.Exit(runWorker(os.Args[2:]))
+		case "serve":
+			os.Exit(runServe(os.Args[2:]))
+		}
+	}
+
+	args, outputs := parseArguments()
+	serveMetrics(args.metricsAddr)
 
-	fmt.Println("nworkers: ", nWorkers, " ratelimit: ", rateLimit, " timeoutseconds: ", timeoutSeconds, " domain: ", domain)
-	crawler := crawler.New(nWorkers, rateLimit, timeoutSeconds, domain)
+	fmt.Println("nworkers: ", args.nWorkers, " ratelimit: ", args.rateLimit, " timeoutseconds: ", args.timeoutSeconds, " domain: ", args.domain)
+	cache := loadState(args)
+	options := args.options(cache)
+	options.Fetcher = loadFetcher(args)
+	options.Cassette = newRecording(args)
+	options.Index = createIndex(outputs.index)
+	if options.Index != nil && options.Cache != nil {
+		// Every page has to be downloaded to be indexed, since the state file doesn't keep the text of the pages:
+		options.Cache = fetcher.NewCache()
+	}
+	archive := createArchive(outputs)
+	if archive != nil {
+		options.Transport = warc.NewTransport(nil, archive, func(err error) {
+			fmt.Fprintln(os.Stderr, "main::main() - Error: failed to archive a request: ", err)
+		})
+	}
+	siteMirror := createMirror(outputs.mirrorDir, args.domain)
+	if siteMirror != nil {
+		// Every page has to be downloaded to be saved, even if it didn't change since the previous crawl:
+		if options.Cache != nil {
+			options.Cache = fetcher.NewCache()
+		}
+		options.Assets = true
+		options.Transport = mirror.NewTransport(options.Transport, siteMirror, func(err error) {
+			fmt.Fprintln(os.Stderr, "main::main() - Error: failed to mirror a resource: ", err)
+		})
+	}
+
+	crawler := crawler.NewWithOptions(args.nWorkers, args.rateLimit, args.timeoutSeconds, args.domain, options)
 	crawler.Run()
+	saveState(args, options.Cache)
+	saveRecording(args, options.Cassette)
+	closeIndex(options.Index)
+	closeArchive(archive)
+	closeMirror(siteMirror)
+	closeSeenSet(options.Seen)
+	closeFrontier(options.Frontier)
+
+	writeOutputs(crawler.Graph(), crawler.Issues(), outputs)
+}
+
+// writeOutputs exports the link graph (and the issues found in its pages) to the files requested.
+func writeOutputs(sitemap *graph.Graph, issues []audit.Issue, outputs outputFiles) {
+	if outputs.dot != "" {
+		writeFile(outputs.dot, func(f *os.File) error { return sitemap.WriteDOT(f, outputs.dotCluster) })
+	}
+	if outputs.graphML != "" {
+		writeFile(outputs.graphML, func(f *os.File) error { return sitemap.WriteGraphML(f) })
+	}
+	if outputs.gexf != "" {
+		writeFile(outputs.gexf, func(f *os.File) error { return sitemap.WriteGEXF(f) })
+	}
+	if outputs.csvDir != "" {
+		if err := sitemap.WriteCSV(outputs.csvDir); err != nil {
+			fmt.Fprintln(os.Stderr, "main::writeOutputs() - Error: failed to write the CSV files: ", err)
+		}
+	}
+	if outputs.report != "" {
+		writeFile(outputs.report, func(f *os.File) error { return report.Write(f, sitemap, issues) })
+	}
+	if outputs.json != "" {
+		writeFile(outputs.json, func(f *os.File) error { return sitemap.WriteJSON(f) })
+	}
+	if outputs.structuredData != "" {
+		writeFile(outputs.structuredData, func(f *os.File) error { return sitemap.WriteStructuredData(f) })
+	}
+}
+
+// writeFile creates a file and writes to it with the given function, reporting any error to stderr.
+func writeFile(path string, write func(f *os.File) error) {
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "main::writeFile() - Error: failed to create file: ", path)
+		return
+	}
+	defer f.Close()
+
+	if err := write(f); err != nil {
+		fmt.Fprintln(os.Stderr, "main::writeFile() - Error: failed to write file: ", path, err)
+	}
 }
 
 func isnWorkersValid(nWorkers int) bool {
@@ -0,0 +1,294 @@
+// Package metrics implements counters, gauges and histograms of the crawls, exposed in the Prometheus text
+// exposition format (https://prometheus.io/docs/instrumenting/exposition_formats/) to be scraped while they run.
+//
+// The metrics are defined by the packages they measure (e.g. the fetcher) in the Default registry, and
+// served by it at /metrics when the -metrics-addr flag is given.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are the default upper bounds of the buckets of histograms, in seconds (the ones of the Prometheus clients).
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Default is the registry in which the metrics of the crawls are defined.
+var Default = NewRegistry()
+
+// Registry is a set of metrics, exposed together. It is safe for concurrent use.
+type Registry struct {
+	mutex    sync.Mutex
+	families map[string]*family // by name
+}
+
+// NewRegistry creates a registry without metrics.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// family is a metric, with a series of values for every combination of the values of its labels.
+type family struct {
+	name    string
+	help    string
+	kind    string // counter, gauge or histogram
+	labels  []string
+	buckets []float64 // upper bounds of the buckets of histograms
+
+	mutex  sync.Mutex
+	series map[string]*series // by the values of the labels, joined
+}
+
+// series are the values of a metric for some values of its labels.
+type series struct {
+	labelValues []string
+	value       float64  // of counters and gauges, or the sum of the values observed by histograms
+	counts      []uint64 // number of values observed by histograms in every bucket (not cumulative)
+	count       uint64   // number of values observed by histograms
+}
+
+// Counter is a metric whose value only goes up (e.g. the number of pages fetched).
+type Counter struct{ family *family }
+
+// Gauge is a metric whose value goes up and down (e.g. the number of workers active).
+type Gauge struct{ family *family }
+
+// Histogram is a metric that counts the values observed (e.g. latencies) in buckets.
+type Histogram struct{ family *family }
+
+// NewCounter defines a counter with some labels in the registry.
+func (registry *Registry) NewCounter(name string, help string, labels ...string) *Counter {
+	return &Counter{registry.register(&family{name: name, help: help, kind: "counter", labels: labels})}
+}
+
+// NewGauge defines a gauge with some labels in the registry.
+func (registry *Registry) NewGauge(name string, help string, labels ...string) *Gauge {
+	return &Gauge{registry.register(&family{name: name, help: help, kind: "gauge", labels: labels})}
+}
+
+// NewHistogram defines a histogram with some buckets (their upper bounds, in ascending order) and labels in the registry.
+func (registry *Registry) NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{registry.register(&family{name: name, help: help, kind: "histogram", labels: labels, buckets: buckets})}
+}
+
+// NewCounter defines a counter with some labels in the Default registry.
+func NewCounter(name string, help string, labels ...string) *Counter {
+	return Default.NewCounter(name, help, labels...)
+}
+
+// NewGauge defines a gauge with some labels in the Default registry.
+func NewGauge(name string, help string, labels ...string) *Gauge {
+	return Default.NewGauge(name, help, labels...)
+}
+
+// NewHistogram defines a histogram with some buckets and labels in the Default registry.
+func NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	return Default.NewHistogram(name, help, buckets, labels...)
+}
+
+// register adds a metric to the registry. Defining two metrics with the same name is a programming error.
+func (registry *Registry) register(metric *family) *family {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if _, ok := registry.families[metric.name]; ok {
+		panic("Registry::register() - Error: metric defined twice: " + metric.name)
+	}
+	metric.series = make(map[string]*series)
+	registry.families[metric.name] = metric
+	return metric
+}
+
+// Inc adds 1 to the counter, for some values of its labels.
+func (counter *Counter) Inc(labelValues ...string) {
+	counter.Add(1, labelValues...)
+}
+
+// Add adds a value, which can't be negative, to the counter, for some values of its labels.
+func (counter *Counter) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		panic("Counter::Add() - Error: counters can't decrease: " + counter.family.name)
+	}
+	counter.family.update(labelValues, func(s *series) { s.value += value })
+}
+
+// Value returns the value of the counter, for some values of its labels.
+func (counter *Counter) Value(labelValues ...string) float64 {
+	return counter.family.value(labelValues)
+}
+
+// Set sets the value of the gauge, for some values of its labels.
+func (gauge *Gauge) Set(value float64, labelValues ...string) {
+	gauge.family.update(labelValues, func(s *series) { s.value = value })
+}
+
+// Add adds a value (possibly negative) to the gauge, for some values of its labels.
+func (gauge *Gauge) Add(value float64, labelValues ...string) {
+	gauge.family.update(labelValues, func(s *series) { s.value += value })
+}
+
+// Inc adds 1 to the gauge, for some values of its labels.
+func (gauge *Gauge) Inc(labelValues ...string) {
+	gauge.Add(1, labelValues...)
+}
+
+// Dec subtracts 1 from the gauge, for some values of its labels.
+func (gauge *Gauge) Dec(labelValues ...string) {
+	gauge.Add(-1, labelValues...)
+}
+
+// Value returns the value of the gauge, for some values of its labels.
+func (gauge *Gauge) Value(labelValues ...string) float64 {
+	return gauge.family.value(labelValues)
+}
+
+// Observe counts a value in the histogram, for some values of its labels.
+func (histogram *Histogram) Observe(value float64, labelValues ...string) {
+	buckets := histogram.family.buckets
+	i := sort.SearchFloat64s(buckets, value) // first bucket whose upper bound is >= value (len(buckets) for +Inf)
+
+	histogram.family.update(labelValues, func(s *series) {
+		if s.counts == nil {
+			s.counts = make([]uint64, len(buckets)+1)
+		}
+		s.counts[i]++
+		s.count++
+		s.value += value
+	})
+}
+
+// update updates the series of some values of the labels of the metric, creating it if needed.
+func (metric *family) update(labelValues []string, update func(s *series)) {
+	if len(labelValues) != len(metric.labels) {
+		panic("family::update() - Error: invalid number of label values for metric: " + metric.name)
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	metric.mutex.Lock()
+	defer metric.mutex.Unlock()
+
+	s, ok := metric.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		metric.series[key] = s
+	}
+	update(s)
+}
+
+// value returns the value of the series of some values of the labels of the metric (0 if it has none).
+func (metric *family) value(labelValues []string) float64 {
+	metric.mutex.Lock()
+	defer metric.mutex.Unlock()
+
+	if s, ok := metric.series[strings.Join(labelValues, "\xff")]; ok {
+		return s.value
+	}
+	return 0
+}
+
+// Write writes the metrics of the registry in the Prometheus text exposition format, sorted by name.
+func (registry *Registry) Write(w io.Writer) error {
+	registry.mutex.Lock()
+	names := make([]string, 0, len(registry.families))
+	for name := range registry.families {
+		names = append(names, name)
+	}
+	registry.mutex.Unlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		registry.mutex.Lock()
+		metric := registry.families[name]
+		registry.mutex.Unlock()
+
+		if _, err := io.WriteString(w, metric.text()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeHTTP serves the metrics of the registry in the Prometheus text exposition format.
+func (registry *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	registry.Write(w)
+}
+
+// text returns the metric in the text exposition format: its help, type and samples, sorted by their labels.
+func (metric *family) text() string {
+	metric.mutex.Lock()
+	defer metric.mutex.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n", metric.name, escape(metric.help, false))
+	fmt.Fprintf(&b, "# TYPE %s %s\n", metric.name, metric.kind)
+
+	keys := make([]string, 0, len(metric.series))
+	for key := range metric.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := metric.series[key]
+		if metric.kind != "histogram" {
+			fmt.Fprintf(&b, "%s%s %s\n", metric.name, metric.labelText(s.labelValues, ""), formatFloat(s.value))
+			continue
+		}
+
+		cumulative := uint64(0)
+		for i, upperBound := range metric.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", metric.name, metric.labelText(s.labelValues, formatFloat(upperBound)), cumulative)
+		}
+		fmt.Fprintf(&b, "%s_bucket%s %d\n", metric.name, metric.labelText(s.labelValues, "+Inf"), s.count)
+		fmt.Fprintf(&b, "%s_sum%s %s\n", metric.name, metric.labelText(s.labelValues, ""), formatFloat(s.value))
+		fmt.Fprintf(&b, "%s_count%s %d\n", metric.name, metric.labelText(s.labelValues, ""), s.count)
+	}
+	return b.String()
+}
+
+// labelText returns the labels of a sample (e.g. `{code="200"}`), with the le label of histogram buckets if given.
+func (metric *family) labelText(labelValues []string, le string) string {
+	pairs := []string{}
+	for i, label := range metric.labels {
+		pairs = append(pairs, label+`="`+escape(labelValues[i], true)+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// escape escapes the backslashes and line feeds of a help text, and also the double quotes of a label value.
+func escape(s string, quotes bool) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	if quotes {
+		s = strings.Replace(s, `"`, `\"`, -1)
+	}
+	return s
+}
+
+// formatFloat formats a value as in the text exposition format (e.g. "+Inf").
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
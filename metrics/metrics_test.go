@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_Write(t *testing.T) {
+	registry := NewRegistry()
+	fetches := registry.NewCounter("fetches_total", "Number of pages fetched.", "code")
+	workers := registry.NewGauge("workers", "Number of workers.")
+	duration := registry.NewHistogram("duration_seconds", "Time taken.", []float64{0.1, 1})
+
+	fetches.Inc("200")
+	fetches.Add(2, "200")
+	fetches.Inc("404")
+	workers.Set(4)
+	workers.Dec()
+	duration.Observe(0.05)
+	duration.Observe(0.5)
+	duration.Observe(3)
+
+	expected := `# HELP duration_seconds Time taken.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{le="0.1"} 1
+duration_seconds_bucket{le="1"} 2
+duration_seconds_bucket{le="+Inf"} 3
+duration_seconds_sum 3.55
+duration_seconds_count 3
+# HELP fetches_total Number of pages fetched.
+# TYPE fetches_total counter
+fetches_total{code="200"} 3
+fetches_total{code="404"} 1
+# HELP workers Number of workers.
+# TYPE workers gauge
+workers 3
+`
+	var b bytes.Buffer
+	if err := registry.Write(&b); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if b.String() != expected {
+		t.Errorf("Metrics were invalid.\nExpected:\n%s\nGot:\n%s", expected, b.String())
+	}
+
+	if value := fetches.Value("200"); value != 3 {
+		t.Errorf("Value of the counter was invalid. Expected: %v, Got: %v", 3, value)
+	}
+	if value := workers.Value(); value != 3 {
+		t.Errorf("Value of the gauge was invalid. Expected: %v, Got: %v", 3, value)
+	}
+}
+
+func TestRegistry_Write_Escaping(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounter("errors_total", "Number of errors\\failures\nby class.", "class").Inc("a \"quoted\"\nclass")
+
+	var b bytes.Buffer
+	registry.Write(&b)
+
+	for _, line := range []string{
+		`# HELP errors_total Number of errors\\failures\nby class.`,
+		`errors_total{class="a \"quoted\"\nclass"} 1`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("Metrics don't contain line: %s\nGot:\n%s", line, b.String())
+		}
+	}
+}
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewGauge("queued", "Number of URLs queued.").Set(7)
+
+	recorder := httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("Content type was invalid. Got: %s", contentType)
+	}
+	if !strings.Contains(recorder.Body.String(), "queued 7\n") {
+		t.Errorf("Metrics were invalid. Got:\n%s", recorder.Body.String())
+	}
+}
+
+func TestRegistry_NewCounter_Twice(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounter("fetches_total", "Number of pages fetched.")
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Defining a metric twice didn't panic")
+		}
+	}()
+	registry.NewGauge("fetches_total", "Number of pages fetched.")
+}
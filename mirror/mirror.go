@@ -0,0 +1,193 @@
+// Package mirror saves the pages and assets of a crawled domain to a directory that mirrors the structure of
+// their URLs, with the links between them rewritten to relative local paths so that it can be browsed offline.
+package mirror
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Mirror saves the resources of a domain under a directory (safe for concurrent use).
+type Mirror struct {
+	dir    string
+	domain *url.URL
+
+	mutex     sync.Mutex
+	files     map[string]string // local path (relative to dir, with slashes) of every URL saved
+	pages     map[string]string // URL of every HTML page saved, by local path
+	redirects map[string]string // target of every redirect followed, by URL
+}
+
+// New creates a Mirror that saves the resources of the domain of an URL under a directory, creating it if needed.
+func New(dir string, domain string) (*Mirror, error) {
+	domainURL, err := url.Parse(domain)
+	if err != nil {
+		return nil, errors.New("Mirror::New() - Error: invalid domain: " + domain)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Mirror{
+		dir:       dir,
+		domain:    domainURL,
+		files:     make(map[string]string),
+		pages:     make(map[string]string),
+		redirects: make(map[string]string),
+	}, nil
+}
+
+// Save writes the body of a resource of the domain to its local path. Resources of other domains are ignored.
+func (mirror *Mirror) Save(rawURL string, contentType string, body []byte) error {
+	resourceURL, err := url.Parse(rawURL)
+	if err != nil || resourceURL.Host != mirror.domain.Host {
+		return nil
+	}
+
+	html := isHTML(contentType)
+	localPath := localPath(resourceURL, html)
+	file := filepath.Join(mirror.dir, filepath.FromSlash(localPath))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(file, body, 0644); err != nil {
+		return err
+	}
+
+	mirror.mutex.Lock()
+	defer mirror.mutex.Unlock()
+	mirror.files[key(resourceURL)] = localPath
+	if html {
+		mirror.pages[localPath] = resourceURL.String()
+	}
+	return nil
+}
+
+// Redirect records that an URL redirects to another, so that the links to it point to the local copy of the target.
+func (mirror *Mirror) Redirect(from string, to string) {
+	fromURL, err := url.Parse(from)
+	if err != nil {
+		return
+	}
+
+	mirror.mutex.Lock()
+	defer mirror.mutex.Unlock()
+	mirror.redirects[key(fromURL)] = to
+}
+
+// Close rewrites the links of the HTML pages saved: the ones to resources saved point to their local paths
+// and the other relative ones become absolute, so that they keep working from the mirror.
+func (mirror *Mirror) Close() error {
+	mirror.mutex.Lock()
+	defer mirror.mutex.Unlock()
+
+	for localPath, pageURL := range mirror.pages {
+		file := filepath.Join(mirror.dir, filepath.FromSlash(localPath))
+		body, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		base, _ := url.Parse(pageURL)
+		rewritten := rewriteLinks(body, base, func(link *url.URL) (string, bool) {
+			target, ok := mirror.lookup(link)
+			if !ok {
+				return "", false
+			}
+			return relativePath(localPath, target), true
+		})
+
+		if err := ioutil.WriteFile(file, rewritten, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookup returns the local path of an URL, following the redirects recorded (with a limit, to avoid loops).
+func (mirror *Mirror) lookup(link *url.URL) (string, bool) {
+	current := link.String()
+	for i := 0; i < 10; i++ {
+		parsed, err := url.Parse(current)
+		if err != nil {
+			return "", false
+		}
+		if localPath, ok := mirror.files[key(parsed)]; ok {
+			return localPath, true
+		}
+
+		target, ok := mirror.redirects[key(parsed)]
+		if !ok {
+			return "", false
+		}
+		current = target
+	}
+	return "", false
+}
+
+// key identifies an URL in the mirror, regardless of its fragment and of a trailing slash in the domain root.
+func key(resourceURL *url.URL) string {
+	keyURL := *resourceURL
+	keyURL.Fragment = ""
+	if keyURL.Path == "" {
+		keyURL.Path = "/"
+	}
+	return keyURL.String()
+}
+
+// localPath returns the path (with slashes) under the mirror directory to which a resource is saved:
+// directories and HTML pages without an extension are saved as index.html files (e.g. "/blog" to
+// "blog/index.html") and a hash of the query string, if any, is added to the name of the file.
+func localPath(resourceURL *url.URL, html bool) string {
+	urlPath := resourceURL.Path
+	if urlPath == "" || strings.HasSuffix(urlPath, "/") {
+		urlPath += "index.html"
+	} else if html && path.Ext(urlPath) == "" {
+		urlPath += "/index.html"
+	}
+	urlPath = strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+
+	if resourceURL.RawQuery != "" {
+		hash := sha1.Sum([]byte(resourceURL.RawQuery))
+		ext := path.Ext(urlPath)
+		urlPath = strings.TrimSuffix(urlPath, ext) + "-" + hex.EncodeToString(hash[:4]) + ext
+	}
+	return urlPath
+}
+
+// relativePath returns the path of a file relative to the directory of another (both relative to the mirror).
+func relativePath(from string, to string) string {
+	fromDir := strings.Split(path.Dir(from), "/")
+	if fromDir[0] == "." {
+		fromDir = nil
+	}
+	toParts := strings.Split(to, "/")
+
+	// Skip the directories in common:
+	common := 0
+	for common < len(fromDir) && common < len(toParts)-1 && fromDir[common] == toParts[common] {
+		common++
+	}
+
+	parts := []string{}
+	for i := common; i < len(fromDir); i++ {
+		parts = append(parts, "..")
+	}
+	parts = append(parts, toParts[common:]...)
+	return strings.Join(parts, "/")
+}
+
+// isHTML tells if a content type is the one of an HTML page.
+func isHTML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "text/html"
+}
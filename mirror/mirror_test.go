@@ -0,0 +1,160 @@
+package mirror
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/msandim/web-crawler/crawler"
+)
+
+func TestLocalPath(t *testing.T) {
+	tests := []struct {
+		url      string
+		html     bool
+		expected string
+	}{
+		{"http://monzo.com", true, "index.html"},
+		{"http://monzo.com/", true, "index.html"},
+		{"http://monzo.com/blog/", true, "blog/index.html"},
+		{"http://monzo.com/blog", true, "blog/index.html"},
+		{"http://monzo.com/blog/post.html", true, "blog/post.html"},
+		{"http://monzo.com/img/logo.png", false, "img/logo.png"},
+		{"http://monzo.com/../etc/passwd", false, "etc/passwd"},
+		{"http://monzo.com/site.css?v=2", false, "site-" + "a1b2c3d4" + ".css"},
+	}
+
+	for _, test := range tests {
+		resourceURL, _ := url.Parse(test.url)
+		got := localPath(resourceURL, test.html)
+
+		// The hash of the query is only checked for its format:
+		if strings.Contains(test.url, "?") {
+			if !strings.HasPrefix(got, "site-") || !strings.HasSuffix(got, ".css") || len(got) != len(test.expected) {
+				t.Errorf("Invalid local path of %s. Got: %s", test.url, got)
+			}
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("Invalid local path of %s. Expected: %s, Got: %s", test.url, test.expected, got)
+		}
+	}
+}
+
+func TestRelativePath(t *testing.T) {
+	tests := []struct {
+		from, to, expected string
+	}{
+		{"index.html", "about/index.html", "about/index.html"},
+		{"about/index.html", "index.html", "../index.html"},
+		{"blog/a/index.html", "blog/b/index.html", "../b/index.html"},
+		{"blog/index.html", "blog/post.html", "post.html"},
+		{"blog/index.html", "img/logo.png", "../img/logo.png"},
+	}
+
+	for _, test := range tests {
+		if got := relativePath(test.from, test.to); got != test.expected {
+			t.Errorf("Invalid path from %s to %s. Expected: %s, Got: %s", test.from, test.to, test.expected, got)
+		}
+	}
+}
+
+func TestRewriteLinks(t *testing.T) {
+	base, _ := url.Parse("http://monzo.com/blog/")
+	local := map[string]string{
+		"http://monzo.com/":             "../index.html",
+		"http://monzo.com/blog/post":    "post/index.html",
+		"http://monzo.com/img/logo.png": "../img/logo.png",
+	}
+
+	body := `<html><!-- comment --><a href="/">Home</a> <a class="x" href="post#comments">Post</a>` +
+		`<a href="#top">Top</a><a href="mailto:a@monzo.com">Mail</a><a href="other">Other</a>` +
+		`<img src="../img/logo.png" srcset="/img/logo.png 1x, /img/logo@2x.png 2x" alt="Logo"><p>Text &amp; more</p></html>`
+	expected := `<html><!-- comment --><a href="../index.html">Home</a> <a class="x" href="post/index.html#comments">Post</a>` +
+		`<a href="#top">Top</a><a href="mailto:a@monzo.com">Mail</a><a href="http://monzo.com/blog/other">Other</a>` +
+		`<img src="../img/logo.png" srcset="../img/logo.png 1x, http://monzo.com/img/logo@2x.png 2x" alt="Logo"><p>Text &amp; more</p></html>`
+
+	got := string(rewriteLinks([]byte(body), base, func(link *url.URL) (string, bool) {
+		localPath, ok := local[link.String()]
+		return localPath, ok
+	}))
+	if got != expected {
+		t.Errorf("Invalid page rewritten. Expected: %s, Got: %s", expected, got)
+	}
+}
+
+func TestMirror(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Add("Content-type", "text/html; charset=utf-8")
+			w.Write([]byte(`<html><head><link rel="stylesheet" href="/css/site.css"></head>` +
+				`<a href="/about">About</a><a href="/old">Old</a><a href="/missing">Missing</a>` +
+				`<img src="/img/logo.png"></html>`))
+		case "/about":
+			w.Header().Add("Content-type", "text/html; charset=utf-8")
+			w.Write([]byte(`<html><a href="/">Home</a><img src="img/logo.png"></html>`))
+		case "/old":
+			http.Redirect(w, r, "/about", http.StatusMovedPermanently)
+		case "/css/site.css":
+			w.Header().Add("Content-type", "text/css")
+			w.Write([]byte(`body { background: url(/img/logo.png); }`))
+		case "/img/logo.png":
+			w.Header().Add("Content-type", "image/png")
+			w.Write([]byte("PNG"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "mirror")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	siteMirror, err := New(dir, server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	options := crawler.Options{
+		Quiet:     true,
+		Assets:    true,
+		Transport: NewTransport(nil, siteMirror, func(err error) { t.Errorf("Unexpected error: %v", err) }),
+	}
+	crawler.NewWithOptions(4, 4, 10, server.URL, options).Run()
+
+	if err := siteMirror.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"index.html": `<html><head><link rel="stylesheet" href="css/site.css"></head>` +
+			`<a href="about/index.html">About</a><a href="about/index.html">Old</a><a href="` + server.URL + `/missing">Missing</a>` +
+			`<img src="img/logo.png"></html>`,
+		"about/index.html": `<html><a href="../index.html">Home</a><img src="../img/logo.png"></html>`,
+		"css/site.css":     `body { background: url(/img/logo.png); }`,
+		"img/logo.png":     "PNG",
+	}
+	for file, content := range expected {
+		got, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil {
+			t.Errorf("File %s was not saved: %v", file, err)
+			continue
+		}
+		if string(got) != content {
+			t.Errorf("Invalid content of %s. Expected: %s, Got: %s", file, content, got)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("Pages not found should not be saved")
+	}
+}
@@ -0,0 +1,125 @@
+package mirror
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// linkAttributes are the attributes that link to other resources, by tag.
+var linkAttributes = map[string][]string{
+	"a":      {"href"},
+	"area":   {"href"},
+	"link":   {"href"},
+	"img":    {"src", "srcset"},
+	"script": {"src"},
+	"iframe": {"src"},
+	"embed":  {"src"},
+	"audio":  {"src"},
+	"video":  {"src", "poster"},
+	"track":  {"src"},
+	"source": {"src", "srcset"},
+	"object": {"data"},
+}
+
+// rewriteLinks rewrites the links of an HTML page: the ones for which local returns a path are replaced by it
+// and the other relative ones are made absolute. The rest of the page is kept as it is.
+func rewriteLinks(body []byte, base *url.URL, local func(link *url.URL) (string, bool)) []byte {
+	var buffer bytes.Buffer
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return buffer.Bytes()
+		}
+
+		raw := tokenizer.Raw()
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			buffer.Write(raw)
+			continue
+		}
+
+		// Tags are only re-rendered if one of their links changed:
+		raw = append([]byte(nil), raw...)
+		token := tokenizer.Token()
+		changed := false
+		for i, attribute := range token.Attr {
+			if !isLinkAttribute(token.Data, attribute.Key) {
+				continue
+			}
+
+			var value string
+			if attribute.Key == "srcset" {
+				value = rewriteSrcset(attribute.Val, base, local)
+			} else {
+				value = rewriteLink(attribute.Val, base, local)
+			}
+			if value != attribute.Val {
+				token.Attr[i].Val = value
+				changed = true
+			}
+		}
+
+		if changed {
+			buffer.WriteString(token.String())
+		} else {
+			buffer.Write(raw)
+		}
+	}
+}
+
+// isLinkAttribute tells if an attribute of a tag links to another resource.
+func isLinkAttribute(tag string, attribute string) bool {
+	for _, linkAttribute := range linkAttributes[tag] {
+		if attribute == linkAttribute {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteLink returns the local path of a link (keeping its fragment) if it was saved, or its absolute URL otherwise.
+// Links that aren't to HTTP resources (e.g. "mailto:") and links to fragments of the page itself are kept.
+func rewriteLink(rawLink string, base *url.URL, local func(link *url.URL) (string, bool)) string {
+	trimmed := strings.TrimSpace(rawLink)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return rawLink
+	}
+
+	parsed, err := url.Parse(trimmed)
+	if err != nil {
+		return rawLink
+	}
+	link := base.ResolveReference(parsed)
+	if link.Scheme != "http" && link.Scheme != "https" {
+		return rawLink
+	}
+
+	fragment := ""
+	if i := strings.Index(trimmed, "#"); i >= 0 {
+		fragment = trimmed[i:]
+	}
+	link.Fragment = ""
+
+	if localPath, ok := local(link); ok {
+		return localPath + fragment
+	}
+	return link.String() + fragment
+}
+
+// rewriteSrcset rewrites the images of a srcset attribute (e.g. "a.png 1x, b.png 2x"), keeping their descriptors.
+func rewriteSrcset(srcset string, base *url.URL, local func(link *url.URL) (string, bool)) string {
+	candidates := strings.Split(srcset, ",")
+	for i, candidate := range candidates {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		fields[0] = rewriteLink(fields[0], base, local)
+		candidates[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(candidates, ", ")
+}
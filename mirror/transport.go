@@ -0,0 +1,55 @@
+package mirror
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+)
+
+// Transport is an http.RoundTripper that saves the resources downloaded successfully with GET requests
+// (and the redirects followed) to a Mirror.
+type Transport struct {
+	base    http.RoundTripper
+	mirror  *Mirror
+	onError func(err error)
+}
+
+// NewTransport returns a Transport that sends the requests with a base transport (http.DefaultTransport if nil)
+// and saves the resources downloaded to a Mirror. Errors saving a resource are passed to onError (if not nil),
+// without failing the request.
+func NewTransport(base http.RoundTripper, mirror *Mirror, onError func(err error)) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{base: base, mirror: mirror, onError: onError}
+}
+
+// RoundTrip sends a request and saves the resource downloaded, whose body is read to be saved.
+func (transport *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := transport.base.RoundTrip(req)
+	if err != nil || req.Method != http.MethodGet {
+		return resp, err
+	}
+
+	switch {
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		if location, err := resp.Location(); err == nil {
+			transport.mirror.Redirect(req.URL.String(), location.String())
+		}
+		return resp, nil
+	case resp.StatusCode != http.StatusOK:
+		return resp, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if err := transport.mirror.Save(req.URL.String(), resp.Header.Get("Content-Type"), body); err != nil && transport.onError != nil {
+		transport.onError(err)
+	}
+	return resp, nil
+}
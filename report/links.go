@@ -0,0 +1,64 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/msandim/web-crawler/graph"
+)
+
+// BrokenLink is a page that couldn't be fetched, along with the links pointing to it.
+type BrokenLink struct {
+	Node      *graph.Node
+	Referrers []graph.Edge
+}
+
+// IsBroken checks if a node corresponds to a page that couldn't be fetched successfully.
+func IsBroken(node *graph.Node) bool {
+	return node.IsBroken()
+}
+
+// BrokenLinks returns the pages of the graph that couldn't be fetched, in the order
+// they were crawled, each with all the links (source page and anchor text) pointing to it.
+func BrokenLinks(sitemap *graph.Graph) []BrokenLink {
+	referrers := make(map[string][]graph.Edge)
+	for _, edge := range sitemap.Edges {
+		referrers[edge.Target] = append(referrers[edge.Target], edge)
+	}
+
+	broken := []BrokenLink{}
+	for _, node := range sitemap.Nodes {
+		if IsBroken(node) {
+			broken = append(broken, BrokenLink{Node: node, Referrers: referrers[node.URL]})
+		}
+	}
+	return broken
+}
+
+// WriteBrokenLinks writes a text report of the broken links of the graph with the following format,
+// and returns the number of broken pages found:
+//
+//	x websiteB (404 Not Found)
+//	  <- websiteA "anchor text"
+func WriteBrokenLinks(w io.Writer, sitemap *graph.Graph) (int, error) {
+	broken := BrokenLinks(sitemap)
+
+	for _, link := range broken {
+		status := "no response"
+		if link.Node.StatusCode != 0 {
+			status = fmt.Sprintf("%d %s", link.Node.StatusCode, http.StatusText(link.Node.StatusCode))
+		}
+
+		if _, err := fmt.Fprintf(w, "x %s (%s)\n", link.Node.URL, status); err != nil {
+			return len(broken), err
+		}
+
+		for _, referrer := range link.Referrers {
+			if _, err := fmt.Fprintf(w, "  <- %s %q\n", referrer.Source, referrer.AnchorText); err != nil {
+				return len(broken), err
+			}
+		}
+	}
+	return len(broken), nil
+}
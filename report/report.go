@@ -0,0 +1,212 @@
+package report
+
+import (
+	"html/template"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/msandim/web-crawler/audit"
+	"github.com/msandim/web-crawler/graph"
+	"github.com/msandim/web-crawler/traps"
+)
+
+// nSlowestPages is the number of pages listed in the slowest pages section.
+const nSlowestPages = 20
+
+// nIssuePages is the number of pages listed in the pages with the most issues section.
+const nIssuePages = 20
+
+// reportData contains everything rendered in the report.
+type reportData struct {
+	Root        string
+	GeneratedAt time.Time
+	TotalPages  int
+	TotalLinks  int
+	TotalBroken int
+	StatusCodes []statusCount
+	Slowest     []*graph.Node
+	Broken      []BrokenLink
+	Redirects   []*graph.Node
+	Depths      []depthCount
+	Duplicates  [][]*graph.Node
+	Issues      []audit.Issue
+	IssueCounts []audit.RuleCount
+	IssuePages  []audit.URLCount
+	Traps       []audit.Issue
+	Tree        *treeNode
+}
+
+type statusCount struct {
+	StatusCode int
+	Count      int
+}
+
+type depthCount struct {
+	Depth   int
+	Count   int
+	Percent float64
+}
+
+// treeNode is a segment of the path of the pages in the site tree.
+type treeNode struct {
+	Name     string
+	Node     *graph.Node // nil if there is no page at this path
+	Children []*treeNode
+}
+
+// Write renders a self-contained HTML report (with its CSS and JS embedded) of a crawl,
+// along with the issues found in its pages. The crawler traps detected are summarized in their own section.
+func Write(w io.Writer, sitemap *graph.Graph, issues []audit.Issue) error {
+	data := newReportData(sitemap)
+	data.Issues = []audit.Issue{}
+	for _, issue := range issues {
+		if traps.IsRule(issue.Rule) {
+			data.Traps = append(data.Traps, issue)
+		} else {
+			data.Issues = append(data.Issues, issue)
+		}
+	}
+	data.IssueCounts = audit.CountByRule(data.Issues)
+	data.IssuePages = audit.CountByURL(data.Issues)
+	if len(data.IssuePages) > nIssuePages {
+		data.IssuePages = data.IssuePages[:nIssuePages]
+	}
+	return reportTemplate.Execute(w, data)
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"isBroken": IsBroken,
+	"ms": func(d time.Duration) int64 {
+		return d.Nanoseconds() / 1e6
+	},
+}).Parse(reportHTML))
+
+// newReportData computes the statistics of a crawl.
+func newReportData(sitemap *graph.Graph) *reportData {
+	data := &reportData{
+		GeneratedAt: time.Now(),
+		TotalPages:  len(sitemap.Nodes),
+		TotalLinks:  len(sitemap.Edges),
+		Tree:        &treeNode{},
+	}
+
+	if len(sitemap.Nodes) > 0 {
+		data.Root = sitemap.Nodes[0].URL
+	}
+
+	statusCodes := make(map[int]int)
+	depths := make(map[int]int)
+	for _, node := range sitemap.Nodes {
+		statusCodes[node.StatusCode]++
+		depths[node.Depth]++
+
+		if len(node.Redirects) > 0 {
+			data.Redirects = append(data.Redirects, node)
+		}
+		data.Tree.add(node)
+	}
+	data.Broken = BrokenLinks(sitemap)
+	data.Duplicates = duplicateGroups(sitemap)
+	data.TotalBroken = len(data.Broken)
+
+	for statusCode, count := range statusCodes {
+		data.StatusCodes = append(data.StatusCodes, statusCount{StatusCode: statusCode, Count: count})
+	}
+	sort.Slice(data.StatusCodes, func(i, j int) bool {
+		return data.StatusCodes[i].StatusCode < data.StatusCodes[j].StatusCode
+	})
+
+	for depth, count := range depths {
+		data.Depths = append(data.Depths, depthCount{
+			Depth:   depth,
+			Count:   count,
+			Percent: 100 * float64(count) / float64(len(sitemap.Nodes)),
+		})
+	}
+	sort.Slice(data.Depths, func(i, j int) bool {
+		return data.Depths[i].Depth < data.Depths[j].Depth
+	})
+
+	data.Slowest = append([]*graph.Node{}, sitemap.Nodes...)
+	sort.SliceStable(data.Slowest, func(i, j int) bool {
+		return data.Slowest[i].ResponseTime > data.Slowest[j].ResponseTime
+	})
+	if len(data.Slowest) > nSlowestPages {
+		data.Slowest = data.Slowest[:nSlowestPages]
+	}
+
+	data.Tree.sort()
+	return data
+}
+
+// duplicateGroups groups the pages that are duplicates of the same page, along with it.
+func duplicateGroups(sitemap *graph.Graph) [][]*graph.Node {
+	groups := [][]*graph.Node{}
+	indexes := make(map[string]int)
+
+	for _, node := range sitemap.Nodes {
+		if node.DuplicateOf == "" {
+			continue
+		}
+
+		i, ok := indexes[node.DuplicateOf]
+		if !ok {
+			original, found := sitemap.Node(node.DuplicateOf)
+			if !found {
+				continue
+			}
+			i = len(groups)
+			indexes[node.DuplicateOf] = i
+			groups = append(groups, []*graph.Node{original})
+		}
+		groups[i] = append(groups[i], node)
+	}
+	return groups
+}
+
+// add inserts a page in the tree, according to the host and segments of its path.
+func (tree *treeNode) add(node *graph.Node) {
+	segments := []string{node.URL}
+	if parsed, err := url.Parse(node.URL); err == nil {
+		segments = []string{parsed.Host}
+		for _, segment := range strings.Split(parsed.Path, "/") {
+			if segment != "" {
+				segments = append(segments, segment)
+			}
+		}
+	}
+
+	current := tree
+	for _, segment := range segments {
+		var child *treeNode
+		for _, c := range current.Children {
+			if c.Name == segment {
+				child = c
+				break
+			}
+		}
+		if child == nil {
+			child = &treeNode{Name: segment}
+			current.Children = append(current.Children, child)
+		}
+		current = child
+	}
+
+	// Keep the first page found for a path (e.g. "http://a.com/" and "https://a.com/"):
+	if current.Node == nil {
+		current.Node = node
+	}
+}
+
+// sort orders the children of the tree alphabetically, recursively.
+func (tree *treeNode) sort() {
+	sort.Slice(tree.Children, func(i, j int) bool {
+		return tree.Children[i].Name < tree.Children[j].Name
+	})
+	for _, child := range tree.Children {
+		child.sort()
+	}
+}
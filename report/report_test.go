@@ -0,0 +1,140 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/msandim/web-crawler/audit"
+	"github.com/msandim/web-crawler/graph"
+)
+
+func testGraph() *graph.Graph {
+	sitemap := graph.New()
+	sitemap.AddNode(&graph.Node{URL: "http://monzo.com/", StatusCode: 200, Depth: 0, ResponseTime: 10 * time.Millisecond})
+	sitemap.AddNode(&graph.Node{URL: "http://monzo.com/blog/a", StatusCode: 200, Depth: 1, ResponseTime: 300 * time.Millisecond,
+		Redirects: []string{"http://monzo.com/blog/a/"}})
+	sitemap.AddNode(&graph.Node{URL: "http://monzo.com/blog/b", StatusCode: 404, Depth: 1, ResponseTime: 20 * time.Millisecond})
+	sitemap.AddNode(&graph.Node{URL: "http://monzo.com/c", StatusCode: 0, Depth: 2})
+	sitemap.AddNode(&graph.Node{URL: "http://monzo.com/d", StatusCode: 200, Depth: 2, DuplicateOf: "http://monzo.com/blog/a"})
+	sitemap.AddEdge(graph.Edge{Source: "http://monzo.com/", Target: "http://monzo.com/blog/a", AnchorText: "A"})
+	sitemap.AddEdge(graph.Edge{Source: "http://monzo.com/", Target: "http://monzo.com/blog/b", AnchorText: "Read <B>"})
+	sitemap.AddEdge(graph.Edge{Source: "http://monzo.com/blog/a", Target: "http://monzo.com/blog/b"})
+	sitemap.AddEdge(graph.Edge{Source: "http://monzo.com/blog/a", Target: "http://monzo.com/c"})
+	return sitemap
+}
+
+func TestNewReportData(t *testing.T) {
+	data := newReportData(testGraph())
+
+	if data.Root != "http://monzo.com/" || data.TotalPages != 5 || data.TotalLinks != 4 || data.TotalBroken != 2 {
+		t.Errorf("Invalid totals: root %s, pages %d, links %d, broken %d", data.Root, data.TotalPages, data.TotalLinks, data.TotalBroken)
+	}
+
+	expectedStatusCodes := []statusCount{{0, 1}, {200, 3}, {404, 1}}
+	if len(data.StatusCodes) != len(expectedStatusCodes) {
+		t.Fatalf("Invalid status codes. Expected: %v, Got: %v", expectedStatusCodes, data.StatusCodes)
+	}
+	for i := range expectedStatusCodes {
+		if data.StatusCodes[i] != expectedStatusCodes[i] {
+			t.Errorf("Invalid status codes. Expected: %v, Got: %v", expectedStatusCodes, data.StatusCodes)
+		}
+	}
+
+	if data.Slowest[0].URL != "http://monzo.com/blog/a" || data.Slowest[1].URL != "http://monzo.com/blog/b" {
+		t.Errorf("Slowest pages are not sorted by response time")
+	}
+
+	if len(data.Broken) != 2 || data.Broken[0].Node.URL != "http://monzo.com/blog/b" || len(data.Broken[0].Referrers) != 2 {
+		t.Errorf("Invalid broken pages: %+v", data.Broken)
+	}
+
+	if len(data.Redirects) != 1 || data.Redirects[0].URL != "http://monzo.com/blog/a" {
+		t.Errorf("Invalid redirect chains: %+v", data.Redirects)
+	}
+
+	if len(data.Depths) != 3 || data.Depths[1].Depth != 1 || data.Depths[1].Count != 2 || data.Depths[1].Percent != 40 {
+		t.Errorf("Invalid depth histogram: %+v", data.Depths)
+	}
+
+	if len(data.Duplicates) != 1 || len(data.Duplicates[0]) != 2 || data.Duplicates[0][0].URL != "http://monzo.com/blog/a" {
+		t.Errorf("Invalid duplicate groups: %+v", data.Duplicates)
+	}
+
+	// Tree: monzo.com -> (blog -> (a, b), c, d)
+	if len(data.Tree.Children) != 1 || data.Tree.Children[0].Name != "monzo.com" {
+		t.Fatalf("Invalid tree root: %+v", data.Tree.Children)
+	}
+	host := data.Tree.Children[0]
+	if host.Node == nil || host.Node.URL != "http://monzo.com/" || len(host.Children) != 3 {
+		t.Fatalf("Invalid tree host node: %+v", host)
+	}
+	if host.Children[0].Name != "blog" || host.Children[0].Node != nil || len(host.Children[0].Children) != 2 {
+		t.Errorf("Invalid tree blog node: %+v", host.Children[0])
+	}
+	if host.Children[1].Name != "c" || host.Children[1].Node == nil {
+		t.Errorf("Invalid tree c node: %+v", host.Children[1])
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	issues := []audit.Issue{
+		{URL: "http://monzo.com/d", Rule: "canonical-chain", Message: "canonical URL is canonicalized"},
+		{URL: "http://monzo.com/a/a/a/a", Rule: "trap-repeating-segments", Message: "2 URLs like http://monzo.com/a/a/a/a/... were skipped"},
+	}
+	if err := Write(&buf, testGraph(), issues); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out := buf.String()
+
+	expected := []string{
+		"<title>Crawl report - http://monzo.com/</title>",
+		`<div class="value broken">2</div>broken pages`,
+		`<a href="http://monzo.com/">http://monzo.com/</a> ("Read &lt;B&gt;")`,
+		"<div>&rarr; http://monzo.com/blog/a/</div>",
+		`<div class="bar" style="width: 40.0%"></div>`,
+		`<div><a href="http://monzo.com/d">http://monzo.com/d</a></div>`,
+		`<a href="http://monzo.com/blog/b" class="broken">b</a>`,
+		"<td>canonical-chain</td><td>canonical URL is canonicalized</td>",
+		"<tr><td>canonical-chain</td><td>1</td></tr>",
+		"<tr><td>trap-repeating-segments</td><td>2 URLs like http://monzo.com/a/a/a/a/... were skipped</td><td>http://monzo.com/a/a/a/a</td></tr>",
+	}
+	for _, s := range expected {
+		if !strings.Contains(out, s) {
+			t.Errorf("Report does not contain: %s", s)
+		}
+	}
+
+	// The traps are only listed in their own section:
+	if strings.Contains(out, "<tr><td>trap-repeating-segments</td><td>1</td></tr>") {
+		t.Errorf("Traps should not be listed with the issues")
+	}
+
+	if strings.Contains(out, "<link") || strings.Contains(out, "<script src") {
+		t.Errorf("Report should not reference external resources")
+	}
+}
+
+func TestWriteBrokenLinks(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := WriteBrokenLinks(&buf, testGraph())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if n != 2 {
+		t.Errorf("Number of broken links was invalid. Expected: %d, Got: %d", 2, n)
+	}
+
+	expected := `x http://monzo.com/blog/b (404 Not Found)
+  <- http://monzo.com/ "Read <B>"
+  <- http://monzo.com/blog/a ""
+x http://monzo.com/c (no response)
+  <- http://monzo.com/blog/a ""
+`
+	if buf.String() != expected {
+		t.Errorf("Invalid broken links report.\nExpected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,171 @@
+package report
+
+// reportHTML is the template of the report. The CSS and JS are embedded so that
+// the report is a single file that can be opened without any server.
+const reportHTML = `<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Crawl report{{if .Root}} - {{.Root}}{{end}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0 auto; max-width: 1100px; padding: 1em 2em; color: #222; }
+h1 { font-size: 1.6em; margin-bottom: 0; }
+h2 { border-bottom: 1px solid #ddd; padding-bottom: .2em; margin-top: 2em; }
+.meta { color: #777; }
+.totals { display: flex; gap: 1em; }
+.total { flex: 1; border: 1px solid #ddd; border-radius: 4px; padding: 1em; text-align: center; }
+.total .value { font-size: 2em; font-weight: bold; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: .3em .6em; border-bottom: 1px solid #eee; vertical-align: top; word-break: break-all; }
+th { cursor: pointer; background: #f6f6f6; }
+.broken { color: #c0392b; }
+.bar { background: #3498db; height: 1em; }
+ul.tree { list-style: none; padding-left: 1.2em; }
+ul.tree li > span { cursor: pointer; }
+ul.tree li.collapsed > ul { display: none; }
+ul.tree li.parent > span::before { content: "\25BE  "; }
+ul.tree li.parent.collapsed > span::before { content: "\25B8  "; }
+input.filter { width: 100%; padding: .4em; margin-bottom: .5em; box-sizing: border-box; }
+</style>
+</head>
+<body>
+<h1>Crawl report</h1>
+<p class="meta">{{if .Root}}<a href="{{.Root}}">{{.Root}}</a> - {{end}}generated at {{.GeneratedAt.Format "2006-01-02 15:04:05"}}</p>
+
+<div class="totals">
+  <div class="total"><div class="value">{{.TotalPages}}</div>pages</div>
+  <div class="total"><div class="value">{{.TotalLinks}}</div>links</div>
+  <div class="total"><div class="value{{if .TotalBroken}} broken{{end}}">{{.TotalBroken}}</div>broken pages</div>
+  <div class="total"><div class="value">{{len .Redirects}}</div>redirected pages</div>
+</div>
+
+<h2>Status codes</h2>
+<table class="sortable">
+<thead><tr><th>Status code</th><th>Pages</th></tr></thead>
+<tbody>
+{{range .StatusCodes}}<tr><td{{if or (eq .StatusCode 0) (ge .StatusCode 400)}} class="broken"{{end}}>{{if .StatusCode}}{{.StatusCode}}{{else}}no response{{end}}</td><td>{{.Count}}</td></tr>
+{{end}}</tbody>
+</table>
+
+<h2>Slowest pages</h2>
+<table class="sortable">
+<thead><tr><th>URL</th><th>Response time (ms)</th><th>Size (bytes)</th><th>Status code</th></tr></thead>
+<tbody>
+{{range .Slowest}}<tr><td><a href="{{.URL}}">{{.URL}}</a></td><td>{{ms .ResponseTime}}</td><td>{{.Size}}</td><td>{{.StatusCode}}</td></tr>
+{{end}}</tbody>
+</table>
+
+<h2>Broken links</h2>
+{{if .Broken}}<table class="sortable">
+<thead><tr><th>URL</th><th>Status code</th><th>Linked from</th></tr></thead>
+<tbody>
+{{range .Broken}}<tr><td class="broken">{{.Node.URL}}</td><td>{{if .Node.StatusCode}}{{.Node.StatusCode}}{{else}}no response{{end}}</td><td>
+{{range .Referrers}}<div><a href="{{.Source}}">{{.Source}}</a>{{if .AnchorText}} ("{{.AnchorText}}"){{end}}</div>
+{{end}}</td></tr>
+{{end}}</tbody>
+</table>{{else}}<p>No broken links were found.</p>{{end}}
+
+<h2>Redirect chains</h2>
+{{if .Redirects}}<table class="sortable">
+<thead><tr><th>URL</th><th>Redirects</th><th>Hops</th></tr></thead>
+<tbody>
+{{range .Redirects}}<tr><td><a href="{{.URL}}">{{.URL}}</a></td><td>{{range .Redirects}}<div>&rarr; {{.}}</div>{{end}}</td><td>{{len .Redirects}}</td></tr>
+{{end}}</tbody>
+</table>{{else}}<p>No redirects were found.</p>{{end}}
+
+<h2>Depth</h2>
+<table>
+<thead><tr><th>Depth</th><th>Pages</th><th style="width:60%"></th></tr></thead>
+<tbody>
+{{range .Depths}}<tr><td>{{.Depth}}</td><td>{{.Count}}</td><td><div class="bar" style="width: {{printf "%.1f" .Percent}}%"></div></td></tr>
+{{end}}</tbody>
+</table>
+
+<h2>Duplicate content</h2>
+{{if .Duplicates}}<table>
+<thead><tr><th>Pages with the same (or nearly the same) content</th></tr></thead>
+<tbody>
+{{range .Duplicates}}<tr><td>{{range .}}<div><a href="{{.URL}}">{{.URL}}</a></div>{{end}}</td></tr>
+{{end}}</tbody>
+</table>{{else}}<p>No duplicate content was found.</p>{{end}}
+
+<h2>Issues</h2>
+{{if .Issues}}<table>
+<thead><tr><th>Rule</th><th>Issues</th></tr></thead>
+<tbody>
+{{range .IssueCounts}}<tr><td>{{.Rule}}</td><td>{{.Count}}</td></tr>
+{{end}}</tbody>
+</table>
+<table>
+<thead><tr><th>Page</th><th>Issues</th></tr></thead>
+<tbody>
+{{range .IssuePages}}<tr><td><a href="{{.URL}}">{{.URL}}</a></td><td>{{.Count}}</td></tr>
+{{end}}</tbody>
+</table>
+<table class="sortable">
+<thead><tr><th>URL</th><th>Rule</th><th>Issue</th></tr></thead>
+<tbody>
+{{range .Issues}}<tr><td><a href="{{.URL}}">{{.URL}}</a></td><td>{{.Rule}}</td><td>{{.Message}}</td></tr>
+{{end}}</tbody>
+</table>{{else}}<p>No issues were found.</p>{{end}}
+
+<h2>Crawler traps</h2>
+{{if .Traps}}<table>
+<thead><tr><th>Rule</th><th>URLs skipped</th><th>First URL skipped</th></tr></thead>
+<tbody>
+{{range .Traps}}<tr><td>{{.Rule}}</td><td>{{.Message}}</td><td>{{.URL}}</td></tr>
+{{end}}</tbody>
+</table>{{else}}<p>No crawler traps were detected.</p>{{end}}
+
+<h2>Site tree</h2>
+<input class="filter" type="text" placeholder="Filter pages..." id="tree-filter">
+<ul class="tree" id="tree">
+{{range .Tree.Children}}{{template "tree" .}}{{end}}
+</ul>
+
+<script>
+(function () {
+  // Collapse and expand the branches of the site tree:
+  document.querySelectorAll("ul.tree li.parent > span").forEach(function (span) {
+    span.addEventListener("click", function () {
+      span.parentNode.classList.toggle("collapsed");
+    });
+  });
+
+  // Filter the site tree, showing only the pages that match and their ancestors:
+  document.getElementById("tree-filter").addEventListener("input", function (event) {
+    var query = event.target.value.toLowerCase();
+    document.querySelectorAll("ul.tree li").forEach(function (li) {
+      li.style.display = query === "" || li.textContent.toLowerCase().indexOf(query) >= 0 ? "" : "none";
+      if (query !== "") {
+        li.classList.remove("collapsed");
+      }
+    });
+  });
+
+  // Sort tables when clicking on their headers:
+  document.querySelectorAll("table.sortable th").forEach(function (th) {
+    th.addEventListener("click", function () {
+      var table = th.closest("table");
+      var index = Array.prototype.indexOf.call(th.parentNode.children, th);
+      var ascending = th.dataset.order !== "asc";
+      th.dataset.order = ascending ? "asc" : "desc";
+      var rows = Array.prototype.slice.call(table.tBodies[0].rows);
+      rows.sort(function (a, b) {
+        var x = a.cells[index].textContent.trim(), y = b.cells[index].textContent.trim();
+        var nx = parseFloat(x), ny = parseFloat(y);
+        var result = !isNaN(nx) && !isNaN(ny) ? nx - ny : x.localeCompare(y);
+        return ascending ? result : -result;
+      });
+      rows.forEach(function (row) { table.tBodies[0].appendChild(row); });
+    });
+  });
+})();
+</script>
+</body>
+</html>
+{{define "tree"}}<li{{if .Children}} class="parent"{{end}}><span>{{if .Node}}<a href="{{.Node.URL}}"{{if isBroken .Node}} class="broken"{{end}}>{{.Name}}</a>{{else}}{{.Name}}{{end}}</span>
+{{if .Children}}<ul>
+{{range .Children}}{{template "tree" .}}{{end}}</ul>
+{{end}}</li>
+{{end}}`
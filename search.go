@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/msandim/web-crawler/search"
+)
+
+// runSearch searches the pages of a crawl indexed with the -index flag and outputs the ones that match a query,
+// ranked, with a snippet of their text. It returns the exit code of the program.
+func runSearch(arguments []string) int {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	nResults := flags.Int("n", 10, "maximum number of results (0 outputs all of them)")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, `Usage: web-crawler search [-n 10] index-dir query (e.g. bank OR "current account" -business)`)
+		flags.PrintDefaults()
+	}
+	flags.Parse(arguments)
+
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return -1
+	}
+
+	index, err := search.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "main::runSearch() - Error: failed to open the index: ", flags.Arg(0), err)
+		return -1
+	}
+
+	results, err := index.Search(strings.Join(flags.Args()[1:], " "), *nResults)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "main::runSearch() - Error: invalid query: ", err)
+		return -1
+	}
+
+	for i, result := range results {
+		fmt.Printf("%d. %s (score: %.2f)\n", i+1, result.URL, result.Score)
+		if result.Title != "" {
+			fmt.Println("   " + result.Title)
+		}
+		fmt.Println("   " + result.Snippet)
+	}
+	fmt.Fprintln(os.Stderr, "main::runSearch() - Pages found: ", len(results))
+	return 0
+}
@@ -0,0 +1,121 @@
+package search
+
+import (
+	"bufio"
+	"encoding/gob"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Index is an index written by a Writer, loaded to be searched.
+type Index struct {
+	documents []document
+	postings  segment
+}
+
+// Result is a page that matches a query.
+type Result struct {
+	URL     string
+	Title   string
+	Score   float64
+	Snippet string // excerpt of the text of the page, with the terms of the query highlighted
+}
+
+// Open loads the index of a directory.
+func Open(dir string) (*Index, error) {
+	index := &Index{documents: []document{}, postings: make(segment)}
+
+	f, err := os.Open(filepath.Join(dir, documentsFile))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(f))
+	for decoder.More() {
+		var doc document
+		if err := decoder.Decode(&doc); err != nil {
+			return nil, err
+		}
+		index.documents = append(index.documents, doc)
+	}
+
+	// The segments are sorted by name, so the postings of every term remain ordered by document:
+	segments, err := filepath.Glob(filepath.Join(dir, "segment-*.gob"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(segments)
+
+	for _, path := range segments {
+		if err := index.loadSegment(path); err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+// loadSegment adds the postings of a segment file to the index.
+func (index *Index) loadSegment(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var postings segment
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(&postings); err != nil {
+		return err
+	}
+
+	for term, termPostings := range postings {
+		index.postings[term] = append(index.postings[term], termPostings...)
+	}
+	return nil
+}
+
+// Len returns the number of documents of the index.
+func (index *Index) Len() int {
+	return len(index.documents)
+}
+
+// Search returns the n pages that best match a query (all of them if n <= 0), ranked by TF-IDF.
+// Queries are made of terms (all required), "phrases", the operators OR and NOT (or -term) and parentheses,
+// e.g. `bank OR "current account" -business`.
+func (index *Index) Search(queryText string, n int) ([]Result, error) {
+	parsed, err := parseQuery(queryText)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := parsed.evaluate(index)
+	docs := []int{}
+	for doc := range scores {
+		docs = append(docs, doc)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if scores[docs[i]] != scores[docs[j]] {
+			return scores[docs[i]] > scores[docs[j]]
+		}
+		return docs[i] < docs[j]
+	})
+	if n > 0 && len(docs) > n {
+		docs = docs[:n]
+	}
+
+	terms := make(map[string]bool)
+	parsed.addTerms(terms)
+
+	results := []Result{}
+	for _, doc := range docs {
+		results = append(results, Result{
+			URL:     index.documents[doc].URL,
+			Title:   index.documents[doc].Title,
+			Score:   scores[doc],
+			Snippet: snippet(index.documents[doc].Text, terms),
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,349 @@
+package search
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// query is a node of a parsed query, which matches documents with a score.
+type query interface {
+	// evaluate returns the score of every document matched by the query.
+	evaluate(index *Index) map[int]float64
+	// addTerms adds the terms whose occurrences are highlighted in the snippets.
+	addTerms(terms map[string]bool)
+}
+
+// termQuery matches the documents that contain a term.
+type termQuery struct {
+	term string
+}
+
+// phraseQuery matches the documents that contain a sequence of terms.
+type phraseQuery struct {
+	terms []string
+}
+
+// andQuery matches the documents matched by all its queries.
+type andQuery struct {
+	queries []query
+}
+
+// orQuery matches the documents matched by any of its queries.
+type orQuery struct {
+	queries []query
+}
+
+// notQuery matches the documents not matched by its query.
+type notQuery struct {
+	query query
+}
+
+// score scores the occurrences of a term (or phrase) in a document with TF-IDF.
+func score(occurrences int, nDocumentsMatched int, nDocuments int) float64 {
+	tf := 1 + math.Log(float64(occurrences))
+	idf := math.Log(1 + float64(nDocuments)/float64(nDocumentsMatched))
+	return tf * idf
+}
+
+func (query *termQuery) evaluate(index *Index) map[int]float64 {
+	scores := make(map[int]float64)
+	postings := index.postings[query.term]
+	for _, posting := range postings {
+		scores[posting.Doc] = score(len(posting.Positions), len(postings), len(index.documents))
+	}
+	return scores
+}
+
+func (query *termQuery) addTerms(terms map[string]bool) {
+	terms[query.term] = true
+}
+
+func (query *phraseQuery) evaluate(index *Index) map[int]float64 {
+	// Positions of every term of the phrase in every document:
+	positions := make([]map[int]map[int]bool, len(query.terms))
+	for i, term := range query.terms {
+		positions[i] = make(map[int]map[int]bool)
+		for _, posting := range index.postings[term] {
+			positions[i][posting.Doc] = make(map[int]bool)
+			for _, position := range posting.Positions {
+				positions[i][posting.Doc][position] = true
+			}
+		}
+	}
+
+	occurrences := make(map[int]int)
+	for _, posting := range index.postings[query.terms[0]] {
+		for _, start := range posting.Positions {
+			found := true
+			for i := 1; i < len(query.terms) && found; i++ {
+				found = positions[i][posting.Doc][start+i]
+			}
+			if found {
+				occurrences[posting.Doc]++
+			}
+		}
+	}
+
+	scores := make(map[int]float64)
+	for doc, n := range occurrences {
+		scores[doc] = score(n, len(occurrences), len(index.documents))
+	}
+	return scores
+}
+
+func (query *phraseQuery) addTerms(terms map[string]bool) {
+	for _, term := range query.terms {
+		terms[term] = true
+	}
+}
+
+func (query *andQuery) evaluate(index *Index) map[int]float64 {
+	scores := query.queries[0].evaluate(index)
+	for _, other := range query.queries[1:] {
+		otherScores := other.evaluate(index)
+		for doc, s := range scores {
+			if otherScore, ok := otherScores[doc]; ok {
+				scores[doc] = s + otherScore
+			} else {
+				delete(scores, doc)
+			}
+		}
+	}
+	return scores
+}
+
+func (query *andQuery) addTerms(terms map[string]bool) {
+	for _, q := range query.queries {
+		q.addTerms(terms)
+	}
+}
+
+func (query *orQuery) evaluate(index *Index) map[int]float64 {
+	scores := make(map[int]float64)
+	for _, q := range query.queries {
+		for doc, s := range q.evaluate(index) {
+			scores[doc] += s
+		}
+	}
+	return scores
+}
+
+func (query *orQuery) addTerms(terms map[string]bool) {
+	for _, q := range query.queries {
+		q.addTerms(terms)
+	}
+}
+
+func (query *notQuery) evaluate(index *Index) map[int]float64 {
+	excluded := query.query.evaluate(index)
+	scores := make(map[int]float64)
+	for doc := range index.documents {
+		if _, ok := excluded[doc]; !ok {
+			scores[doc] = 0
+		}
+	}
+	return scores
+}
+
+func (query *notQuery) addTerms(terms map[string]bool) {
+	// The terms of the documents excluded aren't highlighted.
+}
+
+// Kinds of the tokens of a query:
+const (
+	tokenWord = iota
+	tokenPhrase
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+type queryToken struct {
+	kind int
+	text string
+}
+
+// lexQuery splits a query into its tokens: words, "phrases", parentheses and the operators AND, OR, NOT and -.
+func lexQuery(text string) ([]queryToken, error) {
+	tokens := []queryToken{}
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		switch r := runes[i]; {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: tokenOpen})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: tokenClose})
+			i++
+		case r == '-':
+			tokens = append(tokens, queryToken{kind: tokenNot})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, errors.New("unterminated phrase in query: " + text)
+			}
+			tokens = append(tokens, queryToken{kind: tokenPhrase, text: string(runes[i+1 : end])})
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune(`()"`, runes[end]) {
+				end++
+			}
+			word := string(runes[i:end])
+			switch word {
+			case "AND":
+				tokens = append(tokens, queryToken{kind: tokenAnd})
+			case "OR":
+				tokens = append(tokens, queryToken{kind: tokenOr})
+			case "NOT":
+				tokens = append(tokens, queryToken{kind: tokenNot})
+			default:
+				tokens = append(tokens, queryToken{kind: tokenWord, text: word})
+			}
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// queryParser parses the tokens of a query with the following grammar (AND is implicit between terms):
+//
+//	or      = and { "OR" and }
+//	and     = unary { [ "AND" ] unary }
+//	unary   = ( "NOT" | "-" ) unary | primary
+//	primary = "(" or ")" | phrase | word
+type queryParser struct {
+	tokens []queryToken
+	next   int
+}
+
+// parseQuery parses a query (e.g. `bank OR "current account" -business`).
+func parseQuery(text string) (query, error) {
+	tokens, err := lexQuery(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("empty query")
+	}
+
+	parser := &queryParser{tokens: tokens}
+	parsed, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if parser.next < len(parser.tokens) {
+		return nil, errors.New("unexpected ) in query: " + text)
+	}
+	return parsed, nil
+}
+
+func (parser *queryParser) peek() (queryToken, bool) {
+	if parser.next >= len(parser.tokens) {
+		return queryToken{}, false
+	}
+	return parser.tokens[parser.next], true
+}
+
+func (parser *queryParser) parseOr() (query, error) {
+	queries := []query{}
+	for {
+		q, err := parser.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, q)
+
+		if token, ok := parser.peek(); !ok || token.kind != tokenOr {
+			break
+		}
+		parser.next++
+	}
+
+	if len(queries) == 1 {
+		return queries[0], nil
+	}
+	return &orQuery{queries: queries}, nil
+}
+
+func (parser *queryParser) parseAnd() (query, error) {
+	queries := []query{}
+	for {
+		q, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, q)
+
+		token, ok := parser.peek()
+		if !ok || token.kind == tokenOr || token.kind == tokenClose {
+			break
+		}
+		if token.kind == tokenAnd {
+			parser.next++
+		}
+	}
+
+	if len(queries) == 1 {
+		return queries[0], nil
+	}
+	return &andQuery{queries: queries}, nil
+}
+
+func (parser *queryParser) parseUnary() (query, error) {
+	token, ok := parser.peek()
+	if ok && token.kind == tokenNot {
+		parser.next++
+		q, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notQuery{query: q}, nil
+	}
+	return parser.parsePrimary()
+}
+
+func (parser *queryParser) parsePrimary() (query, error) {
+	token, ok := parser.peek()
+	if !ok {
+		return nil, errors.New("unexpected end of query")
+	}
+	parser.next++
+
+	switch token.kind {
+	case tokenOpen:
+		q, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing, ok := parser.peek(); !ok || closing.kind != tokenClose {
+			return nil, errors.New("missing ) in query")
+		}
+		parser.next++
+		return q, nil
+	case tokenWord, tokenPhrase:
+		// Words with punctuation (e.g. "e-mail") are phrases of their terms:
+		terms := tokenize(token.text)
+		switch len(terms) {
+		case 0:
+			return nil, errors.New("query term without letters or digits: " + token.text)
+		case 1:
+			return &termQuery{term: terms[0]}, nil
+		default:
+			return &phraseQuery{terms: terms}, nil
+		}
+	default:
+		return nil, errors.New("unexpected operator in query")
+	}
+}
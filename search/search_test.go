@@ -0,0 +1,132 @@
+package search
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testIndex(t *testing.T) *Index {
+	dir, err := ioutil.TempDir("", "search")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Write the postings of every 2 documents to a segment:
+	defer func(size int) { segmentSize = size }(segmentSize)
+	segmentSize = 2
+
+	writer, err := NewWriter(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	pages := []struct{ url, title, text string }{
+		{"http://monzo.com/", "Monzo", "Monzo is a bank. Open a current account in minutes."},
+		{"http://monzo.com/business", "Business", "A business account for your company, with a bank account number."},
+		{"http://monzo.com/savings", "Savings", "Savings pots: put money aside. Account holders get interest."},
+		{"http://monzo.com/blog", "Blog", "News about the bank, the app and the community. Bank bank bank."},
+		{"http://monzo.com/help", "Help", "Lost your card? Freeze it in the app."},
+	}
+	for _, page := range pages {
+		if err := writer.Add(page.url, page.title, page.text); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	index, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return index
+}
+
+func resultURLs(results []Result) []string {
+	urls := []string{}
+	for _, result := range results {
+		urls = append(urls, result.URL)
+	}
+	return urls
+}
+
+func TestOpen(t *testing.T) {
+	index := testIndex(t)
+
+	if index.Len() != 5 {
+		t.Errorf("Number of documents was invalid. Expected: %d, Got: %d", 5, index.Len())
+	}
+
+	// "app" is in documents of different segments:
+	expected := []posting{{Doc: 3, Positions: []int{6}}, {Doc: 4, Positions: []int{8}}}
+	if !reflect.DeepEqual(index.postings["app"], expected) {
+		t.Errorf("Postings were invalid. Expected: %v, Got: %v", expected, index.postings["app"])
+	}
+}
+
+func TestIndex_Search(t *testing.T) {
+	index := testIndex(t)
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		// The blog mentions "bank" the most:
+		{"bank", []string{"http://monzo.com/blog", "http://monzo.com/", "http://monzo.com/business"}},
+		{"Bank account", []string{"http://monzo.com/business", "http://monzo.com/"}},
+		{"bank AND account", []string{"http://monzo.com/business", "http://monzo.com/"}},
+		{`"bank account"`, []string{"http://monzo.com/business"}},
+		{"account -business", []string{"http://monzo.com/", "http://monzo.com/savings"}},
+		{"account NOT (business OR savings)", []string{"http://monzo.com/"}},
+		{"card OR pots", []string{"http://monzo.com/savings", "http://monzo.com/help"}},
+		{"mortgage", []string{}},
+	}
+
+	for _, test := range tests {
+		results, err := index.Search(test.query, 0)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", test.query, err)
+			continue
+		}
+		if urls := resultURLs(results); !reflect.DeepEqual(urls, test.expected) {
+			t.Errorf("Results of %s were invalid. Expected: %v, Got: %v", test.query, test.expected, urls)
+		}
+	}
+
+	results, _ := index.Search("bank", 1)
+	if len(results) != 1 || results[0].Title != "Blog" {
+		t.Errorf("Results were not limited: %v", results)
+	}
+
+	expectedSnippet := "Lost your [card?] Freeze it in the app."
+	results, _ = index.Search("card", 0)
+	if len(results) != 1 || results[0].Snippet != expectedSnippet {
+		t.Errorf("Snippet was invalid. Expected: %s, Got: %v", expectedSnippet, results)
+	}
+}
+
+func TestIndex_Search_InvalidQuery(t *testing.T) {
+	index := testIndex(t)
+
+	for _, query := range []string{"", `"bank`, "(bank", "bank)", "bank OR", "NOT", "!!"} {
+		if _, err := index.Search(query, 0); err == nil {
+			t.Errorf("Query %q should be invalid", query)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := "one two three four five six seven eight nine ten eleven twelve thirteen fourteen fifteen sixteen " +
+		"seventeen eighteen nineteen twenty twenty-one twenty-two twenty-three twenty-four twenty-five twenty-six"
+
+	expected := "... four five six seven eight nine ten eleven [Twelve] thirteen fourteen fifteen sixteen seventeen eighteen " +
+		"nineteen twenty twenty-one twenty-two twenty-three twenty-four twenty-five twenty-six"
+	if got := snippet(strings.Replace(text, "twelve", "Twelve", 1), map[string]bool{"twelve": true}); got != expected {
+		t.Errorf("Snippet was invalid.\nExpected: %s\nGot: %s", expected, got)
+	}
+}
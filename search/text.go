@@ -0,0 +1,70 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// tokenize splits a text into its terms: lowercased sequences of letters and digits.
+func tokenize(text string) []string {
+	terms := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for i, term := range terms {
+		terms[i] = strings.ToLower(term)
+	}
+	return terms
+}
+
+// Number of words of the text shown before and after the first match in a snippet:
+const (
+	snippetWordsBefore = 8
+	snippetWordsAfter  = 16
+)
+
+// snippet returns an excerpt of a text around the first word that contains one of the terms,
+// with the words that contain any of them highlighted (e.g. "... the [bank] that ..."), or the beginning
+// of the text if none is found.
+func snippet(text string, terms map[string]bool) string {
+	words := strings.Fields(text)
+
+	matches := make([]bool, len(words))
+	first := -1
+	for i, word := range words {
+		for _, term := range tokenize(word) {
+			if terms[term] {
+				matches[i] = true
+			}
+		}
+		if matches[i] && first < 0 {
+			first = i
+		}
+	}
+
+	start, end := 0, snippetWordsBefore+snippetWordsAfter
+	if first >= 0 {
+		start, end = first-snippetWordsBefore, first+snippetWordsAfter
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end > len(words) {
+		end = len(words)
+	}
+
+	excerpt := []string{}
+	if start > 0 {
+		excerpt = append(excerpt, "...")
+	}
+	for i := start; i < end; i++ {
+		if matches[i] {
+			excerpt = append(excerpt, "["+words[i]+"]")
+		} else {
+			excerpt = append(excerpt, words[i])
+		}
+	}
+	if end < len(words) {
+		excerpt = append(excerpt, "...")
+	}
+	return strings.Join(excerpt, " ")
+}
@@ -0,0 +1,135 @@
+// Package search builds a full-text index of the pages crawled on disk and answers queries over it,
+// with phrases, boolean operators, ranked results and snippets.
+package search
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Names of the files of an index directory:
+const (
+	documentsFile = "documents.jsonl" // a JSON object per document, in the order of their ids
+	segmentFormat = "segment-%05d.gob"
+)
+
+// segmentSize is the number of documents whose postings are kept in memory before being written to a segment.
+var segmentSize = 1000
+
+// document is a page of the index.
+type document struct {
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+	Text  string `json:"text"`
+}
+
+// posting is an occurrence of a term in a document, along with its positions (in terms) in the text.
+type posting struct {
+	Doc       int
+	Positions []int
+}
+
+// segment maps every term to its postings, ordered by document.
+type segment map[string][]posting
+
+// Writer builds an index in a directory, while the documents are added: the documents are appended
+// to a file as soon as they're added and their postings are written to a new segment file every
+// segmentSize documents, so the memory used doesn't grow with the number of documents.
+// A Writer is safe to be used by a single goroutine.
+type Writer struct {
+	dir       string
+	documents *os.File
+	encoder   *json.Encoder
+
+	nDocuments int
+	nSegments  int
+	postings   segment
+}
+
+// NewWriter creates an index in a directory (created if needed), replacing any index it had.
+func NewWriter(dir string) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	segments, err := filepath.Glob(filepath.Join(dir, "segment-*.gob"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range segments {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	documents, err := os.Create(filepath.Join(dir, documentsFile))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{
+		dir:       dir,
+		documents: documents,
+		encoder:   json.NewEncoder(documents),
+		postings:  make(segment),
+	}, nil
+}
+
+// Add adds a page to the index, given its URL, title and visible text.
+func (writer *Writer) Add(url string, title string, text string) error {
+	terms := tokenize(title + " " + text)
+
+	if err := writer.encoder.Encode(document{URL: url, Title: title, Text: text}); err != nil {
+		return err
+	}
+
+	id := writer.nDocuments
+	writer.nDocuments++
+
+	for position, term := range terms {
+		postings := writer.postings[term]
+		if len(postings) == 0 || postings[len(postings)-1].Doc != id {
+			postings = append(postings, posting{Doc: id})
+		}
+		postings[len(postings)-1].Positions = append(postings[len(postings)-1].Positions, position)
+		writer.postings[term] = postings
+	}
+
+	if writer.nDocuments%segmentSize == 0 {
+		return writer.flush()
+	}
+	return nil
+}
+
+// flush writes the postings in memory to a new segment.
+func (writer *Writer) flush() error {
+	if len(writer.postings) == 0 {
+		return nil
+	}
+
+	f, err := os.Create(filepath.Join(writer.dir, fmt.Sprintf(segmentFormat, writer.nSegments)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := gob.NewEncoder(f).Encode(writer.postings); err != nil {
+		return err
+	}
+
+	writer.nSegments++
+	writer.postings = make(segment)
+	return nil
+}
+
+// Close writes the postings that are still in memory and closes the files of the index.
+func (writer *Writer) Close() error {
+	if err := writer.flush(); err != nil {
+		writer.documents.Close()
+		return err
+	}
+	return writer.documents.Close()
+}
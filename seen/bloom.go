@@ -0,0 +1,71 @@
+package seen
+
+import "math"
+
+// bloomFilter is a Set that sets k bits of a bit array for every URL, sized for a number of URLs and
+// a false positive rate: about 1.44*log2(1/rate) bits per URL (e.g. 1.8 bytes for a rate of 0.1%).
+// The false positive rate grows beyond the expected number of URLs.
+type bloomFilter struct {
+	bits []uint64
+	m    uint64 // number of bits
+	k    uint64 // number of bits set per URL
+	n    int
+}
+
+// NewBloomFilter returns a Set that is a Bloom filter for an expected number of URLs and false positive rate.
+func NewBloomFilter(expected int, falsePositiveRate float64) Set {
+	if expected < 1 {
+		expected = 1
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.001
+	}
+
+	// Optimal number of bits and of hash functions:
+	m := uint64(math.Ceil(-float64(expected) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint64(math.Max(1, math.Round(float64(m)/float64(expected)*math.Ln2)))
+
+	return &bloomFilter{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+func (filter *bloomFilter) Add(url string) bool {
+	h1, h2 := filter.hashes(url)
+
+	added := false
+	for i := uint64(0); i < filter.k; i++ {
+		bit := (h1 + i*h2) % filter.m
+		if filter.bits[bit/64]&(1<<(bit%64)) == 0 {
+			filter.bits[bit/64] |= 1 << (bit % 64)
+			added = true
+		}
+	}
+
+	if added {
+		filter.n++
+	}
+	return added
+}
+
+func (filter *bloomFilter) Contains(url string) bool {
+	h1, h2 := filter.hashes(url)
+	for i := uint64(0); i < filter.k; i++ {
+		bit := (h1 + i*h2) % filter.m
+		if filter.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (filter *bloomFilter) Len() int {
+	return filter.n
+}
+
+// hashes returns the two hashes from which the k bits of an URL are derived (double hashing).
+func (filter *bloomFilter) hashes(url string) (uint64, uint64) {
+	h1 := hash(url)
+	return h1, mix(h1^0x9e3779b97f4a7c15) | 1
+}
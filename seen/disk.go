@@ -0,0 +1,422 @@
+package seen
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Parameters of the files of a DiskSet:
+const (
+	blockSize = 1024 // number of hashes of a run per entry of its index (i.e. read to look an URL up)
+	mergeRuns = 4    // number of runs of the same level merged into a run of the next level
+
+	manifestFile = "seen.json" // runs of a set opened with OpenDiskSet
+)
+
+// DiskSet is a Set that keeps the hashes of the URLs on disk, so that its memory is bounded: the hashes
+// are added to a buffer in memory which, when full, is written to a file as a sorted run, with an index
+// of a hash of every block in memory (8 bytes per 1024 URLs). Looking an URL up reads a block of every run.
+//
+// The runs are merged by levels, so that every hash is only rewritten a logarithmic number of times: the runs
+// written from the buffer are of level 0, and every mergeRuns runs of the same level are merged into a run of
+// the next level. There are at most mergeRuns-1 runs of every level. Like the hash set, two URLs with the same
+// hash are a false positive.
+//
+// A set opened with OpenDiskSet is persistent: Checkpoint saves a manifest with its runs (the runs merged since
+// are only deleted when the next one is saved) and Close writes the buffer too, so that it can be opened again.
+//
+// I/O errors can't be returned by Add and Contains: the first one is returned by Err and Close.
+type DiskSet struct {
+	dir        string
+	buffer     map[uint64]struct{}
+	bufferSize int
+	runs       []*run
+	nextRun    int
+	n          int
+	err        error
+
+	persistent bool
+	obsolete   []string // files of the runs merged since the manifest was saved
+}
+
+// run is a file with sorted hashes (big endian uint64s).
+type run struct {
+	file  *os.File
+	n     int64
+	level int      // number of times its hashes were merged
+	index []uint64 // first hash of every block
+}
+
+// diskManifest is the manifest of a persistent DiskSet, saved to seen.json.
+type diskManifest struct {
+	NextRun int           `json:"next_run"`
+	Runs    []manifestRun `json:"runs"`
+}
+
+type manifestRun struct {
+	Name  string `json:"name"`
+	Level int    `json:"level"`
+}
+
+// NewDiskSet returns a DiskSet that writes its runs to a directory (created if needed), keeping up to
+// bufferSize hashes in memory.
+func NewDiskSet(dir string, bufferSize int) (*DiskSet, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	return &DiskSet{dir: dir, buffer: make(map[uint64]struct{}), bufferSize: bufferSize}, nil
+}
+
+// OpenDiskSet opens the persistent DiskSet of a directory, with the URLs it had when it was closed (or when
+// its manifest was saved last), or creates an empty one if the directory doesn't have a set.
+func OpenDiskSet(dir string, bufferSize int) (*DiskSet, error) {
+	set, err := NewDiskSet(dir, bufferSize)
+	if err != nil {
+		return nil, err
+	}
+	set.persistent = true
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	manifest := diskManifest{}
+	if err == nil {
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, err
+		}
+	}
+	set.nextRun = manifest.NextRun
+
+	listed := make(map[string]bool)
+	for _, entry := range manifest.Runs {
+		r, err := openRun(filepath.Join(dir, entry.Name), entry.Level)
+		if err != nil {
+			for _, opened := range set.runs {
+				opened.file.Close()
+			}
+			return nil, err
+		}
+		set.runs = append(set.runs, r)
+		set.n += int(r.n)
+		listed[entry.Name] = true
+	}
+
+	// The runs written since the manifest was saved aren't part of the set:
+	names, err := filepath.Glob(filepath.Join(dir, "seen-*.run"))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if !listed[filepath.Base(name)] {
+			os.Remove(name)
+		}
+	}
+	return set, nil
+}
+
+// Add adds an URL to the set, returning false if it was in the set already.
+func (set *DiskSet) Add(url string) bool {
+	h := hash(url)
+	if set.contains(h) {
+		return false
+	}
+
+	set.buffer[h] = struct{}{}
+	set.n++
+	if len(set.buffer) >= set.bufferSize {
+		set.flush()
+	}
+	return true
+}
+
+// Contains tells if an URL is in the set.
+func (set *DiskSet) Contains(url string) bool {
+	return set.contains(hash(url))
+}
+
+// Len returns the number of URLs added to the set.
+func (set *DiskSet) Len() int {
+	return set.n
+}
+
+// Dir returns the directory of the files of the set.
+func (set *DiskSet) Dir() string {
+	return set.dir
+}
+
+// Err returns the first I/O error found, if any.
+func (set *DiskSet) Err() error {
+	return set.err
+}
+
+// Checkpoint saves the manifest of a persistent set, so that it's opened again with the URLs written to its
+// runs (the ones in the buffer aren't saved), and deletes the runs merged since it was saved last.
+func (set *DiskSet) Checkpoint() error {
+	if !set.persistent {
+		return set.err
+	}
+
+	manifest := diskManifest{NextRun: set.nextRun, Runs: []manifestRun{}}
+	for _, r := range set.runs {
+		manifest.Runs = append(manifest.Runs, manifestRun{Name: filepath.Base(r.file.Name()), Level: r.level})
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(set.dir, manifestFile)
+	if err := ioutil.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+
+	for _, name := range set.obsolete {
+		set.fail(os.Remove(name))
+	}
+	set.obsolete = nil
+	return set.err
+}
+
+// Close closes the files of the set, returning the first I/O error found, if any. The files of a persistent
+// set are kept, with the buffer written to a run and the manifest saved, and the ones of the others removed.
+func (set *DiskSet) Close() error {
+	if set.persistent {
+		if len(set.buffer) > 0 {
+			set.flush()
+		}
+		set.fail(set.Checkpoint())
+	}
+
+	for _, r := range set.runs {
+		if set.persistent {
+			r.file.Close()
+		} else {
+			set.fail(r.remove())
+		}
+	}
+	set.runs = nil
+	return set.err
+}
+
+func (set *DiskSet) contains(h uint64) bool {
+	if _, ok := set.buffer[h]; ok {
+		return true
+	}
+	for _, r := range set.runs {
+		found, err := r.contains(h)
+		set.fail(err)
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// flush writes the buffer to a new run of level 0, merging the runs of the levels that are full.
+func (set *DiskSet) flush() {
+	hashes := make([]uint64, 0, len(set.buffer))
+	for h := range set.buffer {
+		hashes = append(hashes, h)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+
+	r, err := set.writeRun(func(w *runWriter) error {
+		for _, h := range hashes {
+			if err := w.write(h); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if set.fail(err) {
+		return
+	}
+	set.buffer = make(map[uint64]struct{})
+	set.runs = append(set.runs, r)
+
+	// The runs are in decreasing order of level, so the ones of the level of the new run are the last ones:
+	for len(set.runs) >= mergeRuns {
+		last := set.runs[len(set.runs)-mergeRuns:]
+		if last[0].level != last[len(last)-1].level || !set.merge(len(set.runs)-mergeRuns) {
+			return
+		}
+	}
+}
+
+// merge merges the runs from an index into a single run of the next level, returning false if it failed.
+func (set *DiskSet) merge(from int) bool {
+	runs := set.runs[from:]
+	cursors := make([]*cursor, len(runs))
+	for i, r := range runs {
+		cursors[i] = &cursor{reader: bufio.NewReader(io.NewSectionReader(r.file, 0, r.n*8)), remaining: r.n}
+		if set.fail(cursors[i].next()) {
+			return false
+		}
+	}
+
+	merged, err := set.writeRun(func(w *runWriter) error {
+		for {
+			// The smallest hash of the runs (a hash is never in several runs):
+			var min *cursor
+			for _, c := range cursors {
+				if c.valid && (min == nil || c.head < min.head) {
+					min = c
+				}
+			}
+			if min == nil {
+				return nil
+			}
+
+			if err := w.write(min.head); err != nil {
+				return err
+			}
+			if err := min.next(); err != nil {
+				return err
+			}
+		}
+	})
+	if set.fail(err) {
+		return false
+	}
+	merged.level = runs[0].level + 1
+
+	// The runs of a persistent set are listed in its manifest until it's saved again:
+	for _, r := range runs {
+		if set.persistent {
+			r.file.Close()
+			set.obsolete = append(set.obsolete, r.file.Name())
+		} else {
+			set.fail(r.remove())
+		}
+	}
+	set.runs = append(set.runs[:from], merged)
+	return true
+}
+
+// cursor reads the hashes of a run in order.
+type cursor struct {
+	reader    *bufio.Reader
+	remaining int64
+	head      uint64 // hash read last
+	valid     bool   // false when all the hashes were read
+}
+
+func (c *cursor) next() error {
+	if c.remaining == 0 {
+		c.valid = false
+		return nil
+	}
+	c.remaining--
+	err := binary.Read(c.reader, binary.BigEndian, &c.head)
+	c.valid = err == nil
+	return err
+}
+
+// fail records an error, if it's the first one, and tells if there was an error.
+func (set *DiskSet) fail(err error) bool {
+	if err != nil && set.err == nil {
+		set.err = err
+	}
+	return err != nil
+}
+
+// writeRun writes a new run with the hashes written by a function, in ascending order.
+func (set *DiskSet) writeRun(write func(w *runWriter) error) (*run, error) {
+	set.nextRun++
+	file, err := os.Create(filepath.Join(set.dir, fmt.Sprintf("seen-%05d.run", set.nextRun)))
+	if err != nil {
+		return nil, err
+	}
+
+	r := &run{file: file}
+	w := &runWriter{run: r, writer: bufio.NewWriter(file)}
+	if err := write(w); err != nil {
+		r.remove()
+		return nil, err
+	}
+	if err := w.writer.Flush(); err != nil {
+		r.remove()
+		return nil, err
+	}
+	return r, nil
+}
+
+// runWriter writes the hashes of a run, building its index.
+type runWriter struct {
+	run    *run
+	writer *bufio.Writer
+}
+
+func (w *runWriter) write(h uint64) error {
+	if w.run.n%blockSize == 0 {
+		w.run.index = append(w.run.index, h)
+	}
+	w.run.n++
+	return binary.Write(w.writer, binary.BigEndian, h)
+}
+
+// contains tells if the run has a hash, reading the block in which it would be.
+func (r *run) contains(h uint64) (bool, error) {
+	block := sort.Search(len(r.index), func(i int) bool { return r.index[i] > h }) - 1
+	if block < 0 {
+		return false, nil
+	}
+
+	start := int64(block) * blockSize
+	count := r.n - start
+	if count > blockSize {
+		count = blockSize
+	}
+
+	buf := make([]byte, count*8)
+	if _, err := r.file.ReadAt(buf, start*8); err != nil {
+		return false, err
+	}
+
+	i := sort.Search(int(count), func(i int) bool { return binary.BigEndian.Uint64(buf[i*8:]) >= h })
+	return i < int(count) && binary.BigEndian.Uint64(buf[i*8:]) == h, nil
+}
+
+// openRun opens a run written before, reading its index.
+func openRun(path string, level int) (*run, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	r := &run{file: file, n: info.Size() / 8, level: level}
+	buf := make([]byte, 8)
+	for start := int64(0); start < r.n; start += blockSize {
+		if _, err := file.ReadAt(buf, start*8); err != nil {
+			file.Close()
+			return nil, err
+		}
+		r.index = append(r.index, binary.BigEndian.Uint64(buf))
+	}
+	return r, nil
+}
+
+// remove closes and deletes the file of the run.
+func (r *run) remove() error {
+	r.file.Close()
+	return os.Remove(r.file.Name())
+}
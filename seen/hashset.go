@@ -0,0 +1,80 @@
+package seen
+
+// hashSet is a Set that keeps the 64-bit hashes of the URLs in an open addressing hash table, using about
+// 8 to 16 bytes per URL. Two URLs with the same hash are a false positive, which is very unlikely
+// (about n²/2^65 for n URLs, i.e. 1 in 37 million for 1 million URLs).
+type hashSet struct {
+	table []uint64 // 0 marks an empty slot, so the hash 0 is stored as 1
+	n     int
+}
+
+// initialHashSetSize is the initial number of slots of the table (a power of 2).
+const initialHashSetSize = 1024
+
+// NewHashSet returns a Set that keeps the hashes of the URLs in a compact hash table.
+func NewHashSet() Set {
+	return &hashSet{table: make([]uint64, initialHashSetSize)}
+}
+
+func (set *hashSet) Add(url string) bool {
+	// Grow the table when it's 3/4 full:
+	if 4*(set.n+1) > 3*len(set.table) {
+		set.grow()
+	}
+
+	if !set.insert(hashKey(url)) {
+		return false
+	}
+	set.n++
+	return true
+}
+
+func (set *hashSet) Contains(url string) bool {
+	h := hashKey(url)
+	mask := uint64(len(set.table) - 1)
+	for i := h & mask; ; i = (i + 1) & mask {
+		switch set.table[i] {
+		case 0:
+			return false
+		case h:
+			return true
+		}
+	}
+}
+
+func (set *hashSet) Len() int {
+	return set.n
+}
+
+// insert adds a hash to the table (with linear probing), returning false if it was in it already.
+func (set *hashSet) insert(h uint64) bool {
+	mask := uint64(len(set.table) - 1)
+	for i := h & mask; ; i = (i + 1) & mask {
+		switch set.table[i] {
+		case 0:
+			set.table[i] = h
+			return true
+		case h:
+			return false
+		}
+	}
+}
+
+// grow doubles the size of the table, inserting its hashes again.
+func (set *hashSet) grow() {
+	old := set.table
+	set.table = make([]uint64, 2*len(old))
+	for _, h := range old {
+		if h != 0 {
+			set.insert(h)
+		}
+	}
+}
+
+// hashKey returns the hash of an URL stored in the tables, which is never 0.
+func hashKey(url string) uint64 {
+	if h := hash(url); h != 0 {
+		return h
+	}
+	return 1
+}
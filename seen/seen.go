@@ -0,0 +1,59 @@
+// Package seen implements sets of the URLs seen by the crawler, from an exact map of the URLs to compact
+// and probabilistic sets of their hashes, and a set kept on disk for crawls that don't fit in memory.
+package seen
+
+// Set is a set of URLs. The sets of hashes may have false positives (an URL not added being reported
+// as seen), with a probability that depends on the implementation.
+type Set interface {
+	// Add adds an URL to the set, returning false if it was (or seems to be) in the set already.
+	Add(url string) bool
+	// Contains tells if an URL is (or seems to be) in the set.
+	Contains(url string) bool
+	// Len returns the number of URLs added to the set (not counting the ones that were in it already).
+	Len() int
+}
+
+// mapSet is a Set that keeps the URLs themselves, without false positives.
+type mapSet map[string]struct{}
+
+// NewMap returns a Set that keeps the URLs in a map (exact, but the one that uses the most memory).
+func NewMap() Set {
+	return mapSet(make(map[string]struct{}))
+}
+
+func (set mapSet) Add(url string) bool {
+	if _, ok := set[url]; ok {
+		return false
+	}
+	set[url] = struct{}{}
+	return true
+}
+
+func (set mapSet) Contains(url string) bool {
+	_, ok := set[url]
+	return ok
+}
+
+func (set mapSet) Len() int {
+	return len(set)
+}
+
+// hash returns the 64-bit FNV-1a hash of an URL, mixed so that all its bits are evenly distributed.
+func hash(url string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(url); i++ {
+		h ^= uint64(url[i])
+		h *= 1099511628211
+	}
+	return mix(h)
+}
+
+// mix is the finalizer of SplitMix64, which spreads the bits of a hash.
+func mix(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
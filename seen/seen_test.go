@@ -0,0 +1,203 @@
+package seen
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func testURL(i int) string {
+	return "https://monzo.com/blog/" + strconv.Itoa(i) + "/a-post-about-money"
+}
+
+func TestSets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seen")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	disk, err := NewDiskSet(dir, 100)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	sets := map[string]Set{
+		"map":   NewMap(),
+		"hash":  NewHashSet(),
+		"bloom": NewBloomFilter(100000, 0.0001),
+		"disk":  disk,
+	}
+
+	for name, set := range sets {
+		for i := 0; i < 5000; i++ {
+			if !set.Add(testURL(i)) {
+				t.Errorf("%s: URL %d was already in the set", name, i)
+			}
+		}
+		for i := 0; i < 5000; i++ {
+			if set.Add(testURL(i)) || !set.Contains(testURL(i)) {
+				t.Errorf("%s: URL %d was not in the set", name, i)
+			}
+		}
+		if set.Contains(testURL(5000)) {
+			t.Errorf("%s: URL not added is in the set", name)
+		}
+		if set.Len() != 5000 {
+			t.Errorf("%s: Length of the set was invalid. Expected: %d, Got: %d", name, 5000, set.Len())
+		}
+	}
+
+	// The runs were merged by levels (50 runs of 100 URLs are 3 runs of 1600 URLs and 2 runs of 100 URLs),
+	// and they're removed when closed:
+	levels := []int{}
+	for _, r := range disk.runs {
+		levels = append(levels, r.level)
+	}
+	if expected := []int{2, 2, 2, 0, 0}; !reflect.DeepEqual(levels, expected) {
+		t.Errorf("Levels of the runs were invalid. Expected: %v, Got: %v", expected, levels)
+	}
+	if err := disk.Close(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("Files of the disk set were not removed: %d", len(files))
+	}
+}
+
+func TestDiskSet_Reopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seen")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A set killed after a checkpoint has the URLs written to its runs then (not the ones in its buffer):
+	killed, err := OpenDiskSet(dir, 100)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 550; i++ {
+		killed.Add(testURL(i))
+	}
+	if err := killed.Checkpoint(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 550; i < 1000; i++ {
+		killed.Add(testURL(i))
+	}
+
+	set, err := OpenDiskSet(dir, 100)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if set.Len() != 500 || !set.Contains(testURL(499)) || set.Contains(testURL(500)) {
+		t.Errorf("Set opened after a checkpoint was invalid. Expected: %d URLs, Got: %d", 500, set.Len())
+	}
+
+	// A set closed has all its URLs:
+	for i := 500; i < 1000; i++ {
+		if !set.Add(testURL(i)) {
+			t.Errorf("URL %d was already in the set", i)
+		}
+	}
+	if err := set.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	set, err = OpenDiskSet(dir, 100)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer set.Close()
+	for i := 0; i < 1000; i++ {
+		if !set.Contains(testURL(i)) {
+			t.Errorf("URL %d was not in the set opened again", i)
+		}
+	}
+	if set.Len() != 1000 {
+		t.Errorf("Length of the set was invalid. Expected: %d, Got: %d", 1000, set.Len())
+	}
+}
+
+func TestBloomFilter_FalsePositiveRate(t *testing.T) {
+	filter := NewBloomFilter(10000, 0.01)
+	for i := 0; i < 10000; i++ {
+		filter.Add(testURL(i))
+	}
+
+	falsePositives := 0
+	for i := 10000; i < 110000; i++ {
+		if filter.Contains(testURL(i)) {
+			falsePositives++
+		}
+	}
+
+	// About 1000 are expected:
+	if falsePositives < 500 || falsePositives > 1500 {
+		t.Errorf("Invalid number of false positives. Expected: about %d, Got: %d", 1000, falsePositives)
+	}
+}
+
+// benchmarkSet adds URLs to a set, reporting the memory used per URL.
+func benchmarkSet(b *testing.B, newSet func() Set) {
+	const nURLs = 200000
+	urls := make([]string, nURLs)
+	for i := range urls {
+		urls[i] = testURL(i)
+	}
+
+	var bytesPerURL float64
+	for n := 0; n < b.N; n++ {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		set := newSet()
+		for _, url := range urls {
+			set.Add(url)
+		}
+
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		bytesPerURL = float64(int64(after.HeapAlloc)-int64(before.HeapAlloc)) / nURLs
+		runtime.KeepAlive(set)
+	}
+	b.ReportMetric(bytesPerURL, "bytes/url")
+}
+
+func BenchmarkSet_Map(b *testing.B) {
+	benchmarkSet(b, NewMap)
+}
+
+func BenchmarkSet_HashSet(b *testing.B) {
+	benchmarkSet(b, NewHashSet)
+}
+
+func BenchmarkSet_BloomFilter(b *testing.B) {
+	benchmarkSet(b, func() Set { return NewBloomFilter(200000, 0.001) })
+}
+
+func BenchmarkSet_DiskSet(b *testing.B) {
+	dir, err := ioutil.TempDir("", "seen")
+	if err != nil {
+		b.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var sets []*DiskSet
+	benchmarkSet(b, func() Set {
+		set, err := NewDiskSet(dir, 10000)
+		if err != nil {
+			b.Fatalf("Unexpected error: %v", err)
+		}
+		sets = append(sets, set)
+		return set
+	})
+	for _, set := range sets {
+		set.Close()
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/msandim/web-crawler/service"
+)
+
+// runServe runs crawls as a service, started and followed through a REST API, until the program is stopped.
+// It returns the exit code of the program.
+func runServe(arguments []string) int {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := flags.String("listen", ":8070", "address on which the API is served")
+	retain := flags.Int("retain", 0, "number of crawls that ended kept with their results, removing the oldest ones (all if 0)")
+	metricsAddr := flags.String("metrics-addr", "", "address on which the metrics of the crawls are served at /metrics, in the Prometheus text format (e.g. :9090)")
+	flags.Parse(arguments)
+
+	if *retain < 0 {
+		fmt.Fprintln(os.Stderr, "main::runServe() - Error: Number of crawls kept is invalid: ", *retain)
+		return -1
+	}
+	serveMetrics(*metricsAddr)
+
+	fmt.Println("Serving the API on ", *listen)
+	if err := http.ListenAndServe(*listen, service.NewWithRetention(*retain)); err != nil {
+		fmt.Fprintln(os.Stderr, "main::runServe() - Error: failed to serve the API: ", *listen, err)
+		return -1
+	}
+	return 0
+}
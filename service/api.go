@@ -0,0 +1,184 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/msandim/web-crawler/report"
+)
+
+// Format is a format in which the results of a crawl can be downloaded.
+type Format struct {
+	ContentType string
+	write       func(crawl *Crawl, w io.Writer) error
+}
+
+// Formats are the formats in which the results of a crawl can be downloaded, by name (the values of the
+// format parameter). They're the ones of the outputs of the crawl command.
+var Formats = map[string]Format{
+	"sitemap": {"text/plain; charset=utf-8", func(crawl *Crawl, w io.Writer) error {
+		_, err := w.Write(crawl.sitemap.Bytes())
+		return err
+	}},
+	"json": {"application/json", func(crawl *Crawl, w io.Writer) error {
+		return crawl.crawler.Graph().WriteJSON(w)
+	}},
+	"dot": {"text/vnd.graphviz", func(crawl *Crawl, w io.Writer) error {
+		return crawl.crawler.Graph().WriteDOT(w, 0)
+	}},
+	"graphml": {"application/xml", func(crawl *Crawl, w io.Writer) error {
+		return crawl.crawler.Graph().WriteGraphML(w)
+	}},
+	"gexf": {"application/xml", func(crawl *Crawl, w io.Writer) error {
+		return crawl.crawler.Graph().WriteGEXF(w)
+	}},
+	"edges.csv": {"text/csv", func(crawl *Crawl, w io.Writer) error {
+		return crawl.crawler.Graph().WriteEdgesCSV(w)
+	}},
+	"pages.csv": {"text/csv", func(crawl *Crawl, w io.Writer) error {
+		return crawl.crawler.Graph().WritePagesCSV(w)
+	}},
+	"report": {"text/html; charset=utf-8", func(crawl *Crawl, w io.Writer) error {
+		return report.Write(w, crawl.crawler.Graph(), crawl.crawler.Issues())
+	}},
+	"structureddata": {"application/x-ndjson", func(crawl *Crawl, w io.Writer) error {
+		return crawl.crawler.Graph().WriteStructuredData(w)
+	}},
+	"brokenlinks": {"text/plain; charset=utf-8", func(crawl *Crawl, w io.Writer) error {
+		_, err := report.WriteBrokenLinks(w, crawl.crawler.Graph())
+		return err
+	}},
+}
+
+// ServeHTTP answers the requests to the API of the service.
+func (service *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if segments[0] != "crawls" || len(segments) > 3 {
+		http.NotFound(w, r)
+		return
+	}
+
+	// /crawls:
+	if len(segments) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			statuses := []Status{}
+			for _, crawl := range service.Crawls() {
+				statuses = append(statuses, crawl.Status())
+			}
+			writeJSON(w, http.StatusOK, statuses)
+		case http.MethodPost:
+			service.start(w, r)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed: "+r.Method)
+		}
+		return
+	}
+
+	crawl, ok := service.Crawl(segments[1])
+	if !ok {
+		writeError(w, http.StatusNotFound, "crawl not found: "+segments[1])
+		return
+	}
+
+	// /crawls/{id}:
+	if len(segments) == 2 {
+		switch r.Method {
+		case http.MethodGet:
+			status := crawl.Status()
+			status.LatestErrors = crawl.errors.latest()
+			writeJSON(w, http.StatusOK, status)
+		case http.MethodDelete:
+			if err := service.Delete(crawl); err != nil {
+				writeError(w, http.StatusConflict, err.Error())
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed: "+r.Method)
+		}
+		return
+	}
+
+	// /crawls/{id}/{action}:
+	if segments[2] == "results" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed: "+r.Method)
+			return
+		}
+		writeResults(w, crawl, r.URL.Query().Get("format"))
+		return
+	}
+
+	actions := map[string]func(){"pause": crawl.Pause, "resume": crawl.Resume, "cancel": crawl.Cancel}
+	action, ok := actions[segments[2]]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed: "+r.Method)
+		return
+	}
+	action()
+	writeJSON(w, http.StatusOK, crawl.Status())
+}
+
+// start starts a crawl with the configuration of a request (the settings not given take their default values).
+func (service *Service) start(w http.ResponseWriter, r *http.Request) {
+	config := DefaultConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid configuration: "+err.Error())
+		return
+	}
+
+	crawl, err := service.Start(config)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.Header().Set("Location", "/crawls/"+crawl.ID)
+	writeJSON(w, http.StatusCreated, crawl.Status())
+}
+
+// writeResults writes the results of a crawl that ended in a format (json if not given).
+func writeResults(w http.ResponseWriter, crawl *Crawl, name string) {
+	if name == "" {
+		name = "json"
+	}
+	format, ok := Formats[name]
+	if !ok {
+		names := []string{}
+		for formatName := range Formats {
+			names = append(names, formatName)
+		}
+		sort.Strings(names)
+		writeError(w, http.StatusBadRequest, "invalid format: "+name+" (formats: "+strings.Join(names, ", ")+")")
+		return
+	}
+	if !crawl.ended() {
+		writeError(w, http.StatusConflict, errCrawlRunning.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType)
+	format.write(crawl, w)
+}
+
+// writeJSON writes a value as the JSON body of a response.
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error as the JSON body of a response.
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	if !strings.Contains(message, " - Error: ") {
+		message = "Service::ServeHTTP() - Error: " + message
+	}
+	writeJSON(w, statusCode, map[string]string{"error": message})
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/msandim/web-crawler/crawler"
+	"github.com/msandim/web-crawler/traps"
+)
+
+// Config is the configuration of a crawl, with the same settings (and names) as the flags of the crawl command.
+type Config struct {
+	Domain            string `json:"domain"`
+	Workers           int    `json:"nworkers"`
+	RateLimit         int    `json:"ratelimit"`
+	TimeoutSeconds    int    `json:"timeoutseconds"`
+	External          bool   `json:"external"` // check (without crawling them) the links to other domains
+	DuplicateDistance int    `json:"duplicatedistance"`
+	SkipDuplicates    bool   `json:"skipduplicates"`
+	Robots            string `json:"robots"` // ignore, annotate or obey
+	CanonicalDedupe   bool   `json:"canonicaldedupe"`
+	Canonicals        bool   `json:"canonicals"`
+	SEO               bool   `json:"seo"`
+	Accessibility     bool   `json:"accessibility"`
+	Security          bool   `json:"security"`
+	Traps             bool   `json:"traps"`
+	TrapBudget        int    `json:"trapbudget"`
+}
+
+// DefaultConfig is the configuration of the settings of a crawl that aren't given, the same as the defaults of the flags.
+var DefaultConfig = Config{
+	Workers:           4,
+	RateLimit:         4,
+	TimeoutSeconds:    10,
+	DuplicateDistance: 3,
+	Robots:            "ignore",
+	TrapBudget:        traps.DefaultLimits.MaxPerPattern,
+}
+
+// validate returns an error if a setting of the configuration is invalid.
+func (config *Config) validate() error {
+	domain, err := url.Parse(config.Domain)
+	if err != nil || domain.Scheme == "" || domain.Hostname() == "" {
+		return errors.New("Config::validate() - Error: Domain is invalid: " + config.Domain)
+	}
+	if config.Workers <= 0 {
+		return errors.New("Config::validate() - Error: Number of workers is invalid")
+	}
+	if config.RateLimit <= 0 {
+		return errors.New("Config::validate() - Error: Rate limit is invalid")
+	}
+	if config.TimeoutSeconds <= 0 {
+		return errors.New("Config::validate() - Error: Timeout (seconds) is invalid")
+	}
+	if _, ok := crawler.RobotsPolicies[config.Robots]; !ok {
+		return errors.New("Config::validate() - Error: Robots policy is invalid: " + config.Robots)
+	}
+	if config.TrapBudget <= 0 {
+		return errors.New("Config::validate() - Error: Trap budget is invalid")
+	}
+	return nil
+}
+
+// options returns the optional settings of the crawler given by the configuration.
+func (config *Config) options() crawler.Options {
+	var detector *traps.Detector
+	if config.Traps {
+		limits := traps.DefaultLimits
+		limits.MaxPerPattern = config.TrapBudget
+		detector = traps.New(limits)
+	}
+
+	return crawler.Options{
+		CheckExternal:     config.External,
+		DuplicateDistance: config.DuplicateDistance,
+		SkipDuplicates:    config.SkipDuplicates,
+		RobotsPolicy:      crawler.RobotsPolicies[config.Robots],
+		DedupeCanonical:   config.CanonicalDedupe,
+		CanonicalAudit:    config.Canonicals,
+		SEOAudit:          config.SEO,
+		Accessibility:     config.Accessibility,
+		Security:          config.Security,
+		Traps:             detector,
+	}
+}
@@ -0,0 +1,280 @@
+// Package service runs crawls as a service: crawls are started with a configuration, followed, paused,
+// resumed or cancelled while they run and their results downloaded when they end, through a REST API:
+//
+//	GET    /crawls                       lists the crawls (their statuses)
+//	POST   /crawls                       starts a crawl with a Config, returning its status
+//	GET    /crawls/{id}                  returns the status of a crawl, with its latest errors
+//	DELETE /crawls/{id}                  removes a crawl that ended, with its results
+//	POST   /crawls/{id}/pause            pauses a crawl (the URLs being crawled are finished)
+//	POST   /crawls/{id}/resume           resumes a crawl paused
+//	POST   /crawls/{id}/cancel           cancels a crawl, which ends with the pages crawled so far
+//	GET    /crawls/{id}/results?format=  downloads the results of a crawl that ended, in a format (see Formats)
+//
+// Every crawl runs its own crawler.Crawler, with its own fetcher, output and errors. The crawls that ended
+// are kept, with their results, until they're removed (or, with NewWithRetention, until too many ended).
+package service
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/msandim/web-crawler/crawler"
+)
+
+// States of the crawls:
+const (
+	StateRunning    = "running"
+	StatePaused     = "paused"
+	StateCancelling = "cancelling" // the URLs being crawled when it was cancelled are being finished
+	StateCancelled  = "cancelled"
+	StateFinished   = "finished"
+)
+
+// maxErrors is the number of latest errors of a crawl kept to be shown in its status.
+const maxErrors = 100
+
+// Service runs the crawls started through its API. It is safe for concurrent use.
+type Service struct {
+	mutex    sync.Mutex
+	crawls   map[string]*Crawl // by ID
+	ids      []string          // IDs of the crawls, in the order in which they were started
+	nextID   int
+	maxEnded int // number of crawls that ended kept (all if 0)
+}
+
+// Crawl is a crawl run by the service.
+type Crawl struct {
+	ID      string
+	Config  Config
+	Started time.Time
+
+	crawler *crawler.Crawler
+	sitemap bytes.Buffer // printed by the crawler, only read when the crawl ends
+	errors  *errorLog
+	done    chan struct{}
+
+	mutex    sync.Mutex
+	finished time.Time // zero while the crawl runs
+}
+
+// Status is the status of a crawl, with its progress.
+type Status struct {
+	ID       string     `json:"id"`
+	Domain   string     `json:"domain"`
+	State    string     `json:"state"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Crawled  int        `json:"crawled"`   // pages crawled
+	Queued   int        `json:"queued"`    // URLs waiting to be crawled
+	InFlight int        `json:"in_flight"` // URLs being crawled
+	Errors   int        `json:"errors"`    // errors found while crawling (e.g. pages that failed to be fetched)
+
+	LatestErrors []string `json:"latest_errors,omitempty"` // up to the last 100 errors and warnings
+}
+
+// New creates a service without crawls, which keeps all the crawls that ended until they're removed.
+func New() *Service {
+	return NewWithRetention(0)
+}
+
+// NewWithRetention creates a service without crawls, which keeps up to maxEnded crawls that ended (all if 0):
+// when one more ends, the oldest one is removed.
+func NewWithRetention(maxEnded int) *Service {
+	return &Service{crawls: make(map[string]*Crawl), maxEnded: maxEnded}
+}
+
+// Start starts a crawl with a configuration, returning an error if it's invalid.
+func (service *Service) Start(config Config) (*Crawl, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	crawl := &Crawl{Config: config, Started: time.Now(), errors: &errorLog{}, done: make(chan struct{})}
+	options := config.options()
+	options.Output = &crawl.sitemap
+	options.Errors = crawl.errors
+	crawl.crawler = crawler.NewWithOptions(config.Workers, config.RateLimit, config.TimeoutSeconds, config.Domain, options)
+
+	service.mutex.Lock()
+	service.nextID++
+	crawl.ID = strconv.Itoa(service.nextID)
+	service.crawls[crawl.ID] = crawl
+	service.ids = append(service.ids, crawl.ID)
+	service.mutex.Unlock()
+
+	go func() {
+		crawl.crawler.Run()
+		crawl.mutex.Lock()
+		crawl.finished = time.Now()
+		crawl.mutex.Unlock()
+		service.retain()
+		close(crawl.done)
+	}()
+	return crawl, nil
+}
+
+// Crawl returns the crawl with an ID, or false if there isn't one.
+func (service *Service) Crawl(id string) (*Crawl, bool) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	crawl, ok := service.crawls[id]
+	return crawl, ok
+}
+
+// Crawls returns the crawls, in the order in which they were started.
+func (service *Service) Crawls() []*Crawl {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	crawls := make([]*Crawl, len(service.ids))
+	for i, id := range service.ids {
+		crawls[i] = service.crawls[id]
+	}
+	return crawls
+}
+
+// Delete removes a crawl that ended, with its results. It returns an error if it didn't end yet.
+func (service *Service) Delete(crawl *Crawl) error {
+	if !crawl.ended() {
+		return errCrawlNotEnded
+	}
+
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	service.remove(crawl.ID)
+	return nil
+}
+
+// retain removes the oldest crawls that ended, if more than maxEnded ended.
+func (service *Service) retain() {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	if service.maxEnded == 0 {
+		return
+	}
+	ended := []string{}
+	for _, id := range service.ids {
+		if !service.crawls[id].finishedAt().IsZero() {
+			ended = append(ended, id)
+		}
+	}
+	for len(ended) > service.maxEnded {
+		service.remove(ended[0])
+		ended = ended[1:]
+	}
+}
+
+// remove removes a crawl from the service.
+func (service *Service) remove(id string) {
+	delete(service.crawls, id)
+	for i, crawlID := range service.ids {
+		if crawlID == id {
+			service.ids = append(service.ids[:i], service.ids[i+1:]...)
+			return
+		}
+	}
+}
+
+// Status returns the status of the crawl.
+func (crawl *Crawl) Status() Status {
+	progress := crawl.crawler.Progress()
+	status := Status{
+		ID:       crawl.ID,
+		Domain:   crawl.Config.Domain,
+		State:    StateRunning,
+		Started:  crawl.Started,
+		Crawled:  progress.Crawled,
+		Queued:   progress.Queued,
+		InFlight: progress.InFlight,
+		Errors:   progress.Errors,
+	}
+
+	finished := crawl.finishedAt()
+	switch {
+	case !finished.IsZero() && progress.Cancelled:
+		status.State = StateCancelled
+	case !finished.IsZero():
+		status.State = StateFinished
+	case progress.Cancelled:
+		status.State = StateCancelling
+	case progress.Paused:
+		status.State = StatePaused
+	}
+	if !finished.IsZero() {
+		status.Finished = &finished
+	}
+	return status
+}
+
+// Pause pauses the crawl, if it's running.
+func (crawl *Crawl) Pause() {
+	crawl.crawler.Pause()
+}
+
+// Resume resumes the crawl, if it's paused.
+func (crawl *Crawl) Resume() {
+	crawl.crawler.Resume()
+}
+
+// Cancel cancels the crawl, which ends with the pages crawled so far.
+func (crawl *Crawl) Cancel() {
+	crawl.crawler.Cancel()
+}
+
+// Wait waits until the crawl ends.
+func (crawl *Crawl) Wait() {
+	<-crawl.done
+}
+
+// finishedAt returns the time at which the crawl ended (zero while it runs).
+func (crawl *Crawl) finishedAt() time.Time {
+	crawl.mutex.Lock()
+	defer crawl.mutex.Unlock()
+	return crawl.finished
+}
+
+// ended returns whether the crawl ended, so that its results can be read.
+func (crawl *Crawl) ended() bool {
+	select {
+	case <-crawl.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// errorLog keeps the latest errors printed by a crawler (one per write), which may be printed concurrently.
+type errorLog struct {
+	mutex sync.Mutex
+	lines []string
+}
+
+func (log *errorLog) Write(p []byte) (int, error) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	log.lines = append(log.lines, strings.TrimRight(string(p), "\n"))
+	if len(log.lines) > maxErrors {
+		log.lines = append([]string(nil), log.lines[len(log.lines)-maxErrors:]...)
+	}
+	return len(p), nil
+}
+
+// latest returns the latest errors printed.
+func (log *errorLog) latest() []string {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	return append([]string(nil), log.lines...)
+}
+
+// errCrawlRunning is returned when the results of a crawl are read before it ends.
+var errCrawlRunning = errors.New("Crawl::results() - Error: the crawl didn't end yet")
+
+// errCrawlNotEnded is returned when a crawl is removed before it ends.
+var errCrawlNotEnded = errors.New("Service::Delete() - Error: the crawl didn't end yet (it has to be cancelled first)")
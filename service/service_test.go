@@ -0,0 +1,206 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/msandim/web-crawler/graph"
+	"github.com/msandim/web-crawler/sitegen"
+)
+
+// request sends a request to the API and decodes its JSON response, returning its status code.
+func request(t *testing.T, method string, url string, body string, response interface{}) int {
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if response != nil {
+		if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+			t.Fatalf("Invalid response to %s %s: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestService_API(t *testing.T) {
+	site, truth := sitegen.NewServer(sitegen.Spec{Pages: 100, FanOut: 4, Seed: 2, NotFound: 0.1})
+	defer site.Close()
+
+	service := New()
+	api := httptest.NewServer(service)
+	defer api.Close()
+
+	// Start a crawl:
+	status := Status{}
+	config := `{"domain": "` + sitegen.URL(site, "/") + `", "nworkers": 8, "ratelimit": 8}`
+	if code := request(t, "POST", api.URL+"/crawls", config, &status); code != http.StatusCreated || status.ID != "1" {
+		t.Fatalf("Invalid response to the start of the crawl: %d %+v", code, status)
+	}
+
+	crawl, _ := service.Crawl("1")
+	if crawl.Config.TimeoutSeconds != DefaultConfig.TimeoutSeconds || crawl.Config.Workers != 8 {
+		t.Errorf("Invalid configuration of the crawl: %+v", crawl.Config)
+	}
+	crawl.Wait()
+
+	// Its status, in the list of crawls and on its own:
+	statuses := []Status{}
+	if code := request(t, "GET", api.URL+"/crawls", "", &statuses); code != http.StatusOK || len(statuses) != 1 {
+		t.Fatalf("Invalid list of crawls: %d %+v", code, statuses)
+	}
+	request(t, "GET", api.URL+"/crawls/1", "", &status)
+	if status.State != StateFinished || status.Crawled != len(truth.Reachable()) || status.Finished == nil {
+		t.Errorf("Invalid status of the crawl: %+v", status)
+	}
+	if status.Errors == 0 || len(status.LatestErrors) != status.Errors {
+		t.Errorf("Invalid errors of the crawl: %d %v", status.Errors, status.LatestErrors)
+	}
+
+	// Its results, in several formats:
+	resp, err := http.Get(api.URL + "/crawls/1/results?format=json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sitemap, err := graph.ReadJSON(resp.Body)
+	resp.Body.Close()
+	if err != nil || len(sitemap.Nodes) != len(truth.Reachable()) {
+		t.Errorf("Invalid JSON results (error: %v)", err)
+	}
+
+	for name, format := range Formats {
+		resp, err := http.Get(api.URL + "/crawls/1/results?format=" + name)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != format.ContentType {
+			t.Errorf("Invalid response for the %s results: %d %s", name, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		if name != "structureddata" && len(body) == 0 {
+			t.Errorf("Results in the %s format are empty", name)
+		}
+	}
+
+	// Invalid requests:
+	invalid := map[string]int{
+		"GET /crawls/2":                        http.StatusNotFound,
+		"GET /crawls/1/results?format=pdf":     http.StatusBadRequest,
+		"DELETE /crawls":                       http.StatusMethodNotAllowed,
+		"GET /crawls/1/cancel":                 http.StatusMethodNotAllowed,
+		`POST /crawls {"domain": "monzo.com"}`: http.StatusBadRequest,
+		`POST /crawls {"domain": `:             http.StatusBadRequest,
+		`POST /crawls {"robots": "sometimes"}`: http.StatusBadRequest,
+		"GET /crawls/1/status":                 http.StatusNotFound,
+	}
+	for req, expected := range invalid {
+		parts := strings.SplitN(req, " ", 3)
+		body := ""
+		if len(parts) == 3 {
+			body = parts[2]
+		}
+		if code := request(t, parts[0], api.URL+parts[1], body, nil); code != expected {
+			t.Errorf("Invalid status code of %s. Expected: %d, Got: %d", req, expected, code)
+		}
+	}
+}
+
+func TestService_PauseResumeCancel(t *testing.T) {
+	site, truth := sitegen.NewServer(sitegen.Spec{Pages: 300, FanOut: 5, Seed: 3, Slow: 0.5, SlowDelay: 20 * time.Millisecond})
+	defer site.Close()
+
+	service := New()
+	api := httptest.NewServer(service)
+	defer api.Close()
+
+	config := DefaultConfig
+	config.Domain = sitegen.URL(site, "/")
+	paused, _ := service.Start(config)
+	cancelled, _ := service.Start(config)
+
+	status := Status{}
+	if request(t, "POST", api.URL+"/crawls/1/pause", "", &status); status.State != StatePaused {
+		t.Errorf("Invalid state of the paused crawl: %s", status.State)
+	}
+	if request(t, "POST", api.URL+"/crawls/2/cancel", "", &status); status.State != StateCancelling && status.State != StateCancelled {
+		t.Errorf("Invalid state of the cancelled crawl: %s", status.State)
+	}
+
+	// The results can't be read until the crawl ends:
+	if code := request(t, "GET", api.URL+"/crawls/1/results", "", nil); code != http.StatusConflict {
+		t.Errorf("Invalid status code of the results of a running crawl. Expected: %d, Got: %d", http.StatusConflict, code)
+	}
+
+	// The paused crawl doesn't crawl more pages:
+	time.Sleep(100 * time.Millisecond)
+	before := paused.Status().Crawled
+	time.Sleep(100 * time.Millisecond)
+	if after := paused.Status(); after.Crawled != before || after.State != StatePaused {
+		t.Errorf("Paused crawl kept crawling. Before: %d, After: %d", before, after.Crawled)
+	}
+
+	cancelled.Wait()
+	if status := cancelled.Status(); status.State != StateCancelled || status.Crawled >= len(truth.Reachable()) {
+		t.Errorf("Invalid status of the cancelled crawl: %+v", status)
+	}
+
+	request(t, "POST", api.URL+"/crawls/1/resume", "", &status)
+	paused.Wait()
+	if status := paused.Status(); status.State != StateFinished || status.Crawled != len(truth.Reachable()) {
+		t.Errorf("Invalid status of the resumed crawl: %+v", status)
+	}
+
+	// The two crawls don't share their outputs:
+	if bytes.Equal(paused.sitemap.Bytes(), cancelled.sitemap.Bytes()) {
+		t.Errorf("Sitemaps of the crawls are the same")
+	}
+}
+
+func TestService_Delete(t *testing.T) {
+	site, _ := sitegen.NewServer(sitegen.Spec{Pages: 50, FanOut: 4, Seed: 4})
+	defer site.Close()
+
+	service := NewWithRetention(2)
+	api := httptest.NewServer(service)
+	defer api.Close()
+
+	config := DefaultConfig
+	config.Domain = sitegen.URL(site, "/")
+
+	// A running crawl can't be removed, until it's cancelled:
+	running, _ := service.Start(config)
+	running.Pause()
+	if code := request(t, "DELETE", api.URL+"/crawls/1", "", nil); code != http.StatusConflict {
+		t.Errorf("Invalid status code of the removal of a running crawl. Expected: %d, Got: %d", http.StatusConflict, code)
+	}
+	running.Cancel()
+	running.Wait()
+	if code := request(t, "DELETE", api.URL+"/crawls/1", "", nil); code != http.StatusNoContent {
+		t.Errorf("Invalid status code of the removal of a crawl. Expected: %d, Got: %d", http.StatusNoContent, code)
+	}
+	if code := request(t, "GET", api.URL+"/crawls/1", "", nil); code != http.StatusNotFound {
+		t.Errorf("Invalid status code of a crawl removed. Expected: %d, Got: %d", http.StatusNotFound, code)
+	}
+
+	// Only the last 2 crawls that ended are kept:
+	for i := 0; i < 3; i++ {
+		crawl, _ := service.Start(config)
+		crawl.Wait()
+	}
+	ids := []string{}
+	for _, crawl := range service.Crawls() {
+		ids = append(ids, crawl.ID)
+	}
+	if strings.Join(ids, ",") != "3,4" {
+		t.Errorf("Crawls kept were invalid. Expected: %s, Got: %s", "3,4", strings.Join(ids, ","))
+	}
+}
@@ -0,0 +1,160 @@
+// Package simhash implements the SimHash fingerprint of texts, for which similar texts
+// have fingerprints that differ in few bits, and the clustering of near-duplicate texts.
+package simhash
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+)
+
+// shingleSize is the number of consecutive words that form each feature of a text.
+const shingleSize = 3
+
+// Compute returns the 64-bit SimHash of a text, whose features are its shingles
+// (sequences of consecutive words), case insensitive.
+func Compute(text string) uint64 {
+	words := strings.Fields(strings.ToLower(text))
+	if len(words) == 0 {
+		return 0
+	}
+
+	size := shingleSize
+	if len(words) < size {
+		size = len(words)
+	}
+
+	// For every bit, sum +1 for the features that have it set and -1 for the ones that don't:
+	var weights [64]int
+	for i := 0; i+size <= len(words); i++ {
+		hasher := fnv.New64a()
+		hasher.Write([]byte(strings.Join(words[i:i+size], " ")))
+		feature := hasher.Sum64()
+
+		for bit := uint(0); bit < 64; bit++ {
+			if feature&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit := uint(0); bit < 64; bit++ {
+		if weights[bit] > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
+
+// Distance returns the Hamming distance between two fingerprints (the number of bits that differ).
+func Distance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// cluster is a group of near-duplicate documents, represented by its first document.
+type cluster struct {
+	position  int // in the order in which the clusters were created
+	exactHash string
+	simHash   uint64
+	ids       []string
+}
+
+// Clusters groups documents that are near-duplicates: their texts are exactly the same or their
+// SimHash fingerprints are within a maximum Hamming distance of the first document of the group.
+//
+// The fingerprints are split in maxDistance+1 bands of bits, and the clusters are indexed by the
+// value of each band: two fingerprints within maxDistance bits have at least one band in common,
+// so only the clusters that share a band with a document are compared with it.
+type Clusters struct {
+	maxDistance int
+	clusters    []*cluster
+	byExactHash map[string]*cluster
+	bands       []map[uint64][]*cluster // by the value of every band of the fingerprint
+}
+
+// NewClusters returns an empty Clusters, grouping the documents within maxDistance bits.
+func NewClusters(maxDistance int) *Clusters {
+	nBands := maxDistance + 1
+	if nBands < 1 {
+		nBands = 1
+	}
+	if nBands > 64 {
+		nBands = 64
+	}
+
+	bands := make([]map[uint64][]*cluster, nBands)
+	for i := range bands {
+		bands[i] = make(map[uint64][]*cluster)
+	}
+
+	return &Clusters{
+		maxDistance: maxDistance,
+		clusters:    []*cluster{},
+		byExactHash: make(map[string]*cluster),
+		bands:       bands,
+	}
+}
+
+// band returns the value of a band of the bits of a fingerprint.
+func (clusters *Clusters) band(simHash uint64, i int) uint64 {
+	start := uint(i * 64 / len(clusters.bands))
+	end := uint((i + 1) * 64 / len(clusters.bands))
+	return (simHash >> start) & (1<<(end-start) - 1)
+}
+
+// nearest returns the first cluster created whose fingerprint is within the maximum distance of one, or nil.
+func (clusters *Clusters) nearest(simHash uint64) *cluster {
+	// Every fingerprint is within 64 bits of the others:
+	if clusters.maxDistance >= 64 && len(clusters.clusters) > 0 {
+		return clusters.clusters[0]
+	}
+
+	var found *cluster
+	for i, band := range clusters.bands {
+		for _, c := range band[clusters.band(simHash, i)] {
+			if (found == nil || c.position < found.position) && Distance(c.simHash, simHash) <= clusters.maxDistance {
+				found = c
+			}
+		}
+	}
+	return found
+}
+
+// Add adds a document to its group of near-duplicates. It returns the id of the first
+// document of the group, if the document is a duplicate, or "" otherwise.
+func (clusters *Clusters) Add(id string, exactHash string, simHash uint64) string {
+	found, ok := clusters.byExactHash[exactHash]
+	if !ok {
+		found = clusters.nearest(simHash)
+	}
+
+	if found == nil {
+		found = &cluster{position: len(clusters.clusters), exactHash: exactHash, simHash: simHash}
+		clusters.clusters = append(clusters.clusters, found)
+		for i, band := range clusters.bands {
+			value := clusters.band(simHash, i)
+			band[value] = append(band[value], found)
+		}
+	}
+	clusters.byExactHash[exactHash] = found
+	found.ids = append(found.ids, id)
+
+	if len(found.ids) == 1 {
+		return ""
+	}
+	return found.ids[0]
+}
+
+// Groups returns the groups of documents that have near-duplicates, in the order they were added.
+func (clusters *Clusters) Groups() [][]string {
+	groups := [][]string{}
+	for _, c := range clusters.clusters {
+		if len(c.ids) > 1 {
+			groups = append(groups, c.ids)
+		}
+	}
+	return groups
+}
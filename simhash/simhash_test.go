@@ -0,0 +1,103 @@
+package simhash
+
+import (
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const text = `Monzo is a bank that lives on your smartphone. Get paid early, split bills with friends,
+	save money with pots and get instant notifications every time you spend. Sign up in minutes
+	and get a hot coral debit card delivered to your door for free.`
+
+func TestCompute(t *testing.T) {
+	if Compute("") != 0 {
+		t.Errorf("SimHash of an empty text should be 0")
+	}
+
+	if Compute(text) != Compute(strings.ToUpper(text)) {
+		t.Errorf("SimHash should be case insensitive")
+	}
+
+	similar := strings.Replace(text, "in minutes", "in seconds", 1)
+	if d := Distance(Compute(text), Compute(similar)); d > 10 {
+		t.Errorf("Distance between similar texts is too big: %d", d)
+	}
+
+	different := "The quick brown fox jumps over the lazy dog, while the cat sleeps on the warm windowsill all afternoon."
+	if d := Distance(Compute(text), Compute(different)); d < 15 {
+		t.Errorf("Distance between different texts is too small: %d", d)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	if Distance(0, 0) != 0 || Distance(0xF, 0) != 4 || Distance(1<<63, 1) != 2 {
+		t.Errorf("Invalid Hamming distance")
+	}
+}
+
+func TestClusters(t *testing.T) {
+	clusters := NewClusters(3)
+
+	if dup := clusters.Add("A", "hashA", 0x0); dup != "" {
+		t.Errorf("A should not be a duplicate, Got: %s", dup)
+	}
+	if dup := clusters.Add("B", "hashB", 0xFF00); dup != "" {
+		t.Errorf("B should not be a duplicate, Got: %s", dup)
+	}
+	if dup := clusters.Add("C", "hashC", 0x7); dup != "A" {
+		t.Errorf("C should be a duplicate of A, Got: %s", dup)
+	}
+	if dup := clusters.Add("D", "hashB", 0xFFFFFFFF); dup != "B" {
+		t.Errorf("D should be an exact duplicate of B, Got: %s", dup)
+	}
+	if dup := clusters.Add("E", "hashE", 0xF); dup != "" {
+		t.Errorf("E should not be a duplicate, Got: %s", dup)
+	}
+
+	expected := [][]string{{"A", "C"}, {"B", "D"}}
+	if groups := clusters.Groups(); !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Invalid groups. Expected: %v, Got: %v", expected, groups)
+	}
+}
+
+func TestClusters_Index(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	for _, maxDistance := range []int{0, 3, 10, 64} {
+		// Fingerprints close to a few random ones, so that there are duplicates:
+		bases := []uint64{random.Uint64(), random.Uint64(), random.Uint64()}
+		fingerprints := []uint64{}
+		for i := 0; i < 500; i++ {
+			fingerprint := bases[random.Intn(len(bases))]
+			for flips := random.Intn(12); flips > 0; flips-- {
+				fingerprint ^= 1 << uint(random.Intn(64))
+			}
+			fingerprints = append(fingerprints, fingerprint)
+		}
+
+		// The index finds the same clusters as comparing every document with every cluster:
+		clusters := NewClusters(maxDistance)
+		firsts := []uint64{}
+		firstIDs := []string{}
+		for i, fingerprint := range fingerprints {
+			expected := ""
+			for j, first := range firsts {
+				if Distance(first, fingerprint) <= maxDistance {
+					expected = firstIDs[j]
+					break
+				}
+			}
+			if expected == "" {
+				firsts = append(firsts, fingerprint)
+				firstIDs = append(firstIDs, strconv.Itoa(i))
+			}
+
+			if dup := clusters.Add(strconv.Itoa(i), strconv.Itoa(i), fingerprint); dup != expected {
+				t.Fatalf("Invalid duplicate of %d within %d bits. Expected: %q, Got: %q", i, maxDistance, expected, dup)
+			}
+		}
+	}
+}
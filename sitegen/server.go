@@ -0,0 +1,110 @@
+package sitegen
+
+import (
+	"fmt"
+	"html"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// movedSuffix is added to the path of the redirect pages to get the path of their contents.
+const movedSuffix = "/moved"
+
+// Handler returns the HTTP handler that serves the site.
+func (site *Site) Handler() http.Handler {
+	return http.HandlerFunc(site.serveHTTP)
+}
+
+func (site *Site) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+
+	switch {
+	case strings.HasPrefix(path, "/calendar/") && site.Spec.CalendarTrap:
+		site.serveCalendar(w, path)
+		return
+	case strings.HasPrefix(path, PathTrapPath) && site.Spec.PathTrap:
+		writeHTML(w, "Loop", "", []string{path + "loop/"})
+		return
+	}
+
+	// The contents of the redirect pages:
+	if strings.HasSuffix(path, movedSuffix) {
+		if page, ok := site.byPath[strings.TrimSuffix(path, movedSuffix)]; ok && page.Kind == KindRedirect {
+			site.servePage(w, page)
+			return
+		}
+	}
+
+	page, ok := site.byPath[path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch page.Kind {
+	case KindNotFound:
+		http.NotFound(w, r)
+	case KindRedirect:
+		http.Redirect(w, r, page.Path+movedSuffix, http.StatusMovedPermanently)
+	case KindSlow:
+		time.Sleep(site.Spec.SlowDelay)
+		site.servePage(w, page)
+	case KindNonHTML:
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.4\n"))
+	default:
+		site.servePage(w, page)
+	}
+}
+
+// servePage writes the HTML of a page, with its links.
+func (site *Site) servePage(w http.ResponseWriter, page *Page) {
+	i := 0
+	if page.Path != "/" {
+		i, _ = strconv.Atoi(strings.TrimPrefix(page.Path, "/p/"))
+	}
+	writeHTML(w, "Page "+strconv.Itoa(i), site.text[i], page.Links)
+}
+
+// serveCalendar writes a month of the calendar trap (e.g. /calendar/2000/01), which links to the next month.
+func (site *Site) serveCalendar(w http.ResponseWriter, path string) {
+	month, err := time.Parse("/calendar/2006/01", path)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	next := month.AddDate(0, 1, 0).Format("/calendar/2006/01")
+	writeHTML(w, month.Format("January 2006"), "", []string{next})
+}
+
+// writeHTML writes an HTML page with a title, some text and links to paths.
+func writeHTML(w http.ResponseWriter, title string, text string, links []string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html lang=\"en\"><head><title>%s</title></head><body>\n<h1>%s</h1>\n<p>%s</p>\n",
+		html.EscapeString(title), html.EscapeString(title), html.EscapeString(text))
+	for _, link := range links {
+		fmt.Fprintf(&b, "<a href=\"%s\">%s</a>\n", html.EscapeString(link), html.EscapeString(link))
+	}
+	b.WriteString("</body></html>\n")
+	w.Write([]byte(b.String()))
+}
+
+// words are the words of which the text of the pages is made.
+var words = strings.Fields(`alpha bravo charlie delta echo foxtrot golf hotel india juliett kilo lima mike november
+	oscar papa quebec romeo sierra tango uniform victor whiskey xray yankee zulu account balance card payment
+	transfer savings budget salary pot bill travel abroad fee limit report statement branch mobile online`)
+
+// randomText returns a text of random words.
+func randomText(random *rand.Rand, nWords int) string {
+	text := make([]string, nWords)
+	for i := range text {
+		text[i] = words[random.Intn(len(words))]
+	}
+	return strings.Join(text, " ")
+}
@@ -0,0 +1,194 @@
+// Package sitegen generates synthetic websites from a spec (number of pages, fan-out, depth, broken links,
+// redirects, slow and non-HTML pages, crawler traps) and serves them over HTTP, along with the ground truth
+// of what a crawler should find in them. It's meant for end-to-end tests and benchmarks of the crawler.
+package sitegen
+
+import (
+	"math/rand"
+	"net/http/httptest"
+	"strconv"
+	"time"
+)
+
+// Kind is the kind of response of a page of a generated site.
+type Kind int
+
+const (
+	// KindHTML pages are HTML pages served right away.
+	KindHTML Kind = iota
+	// KindNotFound pages are linked to, but respond with 404 Not Found.
+	KindNotFound
+	// KindRedirect pages respond with a 301 redirect to their contents, at another path.
+	KindRedirect
+	// KindSlow pages are HTML pages served after a delay.
+	KindSlow
+	// KindNonHTML pages are PDF documents, with no links.
+	KindNonHTML
+)
+
+// Paths at which the crawler traps start:
+const (
+	CalendarTrapPath = "/calendar/2000/01"
+	PathTrapPath     = "/loop/"
+)
+
+// defaultSlowDelay is the delay of the slow pages when the spec doesn't give one.
+const defaultSlowDelay = 100 * time.Millisecond
+
+// Spec is the specification of a site to generate.
+type Spec struct {
+	Pages      int   // number of pages, including the home page
+	FanOut     int   // number of children of every page in the spanning tree of the site
+	MaxDepth   int   // maximum depth of the spanning tree (0 for no limit), the pages beyond it are attached higher
+	CrossLinks int   // number of extra links of every page, to random pages of the site
+	Seed       int64 // seed of the random choices, the same spec always generates the same site
+
+	// Fractions of the pages (other than the home page) of every kind, the rest being HTML pages:
+	NotFound  float64
+	Redirects float64
+	Slow      float64
+	NonHTML   float64
+	SlowDelay time.Duration // delay of the slow pages (100ms if 0)
+
+	CalendarTrap bool // the home page links to a calendar in which every month links to the next one, forever
+	PathTrap     bool // the home page links to a page that links to itself one directory deeper, forever
+}
+
+// Page is a page of a generated site.
+type Page struct {
+	Path  string
+	Kind  Kind
+	Depth int      // depth in the spanning tree of the site
+	Links []string // paths of the pages linked, without duplicates
+}
+
+// Site is a generated site, served by its Handler.
+type Site struct {
+	Spec   Spec
+	Pages  []*Page // the home page first
+	byPath map[string]*Page
+
+	// Words of the visible text of every page, so that pages don't look like duplicates:
+	text []string
+}
+
+// Generate generates the site of a spec.
+func Generate(spec Spec) *Site {
+	if spec.Pages < 1 {
+		spec.Pages = 1
+	}
+	if spec.FanOut < 1 {
+		spec.FanOut = 1
+	}
+	if spec.SlowDelay == 0 {
+		spec.SlowDelay = defaultSlowDelay
+	}
+
+	random := rand.New(rand.NewSource(spec.Seed))
+	site := &Site{Spec: spec, byPath: make(map[string]*Page)}
+
+	// The spanning tree is filled breadth first, attaching the pages beyond the maximum depth to random
+	// pages above it:
+	for i := 0; i < spec.Pages; i++ {
+		page := &Page{Path: pagePath(i), Kind: KindHTML}
+		if i > 0 {
+			parent := site.Pages[(i-1)/spec.FanOut]
+			if spec.MaxDepth > 0 && parent.Depth >= spec.MaxDepth {
+				parent = site.randomPageAbove(random, spec.MaxDepth)
+			}
+			page.Depth = parent.Depth + 1
+			page.Kind = randomKind(random, spec)
+			parent.addLink(page.Path)
+		}
+		site.Pages = append(site.Pages, page)
+		site.byPath[page.Path] = page
+	}
+
+	for _, page := range site.Pages {
+		for i := 0; i < spec.CrossLinks && spec.Pages > 1; i++ {
+			target := site.Pages[random.Intn(spec.Pages)]
+			if target != page {
+				page.addLink(target.Path)
+			}
+		}
+	}
+
+	home := site.Pages[0]
+	if spec.CalendarTrap {
+		home.Links = append(home.Links, CalendarTrapPath)
+	}
+	if spec.PathTrap {
+		home.Links = append(home.Links, PathTrapPath)
+	}
+
+	site.text = make([]string, spec.Pages)
+	for i := range site.text {
+		site.text[i] = randomText(random, 40)
+	}
+	return site
+}
+
+// NewServer generates the site of a spec and starts serving it. The server should be closed when done.
+func NewServer(spec Spec) (*httptest.Server, *Site) {
+	site := Generate(spec)
+	return httptest.NewServer(site.Handler()), site
+}
+
+// URL returns the URL of a path of a site served by a server (e.g. the home page, to start crawling from).
+func URL(server *httptest.Server, path string) string {
+	return server.URL + path
+}
+
+// Page returns the page of a path, if the site has one.
+func (site *Site) Page(path string) (*Page, bool) {
+	page, ok := site.byPath[path]
+	return page, ok
+}
+
+// addLink adds a link to the page, if it doesn't link to that path already.
+func (page *Page) addLink(path string) {
+	for _, link := range page.Links {
+		if link == path {
+			return
+		}
+	}
+	page.Links = append(page.Links, path)
+}
+
+// randomPageAbove returns a random page whose depth is below a maximum depth.
+func (site *Site) randomPageAbove(random *rand.Rand, maxDepth int) *Page {
+	for {
+		page := site.Pages[random.Intn(len(site.Pages))]
+		if page.Depth < maxDepth {
+			return page
+		}
+	}
+}
+
+// randomKind draws the kind of a page from the fractions of the spec.
+func randomKind(random *rand.Rand, spec Spec) Kind {
+	r := random.Float64()
+	for _, fraction := range []struct {
+		kind     Kind
+		fraction float64
+	}{
+		{KindNotFound, spec.NotFound},
+		{KindRedirect, spec.Redirects},
+		{KindSlow, spec.Slow},
+		{KindNonHTML, spec.NonHTML},
+	} {
+		if r < fraction.fraction {
+			return fraction.kind
+		}
+		r -= fraction.fraction
+	}
+	return KindHTML
+}
+
+// pagePath returns the path of the i-th page of a site.
+func pagePath(i int) string {
+	if i == 0 {
+		return "/"
+	}
+	return "/p/" + strconv.Itoa(i)
+}
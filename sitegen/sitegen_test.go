@@ -0,0 +1,140 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerate_Tree(t *testing.T) {
+	site := Generate(Spec{Pages: 100, FanOut: 3, MaxDepth: 3, Seed: 1})
+
+	if len(site.Pages) != 100 {
+		t.Fatalf("Number of pages was invalid. Expected: %d, Got: %d", 100, len(site.Pages))
+	}
+
+	// Without broken pages, all the pages are reachable through the spanning tree:
+	reachable := site.Reachable()
+	if len(reachable) != 100 {
+		t.Errorf("Number of reachable pages was invalid. Expected: %d, Got: %d", 100, len(reachable))
+	}
+
+	for _, page := range site.Pages {
+		if page.Depth > 3 {
+			t.Errorf("Page %s is deeper than the maximum depth: %d", page.Path, page.Depth)
+		}
+		if reachable[page.Path] != page.Depth {
+			t.Errorf("Invalid depth of %s. Expected: %d, Got: %d", page.Path, page.Depth, reachable[page.Path])
+		}
+	}
+
+	// The pages beyond the maximum depth (the 40 first fill it) are attached to random pages above it:
+	if links := site.Pages[0].Links; len(links) < 3 || !reflect.DeepEqual(links[:3], []string{"/p/1", "/p/2", "/p/3"}) {
+		t.Errorf("Invalid links of the home page: %v", links)
+	}
+	if links := site.Pages[13].Links; len(links) != 0 {
+		t.Errorf("Page at the maximum depth has children: %v", links)
+	}
+
+	if len(site.Edges()) != 99 {
+		t.Errorf("Number of edges was invalid. Expected: %d, Got: %d", 99, len(site.Edges()))
+	}
+}
+
+func TestGenerate_Deterministic(t *testing.T) {
+	spec := Spec{Pages: 500, FanOut: 5, CrossLinks: 3, Seed: 42, NotFound: 0.1, Redirects: 0.1, Slow: 0.1, NonHTML: 0.1}
+	site1, site2 := Generate(spec), Generate(spec)
+
+	if !reflect.DeepEqual(site1.Pages, site2.Pages) || !reflect.DeepEqual(site1.text, site2.text) {
+		t.Errorf("The same spec generated different sites")
+	}
+
+	spec.Seed = 43
+	if reflect.DeepEqual(site1.Pages, Generate(spec).Pages) {
+		t.Errorf("Different seeds generated the same site")
+	}
+
+	kinds := map[Kind]int{}
+	for _, page := range site1.Pages {
+		kinds[page.Kind]++
+	}
+	for _, kind := range []Kind{KindNotFound, KindRedirect, KindSlow, KindNonHTML} {
+		if kinds[kind] < 25 || kinds[kind] > 75 {
+			t.Errorf("Invalid number of pages of kind %d. Expected: about %d, Got: %d", kind, 50, kinds[kind])
+		}
+	}
+}
+
+func TestSite_Reachable(t *testing.T) {
+	site := Generate(Spec{Pages: 7, FanOut: 2})
+
+	// The children of a broken page or a PDF aren't reachable:
+	site.Pages[1].Kind = KindNotFound
+	site.Pages[2].Kind = KindNonHTML
+
+	expected := map[string]int{"/": 0, "/p/1": 1, "/p/2": 1}
+	if reachable := site.Reachable(); !reflect.DeepEqual(reachable, expected) {
+		t.Errorf("Invalid reachable pages. Expected: %v, Got: %v", expected, reachable)
+	}
+
+	if broken := site.Broken(); !reflect.DeepEqual(broken, []string{"/p/1"}) {
+		t.Errorf("Invalid broken pages. Expected: %v, Got: %v", []string{"/p/1"}, broken)
+	}
+
+	if len(site.Edges()) != 2 {
+		t.Errorf("Number of edges was invalid. Expected: %d, Got: %d", 2, len(site.Edges()))
+	}
+}
+
+func TestServer(t *testing.T) {
+	server, site := NewServer(Spec{Pages: 6, FanOut: 5, Seed: 1, SlowDelay: 20 * time.Millisecond, CalendarTrap: true, PathTrap: true})
+	defer server.Close()
+
+	site.Pages[1].Kind = KindNotFound
+	site.Pages[2].Kind = KindRedirect
+	site.Pages[3].Kind = KindSlow
+	site.Pages[4].Kind = KindNonHTML
+
+	tests := []struct {
+		path        string
+		statusCode  int
+		contentType string
+		contains    string
+	}{
+		{"/", 200, "text/html", `<a href="/p/5">`},
+		{"/p/1", 404, "text/plain", ""},
+		{"/p/2", 200, "text/html", "<title>Page 2</title>"},
+		{"/p/3", 200, "text/html", "<title>Page 3</title>"},
+		{"/p/4", 200, "application/pdf", "%PDF"},
+		{"/p/9", 404, "text/plain", ""},
+		{CalendarTrapPath, 200, "text/html", `<a href="/calendar/2000/02">`},
+		{"/calendar/2000/12", 200, "text/html", `<a href="/calendar/2001/01">`},
+		{PathTrapPath + "loop/", 200, "text/html", `<a href="/loop/loop/loop/">`},
+	}
+
+	for _, test := range tests {
+		start := time.Now()
+		resp, err := http.Get(URL(server, test.path))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != test.statusCode || !strings.HasPrefix(resp.Header.Get("Content-Type"), test.contentType) ||
+			!strings.Contains(string(body), test.contains) {
+			t.Errorf("Invalid response for %s. Expected: %d %s %q, Got: %d %s %s",
+				test.path, test.statusCode, test.contentType, test.contains, resp.StatusCode, resp.Header.Get("Content-Type"), body)
+		}
+
+		if test.path == "/p/2" && resp.Request.URL.Path != "/p/2"+movedSuffix {
+			t.Errorf("Redirect page was not redirected: %s", resp.Request.URL.Path)
+		}
+		if test.path == "/p/3" && time.Since(start) < 20*time.Millisecond {
+			t.Errorf("Slow page was served too fast: %v", time.Since(start))
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package sitegen
+
+import "strings"
+
+// Edge is a link between two pages of a site, by their paths.
+type Edge struct {
+	Source string
+	Target string
+}
+
+// Reachable returns the paths of the pages reachable from the home page by following links (the pages
+// of the traps aside), with their shortest distance to it. Pages that are broken or aren't HTML are
+// reachable, but their links (if any) aren't followed.
+func (site *Site) Reachable() map[string]int {
+	depths := map[string]int{"/": 0}
+	queue := []string{"/"}
+
+	for len(queue) > 0 {
+		page := site.byPath[queue[0]]
+		queue = queue[1:]
+		if !page.followed() {
+			continue
+		}
+
+		for _, link := range page.Links {
+			if _, ok := depths[link]; ok || IsTrap(link) {
+				continue
+			}
+			depths[link] = depths[page.Path] + 1
+			queue = append(queue, link)
+		}
+	}
+	return depths
+}
+
+// Edges returns the links of the pages reachable from the home page whose links are followed
+// (the links to the traps aside).
+func (site *Site) Edges() []Edge {
+	edges := []Edge{}
+	for path := range site.Reachable() {
+		page := site.byPath[path]
+		if !page.followed() {
+			continue
+		}
+		for _, link := range page.Links {
+			if !IsTrap(link) {
+				edges = append(edges, Edge{Source: path, Target: link})
+			}
+		}
+	}
+	return edges
+}
+
+// Broken returns the paths of the pages reachable from the home page that respond with 404 Not Found.
+func (site *Site) Broken() []string {
+	broken := []string{}
+	for path := range site.Reachable() {
+		if site.byPath[path].Kind == KindNotFound {
+			broken = append(broken, path)
+		}
+	}
+	return broken
+}
+
+// StatusCode returns the status code with which a page of the site is obtained (after following redirects).
+func (page *Page) StatusCode() int {
+	if page.Kind == KindNotFound {
+		return 404
+	}
+	return 200
+}
+
+// followed tells if the links of a page are followed by a crawler, i.e. it's an HTML page obtained successfully.
+func (page *Page) followed() bool {
+	return page.Kind == KindHTML || page.Kind == KindSlow || page.Kind == KindRedirect
+}
+
+// IsTrap tells if a path belongs to one of the crawler traps of the generated sites.
+func IsTrap(path string) bool {
+	return strings.HasPrefix(path, "/calendar/") || strings.HasPrefix(path, PathTrapPath)
+}
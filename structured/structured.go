@@ -0,0 +1,71 @@
+// Package structured keeps the structured data of HTML pages: JSON-LD blocks, microdata items
+// and OpenGraph/Twitter card meta tags. The microdata items are extracted from the DOM of the pages
+// by the fetcher, along with their other contents.
+package structured
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// Data is the structured data found in a page.
+type Data struct {
+	JSONLD    []json.RawMessage `json:"json_ld,omitempty"`   // contents of the <script type="application/ld+json"> blocks
+	Microdata []*Item           `json:"microdata,omitempty"` // top-level microdata items (itemscope)
+	OpenGraph map[string]string `json:"opengraph,omitempty"` // og:* meta tags, without the prefix (e.g. "title")
+	Twitter   map[string]string `json:"twitter,omitempty"`   // twitter:* meta tags, without the prefix (e.g. "card")
+	Errors    []string          `json:"errors,omitempty"`    // problems found (e.g. JSON-LD blocks that don't parse)
+}
+
+// IsEmpty checks if no structured data was found.
+func (data *Data) IsEmpty() bool {
+	return len(data.JSONLD) == 0 && len(data.Microdata) == 0 && len(data.OpenGraph) == 0 &&
+		len(data.Twitter) == 0 && len(data.Errors) == 0
+}
+
+// AddJSONLD adds the contents of a JSON-LD block, if they parse as JSON. Otherwise, an error is recorded.
+func (data *Data) AddJSONLD(text string) {
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(text), &decoded); err != nil {
+		data.Errors = append(data.Errors, "invalid JSON-LD: "+err.Error())
+		return
+	}
+
+	var compacted bytes.Buffer
+	json.Compact(&compacted, []byte(text))
+	data.JSONLD = append(data.JSONLD, json.RawMessage(compacted.Bytes()))
+}
+
+// AddMeta adds a meta tag if it's an OpenGraph (e.g. property="og:title") or a Twitter card
+// (e.g. name="twitter:card") one. Only the first value of every property is kept.
+func (data *Data) AddMeta(property string, content string) {
+	property = strings.ToLower(strings.TrimSpace(property))
+
+	switch {
+	case strings.HasPrefix(property, "og:"):
+		if data.OpenGraph == nil {
+			data.OpenGraph = make(map[string]string)
+		}
+		addProperty(data.OpenGraph, strings.TrimPrefix(property, "og:"), content)
+	case strings.HasPrefix(property, "twitter:"):
+		if data.Twitter == nil {
+			data.Twitter = make(map[string]string)
+		}
+		addProperty(data.Twitter, strings.TrimPrefix(property, "twitter:"), content)
+	}
+}
+
+func addProperty(properties map[string]string, key string, value string) {
+	if _, ok := properties[key]; !ok {
+		properties[key] = strings.TrimSpace(value)
+	}
+}
+
+// Item is a microdata item: an element with the itemscope attribute, along with the properties (itemprop)
+// found inside it. The values of the properties are strings or, for nested items, *Item.
+type Item struct {
+	Type       []string                 `json:"type,omitempty"`
+	ID         string                   `json:"id,omitempty"`
+	Properties map[string][]interface{} `json:"properties"`
+}
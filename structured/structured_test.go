@@ -0,0 +1,47 @@
+package structured
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestData_AddJSONLD(t *testing.T) {
+	data := &Data{}
+	data.AddJSONLD(`{
+		"@context": "https://schema.org",
+		"@type": "Organization"
+	}`)
+	data.AddJSONLD(`{"@type": "Organization",}`)
+
+	if len(data.JSONLD) != 1 || string(data.JSONLD[0]) != `{"@context":"https://schema.org","@type":"Organization"}` {
+		t.Errorf("JSON-LD was invalid: %s", data.JSONLD)
+	}
+
+	if len(data.Errors) != 1 || !strings.HasPrefix(data.Errors[0], "invalid JSON-LD: ") {
+		t.Errorf("Errors were invalid: %v", data.Errors)
+	}
+}
+
+func TestData_AddMeta(t *testing.T) {
+	data := &Data{}
+	if !data.IsEmpty() {
+		t.Errorf("Data should be empty")
+	}
+
+	data.AddMeta("og:title", " Monzo ")
+	data.AddMeta("OG:Image", "a.png")
+	data.AddMeta("og:image", "b.png")
+	data.AddMeta("twitter:card", "summary")
+	data.AddMeta("description", "Bank")
+
+	expectedOpenGraph := map[string]string{"title": "Monzo", "image": "a.png"}
+	if !reflect.DeepEqual(data.OpenGraph, expectedOpenGraph) {
+		t.Errorf("OpenGraph was invalid. Expected: %v, Got: %v", expectedOpenGraph, data.OpenGraph)
+	}
+
+	expectedTwitter := map[string]string{"card": "summary"}
+	if !reflect.DeepEqual(data.Twitter, expectedTwitter) {
+		t.Errorf("Twitter was invalid. Expected: %v, Got: %v", expectedTwitter, data.Twitter)
+	}
+}
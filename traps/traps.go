@@ -0,0 +1,224 @@
+// Package traps detects crawler traps: infinite URL spaces (e.g. calendars, recursively growing paths
+// or session IDs in paths) that would make a crawl run indefinitely.
+package traps
+
+import (
+	"hash/fnv"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/msandim/web-crawler/audit"
+)
+
+// Rules of the traps detected:
+const (
+	RuleRepeatingSegments = "trap-repeating-segments" // a path segment repeats too many times (e.g. /a/a/a/a/)
+	RulePathDepth         = "trap-path-depth"         // the path has too many segments
+	RuleParameter         = "trap-parameter"          // too many URLs differ only in one path segment
+	RulePatternBudget     = "trap-pattern-budget"     // too many URLs share a pattern (e.g. /calendar/{id}/{id})
+)
+
+// nExamples is the number of URLs skipped kept as examples of every trap.
+const nExamples = 5
+
+// Limits are the thresholds from which URLs are considered to be in a trap.
+type Limits struct {
+	MaxRepeats    int // maximum number of times a segment can appear in a path
+	MaxDepth      int // maximum number of segments of a path
+	MaxVariants   int // maximum number of URLs that differ only in one path segment
+	MaxPerPattern int // maximum number of URLs with the same pattern (IDs, numbers and dates replaced by "{id}")
+}
+
+// DefaultLimits are limits that are hardly reached by the URLs of real pages.
+var DefaultLimits = Limits{MaxRepeats: 3, MaxDepth: 15, MaxVariants: 1000, MaxPerPattern: 5000}
+
+// Trap is a crawler trap detected, with the URLs skipped because of it.
+type Trap struct {
+	Rule     string
+	Pattern  string   // pattern of the URLs of the trap (e.g. "http://a.com/calendar/{id}/{id}")
+	Skipped  int      // number of URLs skipped
+	Examples []string // first URLs skipped
+}
+
+// Detector tells which URLs are in crawler traps, counting the URLs accepted in the budgets of their patterns.
+// The variants, patterns and URLs skipped are kept as 64-bit hashes, so that its memory doesn't grow with the
+// length of the URLs. It is not safe for concurrent use.
+//
+// Only the paths of the URLs are checked: the crawler strips the queries of the URLs of the domain.
+type Detector struct {
+	limits   Limits
+	variants map[uint64]int    // URLs accepted by hash of the URL with one of its segments replaced by "*"
+	patterns map[uint64]int    // URLs accepted by hash of their pattern
+	skipped  map[uint64]string // rules of the URLs skipped by their hash
+	traps    map[string]*Trap  // by rule and pattern
+	order    []*Trap
+}
+
+// New returns a Detector of the traps beyond some limits (the ones not greater than 0 aren't checked).
+func New(limits Limits) *Detector {
+	return &Detector{
+		limits:   limits,
+		variants: make(map[uint64]int),
+		patterns: make(map[uint64]int),
+		skipped:  make(map[uint64]string),
+		traps:    make(map[string]*Trap),
+	}
+}
+
+// Check tells if an URL is in a trap, along with the rule of the trap. URLs that aren't in a trap are counted
+// in the budgets of their patterns, so every URL should only be checked once (URLs skipped can be checked again).
+func (detector *Detector) Check(rawURL string) (string, bool) {
+	if rule, ok := detector.skipped[hash(rawURL)]; ok {
+		return rule, true
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	segments := pathSegments(parsed.Path)
+	base := parsed.Scheme + "://" + parsed.Host
+
+	if limit := detector.limits.MaxDepth; limit > 0 && len(segments) > limit {
+		return detector.skip(rawURL, RulePathDepth, base+"/"+strings.Join(segments[:limit], "/")+"/...")
+	}
+
+	if limit := detector.limits.MaxRepeats; limit > 0 {
+		counts := make(map[string]int)
+		for i, segment := range segments {
+			counts[segment]++
+			if counts[segment] > limit {
+				return detector.skip(rawURL, RuleRepeatingSegments, base+"/"+strings.Join(segments[:i+1], "/")+"/...")
+			}
+		}
+	}
+
+	variants := variantKeys(base, segments)
+	variantHashes := make([]uint64, len(variants))
+	for i, variant := range variants {
+		variantHashes[i] = hash(variant)
+	}
+	if limit := detector.limits.MaxVariants; limit > 0 {
+		for i, variant := range variants {
+			if detector.variants[variantHashes[i]] >= limit {
+				return detector.skip(rawURL, RuleParameter, variant)
+			}
+		}
+	}
+
+	pattern := urlPattern(base, segments)
+	patternHash := hash(pattern)
+	if limit := detector.limits.MaxPerPattern; limit > 0 && detector.patterns[patternHash] >= limit {
+		return detector.skip(rawURL, RulePatternBudget, pattern)
+	}
+
+	for _, variantHash := range variantHashes {
+		detector.variants[variantHash]++
+	}
+	detector.patterns[patternHash]++
+	return "", false
+}
+
+// Traps returns the traps detected, in the order in which they were detected.
+func (detector *Detector) Traps() []*Trap {
+	return detector.order
+}
+
+// Issues returns the traps detected as issues of their first URL skipped.
+func (detector *Detector) Issues() []audit.Issue {
+	issues := []audit.Issue{}
+	for _, trap := range detector.order {
+		issues = append(issues, audit.Issue{
+			URL:     trap.Examples[0],
+			Rule:    trap.Rule,
+			Message: strconv.Itoa(trap.Skipped) + " URLs like " + trap.Pattern + " were skipped",
+		})
+	}
+	return issues
+}
+
+// IsRule tells if the rule of an issue is the one of a trap.
+func IsRule(rule string) bool {
+	return strings.HasPrefix(rule, "trap-")
+}
+
+// skip records an URL skipped because of a trap.
+func (detector *Detector) skip(rawURL string, rule string, pattern string) (string, bool) {
+	detector.skipped[hash(rawURL)] = rule
+
+	trap, ok := detector.traps[rule+" "+pattern]
+	if !ok {
+		trap = &Trap{Rule: rule, Pattern: pattern}
+		detector.traps[rule+" "+pattern] = trap
+		detector.order = append(detector.order, trap)
+	}
+	trap.Skipped++
+	if len(trap.Examples) < nExamples {
+		trap.Examples = append(trap.Examples, rawURL)
+	}
+	return rule, true
+}
+
+// pathSegments returns the non-empty segments of a path.
+func pathSegments(path string) []string {
+	segments := []string{}
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// variantKeys returns the URL with each of its path segments replaced by "*", in turn.
+func variantKeys(base string, segments []string) []string {
+	keys := []string{}
+	for i := range segments {
+		replaced := append([]string{}, segments...)
+		replaced[i] = "*"
+		keys = append(keys, base+"/"+strings.Join(replaced, "/"))
+	}
+	return keys
+}
+
+// urlPattern returns the URL with the path segments that look like IDs replaced by "{id}".
+func urlPattern(base string, segments []string) string {
+	pattern := make([]string, len(segments))
+	for i, segment := range segments {
+		pattern[i] = segment
+		if isID(segment) {
+			pattern[i] = "{id}"
+		}
+	}
+	return base + "/" + strings.Join(pattern, "/")
+}
+
+// hash returns the 64-bit FNV-1a hash of a key.
+func hash(key string) uint64 {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(key))
+	return hasher.Sum64()
+}
+
+// isID tells if a path segment looks like an ID: a number, a date, or a long token
+// with digits (e.g. a hash or a session ID).
+func isID(segment string) bool {
+	digits, letters := 0, 0
+	for _, r := range segment {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			letters++
+		case r == '-' || r == '_' || r == '.':
+		default:
+			return false
+		}
+	}
+
+	if digits == 0 {
+		return false
+	}
+	return letters == 0 || len(segment) >= 8
+}
@@ -0,0 +1,130 @@
+package traps
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestDetector_RepeatingSegments(t *testing.T) {
+	detector := New(DefaultLimits)
+
+	for _, u := range []string{"http://monzo.com/a/", "http://monzo.com/a/a/", "http://monzo.com/a/b/a/c/a/"} {
+		if rule, trapped := detector.Check(u); trapped {
+			t.Errorf("URL %s should not be in a trap, Got: %s", u, rule)
+		}
+	}
+
+	if rule, trapped := detector.Check("http://monzo.com/a/b/a/a/a/"); !trapped || rule != RuleRepeatingSegments {
+		t.Errorf("Invalid rule of a repeating path. Expected: %s, Got: %s", RuleRepeatingSegments, rule)
+	}
+	detector.Check("http://monzo.com/a/b/a/a/a/")
+
+	traps := detector.Traps()
+	if len(traps) != 1 || traps[0].Pattern != "http://monzo.com/a/b/a/a/a/..." || traps[0].Skipped != 1 {
+		t.Errorf("Invalid traps detected: %+v", traps)
+	}
+}
+
+func TestDetector_PathDepth(t *testing.T) {
+	detector := New(Limits{MaxDepth: 3})
+
+	if _, trapped := detector.Check("http://monzo.com/a/b/c"); trapped {
+		t.Errorf("URL with 3 segments should not be in a trap")
+	}
+	if rule, trapped := detector.Check("http://monzo.com/a/b/c/d"); !trapped || rule != RulePathDepth {
+		t.Errorf("Invalid rule of a deep path. Expected: %s, Got: %s", RulePathDepth, rule)
+	}
+}
+
+func TestDetector_Parameter(t *testing.T) {
+	detector := New(Limits{MaxVariants: 10})
+
+	for i := 0; i < 10; i++ {
+		if _, trapped := detector.Check("http://monzo.com/search/cards/" + strconv.Itoa(i)); trapped {
+			t.Errorf("Page %d should not be in a trap", i)
+		}
+	}
+
+	rule, trapped := detector.Check("http://monzo.com/search/cards/10")
+	if !trapped || rule != RuleParameter {
+		t.Errorf("Invalid rule of a parameter. Expected: %s, Got: %s", RuleParameter, rule)
+	}
+	if pattern := detector.Traps()[0].Pattern; pattern != "http://monzo.com/search/cards/*" {
+		t.Errorf("Invalid pattern. Expected: %s, Got: %s", "http://monzo.com/search/cards/*", pattern)
+	}
+
+	// The URLs skipped are only counted once:
+	detector.Check("http://monzo.com/search/cards/10")
+	if skipped := detector.Traps()[0].Skipped; skipped != 1 {
+		t.Errorf("Number of URLs skipped was invalid. Expected: %d, Got: %d", 1, skipped)
+	}
+
+	// Other searches are still crawled:
+	if _, trapped := detector.Check("http://monzo.com/search/loans/1"); trapped {
+		t.Errorf("Other searches should not be in a trap")
+	}
+}
+
+func TestDetector_PatternBudget(t *testing.T) {
+	detector := New(Limits{MaxPerPattern: 24})
+
+	skipped := 0
+	for year := 2000; year < 2003; year++ {
+		for month := 1; month <= 12; month++ {
+			if _, trapped := detector.Check("http://monzo.com/calendar/" + strconv.Itoa(year) + "/" + strconv.Itoa(month)); trapped {
+				skipped++
+			}
+		}
+	}
+
+	if skipped != 12 {
+		t.Errorf("Number of URLs skipped was invalid. Expected: %d, Got: %d", 12, skipped)
+	}
+
+	expected := []*Trap{{
+		Rule:    RulePatternBudget,
+		Pattern: "http://monzo.com/calendar/{id}/{id}",
+		Skipped: 12,
+		Examples: []string{
+			"http://monzo.com/calendar/2002/1", "http://monzo.com/calendar/2002/2", "http://monzo.com/calendar/2002/3",
+			"http://monzo.com/calendar/2002/4", "http://monzo.com/calendar/2002/5",
+		},
+	}}
+	if !reflect.DeepEqual(detector.Traps(), expected) {
+		t.Errorf("Invalid traps detected. Expected: %+v, Got: %+v", expected[0], detector.Traps()[0])
+	}
+
+	issues := detector.Issues()
+	if len(issues) != 1 || issues[0].URL != "http://monzo.com/calendar/2002/1" ||
+		issues[0].Message != "12 URLs like http://monzo.com/calendar/{id}/{id} were skipped" {
+		t.Errorf("Invalid issues: %v", issues)
+	}
+}
+
+func TestIsID(t *testing.T) {
+	tests := map[string]bool{
+		"2019":                  true,
+		"2019-01-31":            true,
+		"a1b2c3d4e5":            true,
+		"sess_9f8e7d6c5b4a":     true,
+		"blog":                  false,
+		"v2":                    false,
+		"index.html":            false,
+		"my-first-post":         false,
+		"%7Euser":               false,
+		"0123456789abcdef0123a": true,
+	}
+
+	for segment, expected := range tests {
+		if got := isID(segment); got != expected {
+			t.Errorf("Invalid ID check of %s. Expected: %t, Got: %t", segment, expected, got)
+		}
+	}
+}
+
+func TestIsRule(t *testing.T) {
+	if !IsRule(RulePatternBudget) || IsRule("canonical-chain") {
+		t.Errorf("Invalid trap rules")
+	}
+}
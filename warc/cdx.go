@@ -0,0 +1,94 @@
+package warc
+
+import (
+	"bufio"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cdxHeader is the first line of a CDX file, describing its fields: canonicalized URL, date, original URL,
+// MIME type, status code, digest, redirect, meta tags, compressed record size, offset and file name (CDX 11).
+const cdxHeader = " CDX N b a m s k r M S V g"
+
+// cdxEntry is a line of a CDX index, locating the record of a response in a WARC file.
+type cdxEntry struct {
+	url        string
+	date       time.Time
+	mimeType   string
+	statusCode int
+	digest     string
+	redirect   string
+	length     int64
+	offset     int64
+	fileName   string
+}
+
+// writeCDX writes a CDX index of the records, sorted by canonicalized URL and date.
+func writeCDX(w io.Writer, entries []cdxEntry) error {
+	lines := []string{}
+	for _, entry := range entries {
+		lines = append(lines, strings.Join([]string{
+			canonicalizeURL(entry.url),
+			entry.date.UTC().Format("20060102150405"),
+			entry.url,
+			cdxField(strings.TrimSpace(strings.Split(entry.mimeType, ";")[0])),
+			strconv.Itoa(entry.statusCode),
+			strings.TrimPrefix(entry.digest, "sha1:"),
+			cdxField(entry.redirect),
+			"-",
+			strconv.FormatInt(entry.length, 10),
+			strconv.FormatInt(entry.offset, 10),
+			entry.fileName,
+		}, " "))
+	}
+	sort.Strings(lines)
+
+	buffered := bufio.NewWriter(w)
+	buffered.WriteString(cdxHeader + "\n")
+	for _, line := range lines {
+		buffered.WriteString(line + "\n")
+	}
+	return buffered.Flush()
+}
+
+// cdxField returns the value of a field of a CDX line ("-" if it's empty, with no spaces).
+func cdxField(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return strings.Replace(value, " ", "%20", -1)
+}
+
+// canonicalizeURL returns the SURT (Sort-friendly URI Reordering Transform) form of an URL used as key of
+// the CDX index, e.g. "http://www.Monzo.com/blog?a=1" is "com,monzo)/blog?a=1".
+func canonicalizeURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return strings.ToLower(rawURL)
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	labels := strings.Split(host, ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+
+	key := strings.Join(labels, ",")
+	if port := parsed.Port(); port != "" && port != "80" && port != "443" {
+		key += ":" + port
+	}
+
+	path := parsed.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	key += ")" + strings.ToLower(path)
+	if parsed.RawQuery != "" {
+		key += "?" + strings.ToLower(parsed.RawQuery)
+	}
+	return key
+}
@@ -0,0 +1,87 @@
+package warc
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Reader reads the records of a WARC file, compressed with gzip (as a whole or per record) or not.
+type Reader struct {
+	reader *bufio.Reader
+}
+
+// NewReader returns a Reader of the records of a WARC file.
+func NewReader(r io.Reader) (*Reader, error) {
+	buffered := bufio.NewReader(r)
+
+	// Gzip files start with the bytes 0x1f 0x8b (every member of the file is read, i.e. every record):
+	if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		buffered = bufio.NewReader(gzipReader)
+	}
+	return &Reader{reader: buffered}, nil
+}
+
+// Next returns the next record of the file, or io.EOF if there are no more records.
+func (reader *Reader) Next() (*Record, error) {
+	version, err := reader.reader.ReadString('\n')
+	if err == io.EOF && version == "" {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(version) != Version {
+		return nil, errors.New("warc: invalid record version: " + strings.TrimSpace(version))
+	}
+
+	record := &Record{}
+	contentLength := -1
+	for {
+		line, err := reader.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+
+		i := strings.Index(line, ":")
+		if i < 0 {
+			return nil, errors.New("warc: invalid header field: " + line)
+		}
+		name, value := line[:i], strings.TrimSpace(line[i+1:])
+
+		if strings.EqualFold(name, "Content-Length") {
+			if contentLength, err = strconv.Atoi(value); err != nil {
+				return nil, errors.New("warc: invalid Content-Length: " + value)
+			}
+			continue
+		}
+		record.Fields = append(record.Fields, Field{Name: name, Value: value})
+	}
+
+	if contentLength < 0 {
+		return nil, errors.New("warc: record without Content-Length")
+	}
+
+	record.Block = make([]byte, contentLength)
+	if _, err := io.ReadFull(reader.reader, record.Block); err != nil {
+		return nil, err
+	}
+
+	// Every record ends with two newlines:
+	end := make([]byte, 4)
+	if _, err := io.ReadFull(reader.reader, end); err != nil || string(end) != "\r\n\r\n" {
+		return nil, errors.New("warc: record not terminated by two newlines")
+	}
+	return record, nil
+}
@@ -0,0 +1,83 @@
+// Package warc archives the HTTP requests and responses of a crawl in WARC 1.1 files
+// (https://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/), with a CDX index.
+package warc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Version is the version line that starts every record.
+const Version = "WARC/1.1"
+
+// Types of the records written:
+const (
+	TypeWarcinfo = "warcinfo"
+	TypeRequest  = "request"
+	TypeResponse = "response"
+)
+
+// Field is a named field of the header of a record.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Record is a WARC record: its header fields (in order) and its content block.
+type Record struct {
+	Fields []Field
+	Block  []byte
+}
+
+// Get returns the value of a field of the header (matched case insensitively), or "" if it isn't present.
+func (record *Record) Get(name string) string {
+	for _, field := range record.Fields {
+		if strings.EqualFold(field.Name, name) {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// Type returns the type of the record (e.g. "response").
+func (record *Record) Type() string {
+	return record.Get("WARC-Type")
+}
+
+// write writes the record in the WARC format. The Content-Length field is added after the other fields.
+func (record *Record) write(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString(Version + "\r\n")
+	for _, field := range record.Fields {
+		buf.WriteString(field.Name + ": " + field.Value + "\r\n")
+	}
+	buf.WriteString("Content-Length: " + strconv.Itoa(len(record.Block)) + "\r\n\r\n")
+	buf.Write(record.Block)
+	buf.WriteString("\r\n\r\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// newRecordID returns a new globally unique record id (a random UUID URN).
+func newRecordID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	id[6] = (id[6] & 0x0f) | 0x40 // version 4
+	id[8] = (id[8] & 0x3f) | 0x80 // variant 10
+
+	hexID := hex.EncodeToString(id)
+	return "<urn:uuid:" + hexID[0:8] + "-" + hexID[8:12] + "-" + hexID[12:16] + "-" + hexID[16:20] + "-" + hexID[20:] + ">"
+}
+
+// digest returns the SHA-1 digest of some data in the format used by WARC and CDX files (e.g. "sha1:3I42H3...").
+func digest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
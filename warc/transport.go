@@ -0,0 +1,47 @@
+package warc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Transport is an http.RoundTripper that archives every request sent and response received
+// (including redirects) with a Writer.
+type Transport struct {
+	base    http.RoundTripper
+	writer  *Writer
+	onError func(err error)
+}
+
+// NewTransport returns a Transport that sends the requests with a base transport (http.DefaultTransport if nil)
+// and archives them with a Writer. Errors archiving an exchange are passed to onError (if not nil), without
+// failing the request.
+func NewTransport(base http.RoundTripper, writer *Writer, onError func(err error)) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{base: base, writer: writer, onError: onError}
+}
+
+// RoundTrip sends a request and archives it along with its response, whose body is read to be archived.
+func (transport *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	date := time.Now()
+	resp, err := transport.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if err := transport.writer.WriteExchange(req, resp, body, date); err != nil && transport.onError != nil {
+		transport.onError(err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,216 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/msandim/web-crawler/fetcher"
+	"github.com/msandim/web-crawler/fetcher/urlwrapper"
+)
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/", http.StatusMovedPermanently)
+		default:
+			w.Header().Add("Content-type", "text/html; charset=utf-8")
+			w.Write([]byte(`<html><a href="/page` + r.URL.Path + `">Page</a></html>`))
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "warc")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Every exchange is big enough to start a new file:
+	writer, err := NewWriter(dir, "crawl", 100)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	httpFetcher := fetcher.NewHTTPFetcher(4, 10)
+	httpFetcher.SetTransport(NewTransport(nil, writer, func(err error) { t.Errorf("Unexpected error: %v", err) }))
+
+	page, _ := httpFetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/old", server.URL+"/old"))
+	if len(page.Links) != 1 {
+		t.Errorf("Page was not fetched through the transport: %+v", page)
+	}
+	httpFetcher.Fetch(urlwrapper.NewTesting("http://monzo.com/a", server.URL+"/a"))
+
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "crawl-*.warc.gz"))
+	if len(files) != 3 {
+		t.Fatalf("Invalid number of WARC files. Expected: %d, Got: %d", 3, len(files))
+	}
+
+	// The first file has the warcinfo record, the request to /old and its redirect:
+	records := readRecords(t, files[0])
+	if len(records) != 3 || records[0].Type() != TypeWarcinfo || records[1].Type() != TypeRequest || records[2].Type() != TypeResponse {
+		t.Fatalf("Invalid records in the first file: %v", records)
+	}
+
+	request, response := records[1], records[2]
+	if request.Get("WARC-Concurrent-To") != response.Get("WARC-Record-ID") || response.Get("warc-target-uri") != server.URL+"/old" {
+		t.Errorf("Request and response records are not related: %v, %v", request.Fields, response.Fields)
+	}
+	if !strings.HasPrefix(string(request.Block), "GET /old HTTP/1.1\r\nHost: ") {
+		t.Errorf("Invalid request block: %q", request.Block)
+	}
+	if !strings.HasPrefix(string(response.Block), "HTTP/1.1 301 Moved Permanently\r\n") {
+		t.Errorf("Invalid response block: %q", response.Block)
+	}
+
+	// The second file has the page the request was redirected to:
+	records = readRecords(t, files[1])
+	if len(records) != 3 || !strings.HasSuffix(string(records[2].Block), `<html><a href="/page/">Page</a></html>`) {
+		t.Errorf("Invalid records in the second file: %v", records)
+	}
+
+	checkCDX(t, dir)
+}
+
+// checkCDX checks that the CDX index locates the response records in the WARC files.
+func checkCDX(t *testing.T, dir string) {
+	f, err := os.Open(filepath.Join(dir, "crawl.cdx"))
+	if err != nil {
+		t.Fatalf("Failed to open the CDX file: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lines := []string{}
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	if len(lines) != 4 || lines[0] != cdxHeader {
+		t.Fatalf("Invalid CDX file: %v", lines)
+	}
+
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) != 11 {
+			t.Errorf("Invalid CDX line: %s", line)
+			continue
+		}
+
+		offset, _ := strconv.ParseInt(fields[9], 10, 64)
+		length, _ := strconv.ParseInt(fields[8], 10, 64)
+		warcFile, err := os.Open(filepath.Join(dir, fields[10]))
+		if err != nil {
+			t.Fatalf("Failed to open the WARC file: %v", err)
+		}
+
+		// Every record is a gzip member, so it can be read from its offset:
+		reader, err := NewReader(io.NewSectionReader(warcFile, offset, length))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		record, err := reader.Next()
+		warcFile.Close()
+
+		if err != nil || record.Type() != TypeResponse || record.Get("WARC-Target-URI") != fields[2] {
+			t.Errorf("CDX line doesn't locate its response: %s (error: %v)", line, err)
+		}
+		if status := fields[4]; !strings.HasPrefix(string(record.Block), "HTTP/1.1 "+status) {
+			t.Errorf("Invalid status in CDX line: %s", line)
+		}
+	}
+}
+
+func readRecords(t *testing.T, path string) []*Record {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open the WARC file: %v", err)
+	}
+	defer f.Close()
+
+	reader, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	records := []*Record{}
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			return records
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		records = append(records, record)
+	}
+}
+
+func TestReader_RoundTrip(t *testing.T) {
+	record := &Record{
+		Fields: []Field{{"WARC-Type", TypeResponse}, {"WARC-Record-ID", newRecordID()}},
+		Block:  []byte("HTTP/1.1 200 OK\r\n\r\nbody\r\n\r\n"),
+	}
+
+	// Uncompressed and gzipped files are read:
+	var plain, compressed bytes.Buffer
+	record.write(&plain)
+	record.write(&plain)
+	gzipWriter := gzip.NewWriter(&compressed)
+	gzipWriter.Write(plain.Bytes())
+	gzipWriter.Close()
+
+	for _, buf := range []*bytes.Buffer{bytes.NewBuffer(plain.Bytes()), &compressed} {
+		reader, err := NewReader(buf)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for i := 0; i < 2; i++ {
+			read, err := reader.Next()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if read.Get("WARC-Record-ID") != record.Get("WARC-Record-ID") || !bytes.Equal(read.Block, record.Block) {
+				t.Errorf("Record read is different from the one written. Expected: %+v, Got: %+v", record, read)
+			}
+		}
+
+		if _, err := reader.Next(); err != io.EOF {
+			t.Errorf("Expected the end of the file, Got: %v", err)
+		}
+	}
+
+	reader, _ := NewReader(strings.NewReader("WARC/0.9\r\n"))
+	if _, err := reader.Next(); err == nil {
+		t.Errorf("Records of other versions should not be read")
+	}
+}
+
+func TestCanonicalizeURL(t *testing.T) {
+	tests := map[string]string{
+		"http://www.Monzo.com/Blog?B=1": "com,monzo)/blog?b=1",
+		"https://monzo.com":             "com,monzo)/",
+		"http://localhost:8080/a":       "localhost:8080)/a",
+	}
+
+	for rawURL, expected := range tests {
+		if got := canonicalizeURL(rawURL); got != expected {
+			t.Errorf("Canonicalized URL of %s was invalid. Expected: %s, Got: %s", rawURL, expected, got)
+		}
+	}
+}
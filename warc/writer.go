@@ -0,0 +1,201 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Writer writes the requests sent and the responses received during a crawl to WARC files in a directory,
+// compressing every record with gzip on its own (so they can be read individually from their offset).
+// A new file is started when the current one reaches a maximum size. The records of the responses are indexed
+// in a CDX file, written when the Writer is closed.
+// A Writer is safe for concurrent use.
+type Writer struct {
+	dir     string
+	prefix  string
+	maxSize int64
+
+	mutex    sync.Mutex
+	file     *os.File
+	fileName string
+	size     int64 // bytes written to the current file
+	nFiles   int
+	cdx      []cdxEntry
+}
+
+// NewWriter returns a Writer of WARC files named "<prefix>-00000.warc.gz", "<prefix>-00001.warc.gz", etc.
+// in a directory (created if needed). A new file is started when the current one has at least maxSize bytes.
+func NewWriter(dir string, prefix string, maxSize int64) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Writer{dir: dir, prefix: prefix, maxSize: maxSize}, nil
+}
+
+// WriteExchange writes a request and its response (with the body read from it) as a pair of request
+// and response records. The response is indexed in the CDX file.
+func (writer *Writer) WriteExchange(req *http.Request, resp *http.Response, body []byte, date time.Time) error {
+	requestBlock := httpRequest(req)
+	responseBlock := httpResponse(resp, body)
+
+	warcDate := date.UTC().Format(time.RFC3339)
+	targetURI := req.URL.String()
+	responseID := newRecordID()
+	payloadDigest := digest(body)
+
+	response := &Record{
+		Fields: []Field{
+			{"WARC-Type", TypeResponse},
+			{"WARC-Record-ID", responseID},
+			{"WARC-Date", warcDate},
+			{"WARC-Target-URI", targetURI},
+			{"WARC-Block-Digest", digest(responseBlock)},
+			{"WARC-Payload-Digest", payloadDigest},
+			{"Content-Type", "application/http;msgtype=response"},
+		},
+		Block: responseBlock,
+	}
+	request := &Record{
+		Fields: []Field{
+			{"WARC-Type", TypeRequest},
+			{"WARC-Record-ID", newRecordID()},
+			{"WARC-Date", warcDate},
+			{"WARC-Target-URI", targetURI},
+			{"WARC-Concurrent-To", responseID},
+			{"WARC-Block-Digest", digest(requestBlock)},
+			{"Content-Type", "application/http;msgtype=request"},
+		},
+		Block: requestBlock,
+	}
+
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.file == nil || writer.size >= writer.maxSize {
+		if err := writer.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if _, _, err := writer.writeRecord(request); err != nil {
+		return err
+	}
+	offset, length, err := writer.writeRecord(response)
+	if err != nil {
+		return err
+	}
+
+	writer.cdx = append(writer.cdx, cdxEntry{
+		url:        targetURI,
+		date:       date,
+		mimeType:   resp.Header.Get("Content-Type"),
+		statusCode: resp.StatusCode,
+		digest:     payloadDigest,
+		redirect:   resp.Header.Get("Location"),
+		length:     length,
+		offset:     offset,
+		fileName:   writer.fileName,
+	})
+	return nil
+}
+
+// rotate closes the current file (if any) and starts a new one with a warcinfo record.
+func (writer *Writer) rotate() error {
+	if writer.file != nil {
+		if err := writer.file.Close(); err != nil {
+			return err
+		}
+	}
+
+	writer.fileName = fmt.Sprintf("%s-%05d.warc.gz", writer.prefix, writer.nFiles)
+	file, err := os.Create(filepath.Join(writer.dir, writer.fileName))
+	if err != nil {
+		return err
+	}
+	writer.file, writer.size = file, 0
+	writer.nFiles++
+
+	info := []byte("software: web-crawler\r\nformat: WARC File Format 1.1\r\n" +
+		"conformsTo: http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/\r\n")
+	_, _, err = writer.writeRecord(&Record{
+		Fields: []Field{
+			{"WARC-Type", TypeWarcinfo},
+			{"WARC-Record-ID", newRecordID()},
+			{"WARC-Date", time.Now().UTC().Format(time.RFC3339)},
+			{"WARC-Filename", writer.fileName},
+			{"Content-Type", "application/warc-fields"},
+		},
+		Block: info,
+	})
+	return err
+}
+
+// writeRecord writes a record to the current file as a gzip member, returning its offset and compressed length.
+func (writer *Writer) writeRecord(record *Record) (offset int64, length int64, err error) {
+	var compressed bytes.Buffer
+	gzipWriter := gzip.NewWriter(&compressed)
+	if err := record.write(gzipWriter); err != nil {
+		return 0, 0, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return 0, 0, err
+	}
+
+	offset = writer.size
+	n, err := writer.file.Write(compressed.Bytes())
+	writer.size += int64(n)
+	return offset, int64(n), err
+}
+
+// Close closes the current WARC file and writes the CDX index of the responses ("<prefix>.cdx").
+func (writer *Writer) Close() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.file != nil {
+		if err := writer.file.Close(); err != nil {
+			return err
+		}
+		writer.file = nil
+	}
+
+	f, err := os.Create(filepath.Join(writer.dir, writer.prefix+".cdx"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeCDX(f, writer.cdx)
+}
+
+// httpRequest returns the HTTP message of a request (its request line and headers, as it was sent).
+func httpRequest(req *http.Request) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(req.Method + " " + req.URL.RequestURI() + " HTTP/1.1\r\n")
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	buf.WriteString("Host: " + host + "\r\n")
+	req.Header.Write(&buf)
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// httpResponse returns the HTTP message of a response, with its body. Bodies decompressed by the client
+// are stored decompressed (without the Content-Encoding and Content-Length headers, removed by the client).
+func httpResponse(resp *http.Response, body []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("HTTP/" + strconv.Itoa(resp.ProtoMajor) + "." + strconv.Itoa(resp.ProtoMinor) + " " + resp.Status + "\r\n")
+	resp.Header.Write(&buf)
+	buf.WriteString("\r\n")
+	buf.Write(body)
+	return buf.Bytes()
+}
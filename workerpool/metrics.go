@@ -0,0 +1,17 @@
+package workerpool
+
+import "github.com/msandim/web-crawler/metrics"
+
+// Metrics of the worker pools:
+var (
+	runningWorkers = metrics.NewGauge("web_crawler_workers",
+		"Number of workers running.")
+	activeWorkers = metrics.NewGauge("web_crawler_workers_active",
+		"Number of workers processing a job.")
+	jobsWaiting = metrics.NewGauge("web_crawler_jobs_waiting",
+		"Number of jobs added to the pools that weren't taken by a worker yet.")
+	jobsProcessed = metrics.NewCounter("web_crawler_jobs_processed_total",
+		"Number of jobs processed by the workers.")
+	jobDuration = metrics.NewHistogram("web_crawler_job_duration_seconds",
+		"Time taken by the workers to process a job.", metrics.DefBuckets)
+)
@@ -2,6 +2,7 @@ package workerpool
 
 import (
 	"sync"
+	"time"
 )
 
 // Job is an entity that works on a specific task (the Process function).
@@ -55,8 +56,10 @@ func (pool *WorkerPool) GetResultsChannel() chan JobResult {
 // AddJob adds a job to the pool of workers.
 func (pool *WorkerPool) AddJob(job Job) {
 	pool.pendingAddJobs.Add(1)
+	jobsWaiting.Inc()
 	go func() {
 		pool.pendingJobs <- job
+		jobsWaiting.Dec()
 		pool.pendingAddJobs.Done()
 	}()
 }
@@ -72,13 +75,22 @@ func (pool *WorkerPool) EndJobs() {
 
 // workerRoutine corresponds to the routine in which a worker runs until it is done.
 func workerRoutine(pool *WorkerPool) {
+	runningWorkers.Inc()
+
 	// While there are jobs to process:
 	for job := range pool.pendingJobs {
+		activeWorkers.Inc()
+		start := time.Now()
 		result := job.Process()
+		jobDuration.Observe(time.Since(start).Seconds())
+		jobsProcessed.Inc()
+		activeWorkers.Dec()
+
 		pool.finishedJobs <- result
 	}
 
 	// Mark this worker as finished:
+	runningWorkers.Dec()
 	pool.workersActive.Done()
 }
 